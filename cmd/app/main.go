@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	_ "github.com/defskela/merchmarket/docs"
 	"github.com/defskela/merchmarket/internal/api/routes"
+	"github.com/defskela/merchmarket/internal/config"
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -40,26 +43,139 @@ func initDB() (*gorm.DB, error) {
 		return nil, err
 	}
 	// Лишь для того, чтобы при запуске контейнера с новой БД всё работало, как надо. В обычной жизни эти строки не нужны
-	if err := db.Migrator().CreateTable(models.Merch{}, models.Purchase{}, models.Transaction{}, models.User{}); err != nil {
+	if err := db.Migrator().CreateTable(models.All()...); err != nil {
 		fmt.Printf("Ошибка при создании таблиц: %v", err)
 	}
 
-	if err := db.AutoMigrate(models.Merch{}, models.Purchase{}, models.Transaction{}, models.User{}); err != nil {
+	if err := db.AutoMigrate(models.All()...); err != nil {
 		fmt.Printf("Ошибка при миграции: %v", err)
 	}
-	db.Create(&models.Merch{Name: "t-shirt", Price: 80})
-	db.Create(&models.Merch{Name: "cup", Price: 20})
-	db.Create(&models.Merch{Name: "book", Price: 50})
-	db.Create(&models.Merch{Name: "pen", Price: 10})
-	db.Create(&models.Merch{Name: "powerbank", Price: 200})
-	db.Create(&models.Merch{Name: "hoody", Price: 300})
-	db.Create(&models.Merch{Name: "umbrella", Price: 200})
-	db.Create(&models.Merch{Name: "socks", Price: 10})
-	db.Create(&models.Merch{Name: "wallet", Price: 50})
-	db.Create(&models.Merch{Name: "pink-hoody", Price: 500})
+	// Прежний уникальный индекс корзины не учитывал вариант товара и мешает положить в корзину два варианта одного товара
+	if db.Migrator().HasIndex(&models.CartItem{}, "idx_cart_user_merch") {
+		if err := db.Migrator().DropIndex(&models.CartItem{}, "idx_cart_user_merch"); err != nil {
+			fmt.Printf("Ошибка при удалении индекса корзины: %v", err)
+		}
+	}
+	seedCatalog(db)
+
+	// Переносим в журнал остатки пользователей, созданных до его появления
+	if err := services.OpenLedgerBalances(db); err != nil {
+		fmt.Printf("Ошибка при переносе остатков в журнал: %v", err)
+	}
+	// Создаём партии для монет, начисленных до введения срока действия
+	if err := services.OpenCoinLots(db); err != nil {
+		fmt.Printf("Ошибка при создании партий монет: %v", err)
+	}
+
+	// Создаём администратора, если он задан в конфигурации
+	if username := config.GetString("ADMIN_USERNAME", ""); username != "" {
+		if err := services.EnsureAdmin(db, username, config.GetString("ADMIN_PASSWORD", "")); err != nil {
+			fmt.Printf("Ошибка при создании администратора: %v", err)
+		}
+	}
 	return db, nil
 }
 
+// seedCatalog заполняет каталог стартовым набором мерча, если он пуст.
+// Дальнейшие изменения каталога выполняются через /api/admin/merch.
+func seedCatalog(db *gorm.DB) {
+	var count int64
+	if err := db.Unscoped().Model(&models.Merch{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	db.Create(&[]models.Merch{
+		{Name: "t-shirt", Price: 80},
+		{Name: "cup", Price: 20},
+		{Name: "book", Price: 50},
+		{Name: "pen", Price: 10},
+		{Name: "powerbank", Price: 200},
+		{Name: "hoody", Price: 300},
+		{Name: "umbrella", Price: 200},
+		{Name: "socks", Price: 10},
+		{Name: "wallet", Price: 50},
+		{Name: "pink-hoody", Price: 500},
+	})
+}
+
+// reconcileBalances сверяет кэшированные балансы пользователей с журналом и логирует расхождения.
+func reconcileBalances(db *gorm.DB) {
+	mismatches, err := services.Reconcile(db)
+	if err != nil {
+		log.Printf("Ошибка при сверке балансов: %v", err)
+		return
+	}
+	for _, m := range mismatches {
+		log.Printf("Баланс пользователя %s (id=%d) расходится с журналом: coins=%d, по журналу=%d",
+			m.Username, m.UserID, m.Coins, m.LedgerBalance)
+	}
+}
+
+// startCoinExpiry запускает задачу сгорания монет: сразу при старте
+// и далее раз в COIN_EXPIRY_INTERVAL (по умолчанию раз в сутки).
+func startCoinExpiry(db *gorm.DB) {
+	interval := config.GetDuration("COIN_EXPIRY_INTERVAL", 24*time.Hour)
+	go func() {
+		expireCoins(db, time.Now())
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			expireCoins(db, now)
+		}
+	}()
+}
+
+// expireCoins списывает монеты с истёкшим сроком действия.
+func expireCoins(db *gorm.DB, now time.Time) {
+	expired, err := services.ExpireCoinLots(db, now)
+	if err != nil {
+		log.Printf("Ошибка при списании сгоревших монет: %v", err)
+	}
+	if expired > 0 {
+		log.Printf("Сгорело монет: %d", expired)
+	}
+}
+
+// startWorker запускает фоновый обработчик, который раз в WORKER_INTERVAL
+// выполняет наступившие запланированные переводы, начисления по политикам
+// и возврат просроченных переводов с удержанием.
+func startWorker(db *gorm.DB) {
+	interval := config.GetDuration("WORKER_INTERVAL", time.Minute)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			runWorkerJobs(db, now)
+		}
+	}()
+}
+
+// runWorkerJobs выполняет один проход фонового обработчика.
+func runWorkerJobs(db *gorm.DB, now time.Time) {
+	executed, err := services.RunDueScheduledTransfers(db, now)
+	if err != nil {
+		log.Printf("Ошибка при выполнении запланированных переводов: %v", err)
+	}
+	if executed > 0 {
+		log.Printf("Выполнено запланированных переводов: %d", executed)
+	}
+
+	granted, err := services.GrantAllowances(db, now)
+	if err != nil {
+		log.Printf("Ошибка при начислении монет по политикам: %v", err)
+	}
+	if len(granted) > 0 {
+		log.Printf("Выполнено начислений по политикам: %d", len(granted))
+	}
+
+	released, err := services.ReleaseExpiredEscrows(db, now)
+	if err != nil {
+		log.Printf("Ошибка при возврате неподтверждённых переводов: %v", err)
+	}
+	if released > 0 {
+		log.Printf("Возвращено неподтверждённых переводов: %d", released)
+	}
+}
+
 func main() {
 	if err := godotenv.Load(); err != nil {
 		fmt.Println("No .env file found, using defaults")
@@ -69,6 +185,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	reconcileBalances(db)
+	startWorker(db)
+	startCoinExpiry(db)
 
 	router := gin.Default()
 
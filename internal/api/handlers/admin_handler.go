@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	db *gorm.DB
+}
+
+func NewAdminHandler(db *gorm.DB) *AdminHandler {
+	return &AdminHandler{db: db}
+}
+
+type ReconciliationResponse struct {
+	Mismatches []services.BalanceMismatch `json:"mismatches"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type TransactionEntry struct {
+	ID         uint      `json:"id"`
+	FromUser   string    `json:"fromUser"`
+	ToUser     string    `json:"toUser"`
+	Amount     int       `json:"amount"`
+	ReversalOf *uint     `json:"reversalOf,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Status     string    `json:"status"` // settled, held или rejected
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ReverseTransferRequest struct {
+	Policy string `json:"policy"` // partial или negative; по умолчанию TRANSFER_REVERSAL_POLICY
+	Reason string `json:"reason"`
+}
+
+type ReversalResponse struct {
+	OriginalID uint   `json:"originalId"`
+	ReversalID uint   `json:"reversalId"`
+	Policy     string `json:"policy"`
+	Amount     int    `json:"amount"` // сумма, возвращённая отправителю
+	Debt       int    `json:"debt"`   // сумма, записанная получателю в долг
+}
+
+// maxAdminTransactions — сколько последних переводов возвращает GET /admin/transactions.
+const maxAdminTransactions = 100
+
+// @Summary      Сверка балансов с журналом.
+// @Description  Возвращает пользователей, у которых кэшированный баланс расходится с суммой проводок журнала.
+// @Tags         Admin
+// @Produce      json
+// @Success      200 {object} ReconciliationResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/reconciliation [get]
+// @Security     BearerAuth
+func (h *AdminHandler) GetReconciliation(c *gin.Context) {
+	mismatches, err := services.Reconcile(h.db)
+	if err != nil {
+		resp := ErrorResponse{Error: "Ошибка при сверке балансов"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	if mismatches == nil {
+		mismatches = []services.BalanceMismatch{}
+	}
+	c.JSON(http.StatusOK, ReconciliationResponse{Mismatches: mismatches})
+}
+
+// @Summary      Изменить роль пользователя.
+// @Description  Назначает пользователю роль user или admin. Новая роль попадает в токены при следующем входе или обновлении токена.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        username path string true "Имя пользователя"
+// @Param        body body SetRoleRequest true "Роль"
+// @Success      200 {null} nil "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Пользователь не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/users/{username}/role [put]
+// @Security     BearerAuth
+func (h *AdminHandler) SetUserRole(c *gin.Context) {
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Role != models.RoleUser && req.Role != models.RoleAdmin {
+		resp := ErrorResponse{Error: "Неизвестная роль"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	res := h.db.Model(&models.User{}).Where("username = ?", c.Param("username")).Update("role", req.Role)
+	if res.Error != nil {
+		resp := ErrorResponse{Error: "Ошибка при изменении роли"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	if res.RowsAffected == 0 {
+		resp := ErrorResponse{Error: "Пользователь не найден"}
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+}
+
+// @Summary      Переводы пользователя.
+// @Description  Возвращает последние 100 переводов, в которых участвовал пользователь (или все переводы, если пользователь не указан), с идентификаторами для отмены.
+// @Tags         Admin
+// @Produce      json
+// @Param        username query string false "Имя пользователя"
+// @Success      200 {array} TransactionEntry "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/transactions [get]
+// @Security     BearerAuth
+func (h *AdminHandler) ListTransactions(c *gin.Context) {
+	query := h.db.Table("transactions").
+		Select("transactions.id, senders.username AS from_user, receivers.username AS to_user, transactions.amount, transactions.reversal_of, transactions.message, transactions.status, transactions.created_at").
+		Joins("JOIN users senders ON senders.id = transactions.from_user_id").
+		Joins("JOIN users receivers ON receivers.id = transactions.to_user_id").
+		Where("transactions.deleted_at IS NULL")
+	if username := c.Query("username"); username != "" {
+		query = query.Where("senders.username = ? OR receivers.username = ?", username, username)
+	}
+
+	var entries []TransactionEntry
+	if err := query.Order("transactions.id DESC").Limit(maxAdminTransactions).Scan(&entries).Error; err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить переводы"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	if entries == nil {
+		entries = []TransactionEntry{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Отменить перевод.
+// @Description  Создаёт компенсирующий перевод от получателя к отправителю. Если получатель уже потратил монеты, политика partial возвращает только остаток, а negative — всю сумму, записывая недостающее получателю в долг. Долг погашается из будущих зачислений.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID перевода"
+// @Param        body body ReverseTransferRequest false "Политика и причина отмены"
+// @Success      200 {object} ReversalResponse "Перевод отменён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Перевод не найден."
+// @Failure      409 {object} ErrorResponse "Перевод уже отменён или отменить его нельзя."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/transactions/{id}/reverse [post]
+// @Security     BearerAuth
+func (h *AdminHandler) ReverseTransaction(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ReverseTransferRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := ErrorResponse{Error: "Неверный запрос"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+	if req.Policy == "" {
+		req.Policy = services.DefaultReversalPolicy()
+	}
+
+	reversal, err := services.ReverseTransfer(h.db, c.GetString("username"), id, req.Policy, strings.TrimSpace(req.Reason))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownReversalPolicy):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Политика отмены должна быть partial или negative"})
+		case errors.Is(err, services.ErrTransactionNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Перевод не найден"})
+		case errors.Is(err, services.ErrAlreadyReversed):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Перевод уже отменён"})
+		case errors.Is(err, services.ErrReversalOfReversal):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Нельзя отменить отмену перевода"})
+		case errors.Is(err, services.ErrTransferNotSettled):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Перевод задержан или отклонён и не зачислен получателю"})
+		case errors.Is(err, services.ErrNothingToReverse):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "У получателя не осталось монет для возврата"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при отмене перевода"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ReversalResponse{
+		OriginalID: reversal.Original.ID,
+		ReversalID: reversal.Compensation.ID,
+		Policy:     req.Policy,
+		Amount:     reversal.Compensation.Amount,
+		Debt:       reversal.Debt,
+	})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AllowanceHandler struct {
+	db *gorm.DB
+}
+
+func NewAllowanceHandler(db *gorm.DB) *AllowanceHandler {
+	return &AllowanceHandler{db: db}
+}
+
+type CreateAllowancePolicyRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Amount int    `json:"amount" binding:"required"`
+	Period string `json:"period" binding:"required"` // day, week или month
+	// Политика задаётся для пользователя (username), для роли (role) или, если не указано ни то ни другое, для всех.
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Active   *bool  `json:"active"` // по умолчанию true
+}
+
+type UpdateAllowancePolicyRequest struct {
+	Name   *string `json:"name"`
+	Amount *int    `json:"amount"`
+	Period *string `json:"period"`
+	Active *bool   `json:"active"`
+}
+
+type AllowancePolicyResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Amount    int       `json:"amount"`
+	Period    string    `json:"period"`
+	Username  string    `json:"username,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type AllowanceRunResponse struct {
+	DryRun bool                          `json:"dryRun"`
+	Grants []services.AllowanceGrantPlan `json:"grants"`
+	Total  int                           `json:"total"` // сумма всех начислений
+}
+
+// @Summary      Политики начисления монет.
+// @Description  Возвращает политики регулярного начисления монет.
+// @Tags         Admin
+// @Produce      json
+// @Success      200 {array} AllowancePolicyResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/allowances [get]
+// @Security     BearerAuth
+func (h *AllowanceHandler) ListPolicies(c *gin.Context) {
+	var rows []struct {
+		models.AllowancePolicy
+		Username string
+	}
+	err := h.db.Table("allowance_policies").
+		Select("allowance_policies.*, users.username").
+		Joins("LEFT JOIN users ON users.id = allowance_policies.user_id").
+		Where("allowance_policies.deleted_at IS NULL").
+		Order("allowance_policies.id").
+		Scan(&rows).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить политики начисления"})
+		return
+	}
+
+	policies := make([]AllowancePolicyResponse, 0, len(rows))
+	for i := range rows {
+		policies = append(policies, newAllowancePolicyResponse(&rows[i].AllowancePolicy, rows[i].Username))
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// @Summary      Создать политику начисления.
+// @Description  Создаёт политику регулярного начисления монет пользователю, роли или всем пользователям. Монеты начисляет фоновый обработчик один раз за период (день, неделю ISO или месяц по UTC).
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateAllowancePolicyRequest true "Параметры политики"
+// @Success      201 {object} AllowancePolicyResponse "Политика создана."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Пользователь не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/allowances [post]
+// @Security     BearerAuth
+func (h *AllowanceHandler) CreatePolicy(c *gin.Context) {
+	var req CreateAllowancePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.Amount <= 0 {
+		resp := ErrorResponse{Error: "Название политики не должно быть пустым, а сумма должна быть положительной"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Role != "" && req.Role != models.RoleUser && req.Role != models.RoleAdmin {
+		resp := ErrorResponse{Error: "Неизвестная роль"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	policy := models.AllowancePolicy{
+		Name:   req.Name,
+		Amount: req.Amount,
+		Period: req.Period,
+		Role:   req.Role,
+		Active: req.Active == nil || *req.Active,
+	}
+	if req.Username != "" {
+		var user models.User
+		if err := h.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
+			resp := ErrorResponse{Error: "Пользователь не найден"}
+			c.JSON(http.StatusNotFound, resp)
+			return
+		}
+		policy.UserID = &user.ID
+	}
+
+	if err := services.CreateAllowancePolicy(h.db, &policy); err != nil {
+		respondAllowanceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, newAllowancePolicyResponse(&policy, req.Username))
+}
+
+// @Summary      Изменить политику начисления.
+// @Description  Изменяет сумму, период, название или активность политики. Уже выполненные начисления не пересчитываются.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID политики"
+// @Param        body body UpdateAllowancePolicyRequest true "Изменяемые поля"
+// @Success      200 {object} AllowancePolicyResponse "Политика изменена."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Политика не найдена."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/allowances/{id} [put]
+// @Security     BearerAuth
+func (h *AllowanceHandler) UpdatePolicy(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateAllowancePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+	if (req.Name != nil && *req.Name == "") || (req.Amount != nil && *req.Amount <= 0) {
+		resp := ErrorResponse{Error: "Название политики не должно быть пустым, а сумма должна быть положительной"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	policy, err := services.UpdateAllowancePolicy(h.db, id, services.AllowancePolicyChanges{
+		Name:   req.Name,
+		Amount: req.Amount,
+		Period: req.Period,
+		Active: req.Active,
+	})
+	if err != nil {
+		respondAllowanceError(c, err)
+		return
+	}
+
+	var username string
+	if policy.UserID != nil {
+		h.db.Model(&models.User{}).Where("id = ?", *policy.UserID).Pluck("username", &username)
+	}
+	c.JSON(http.StatusOK, newAllowancePolicyResponse(policy, username))
+}
+
+// @Summary      Удалить политику начисления.
+// @Description  Удаляет политику. Начисления, выполненные по ней, сохраняются.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID политики"
+// @Success      200 {null} nil "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Политика не найдена."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/allowances/{id} [delete]
+// @Security     BearerAuth
+func (h *AllowanceHandler) DeletePolicy(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := services.DeleteAllowancePolicy(h.db, id); err != nil {
+		respondAllowanceError(c, err)
+		return
+	}
+}
+
+// @Summary      Начислить монеты по политикам.
+// @Description  Выполняет начисления за текущий период, не дожидаясь фонового обработчика. Начисления, уже выполненные в этом периоде, не повторяются. С dryRun=true только показывает, что будет начислено.
+// @Tags         Admin
+// @Produce      json
+// @Param        dryRun query bool false "Только предпросмотр"
+// @Success      200 {object} AllowanceRunResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/allowances/run [post]
+// @Security     BearerAuth
+func (h *AllowanceHandler) RunAllowances(c *gin.Context) {
+	dryRun := false
+	if c.Query("dryRun") != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(c.Query("dryRun")); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверное значение dryRun"})
+			return
+		}
+	}
+
+	var grants []services.AllowanceGrantPlan
+	var err error
+	if dryRun {
+		grants, err = services.PlanAllowances(h.db, time.Now())
+	} else {
+		grants, err = services.GrantAllowances(h.db, time.Now())
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при начислении монет"})
+		return
+	}
+
+	resp := AllowanceRunResponse{DryRun: dryRun, Grants: grants}
+	for _, grant := range grants {
+		resp.Total += grant.Amount
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func newAllowancePolicyResponse(policy *models.AllowancePolicy, username string) AllowancePolicyResponse {
+	return AllowancePolicyResponse{
+		ID:        policy.ID,
+		Name:      policy.Name,
+		Amount:    policy.Amount,
+		Period:    policy.Period,
+		Username:  username,
+		Role:      policy.Role,
+		Active:    policy.Active,
+		CreatedAt: policy.CreatedAt,
+	}
+}
+
+// respondAllowanceError преобразует ошибку политики начисления в HTTP-ответ.
+func respondAllowanceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAllowancePolicyNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Политика начисления не найдена"})
+	case errors.Is(err, services.ErrUnknownAllowancePeriod):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Период должен быть day, week или month"})
+	case errors.Is(err, services.ErrAllowanceTarget):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Укажите либо пользователя, либо роль"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при сохранении политики начисления"})
+	}
+}
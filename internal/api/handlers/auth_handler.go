@@ -3,23 +3,28 @@ package handlers
 import (
 	"errors"
 	"net/http"
-	"os"
-	"time"
+	"regexp"
+	"unicode"
 
+	"github.com/defskela/merchmarket/internal/config"
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthHandler struct {
 	Db *gorm.DB
+	// AutoRegister включает старое поведение /auth: неизвестный пользователь регистрируется автоматически.
+	AutoRegister bool
 }
 
 func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{Db: db}
+	return &AuthHandler{
+		Db:           db,
+		AutoRegister: config.GetBool("AUTH_AUTO_REGISTER", false),
+	}
 }
 
 type AuthRequest struct {
@@ -27,14 +32,101 @@ type AuthRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"` // время жизни access-токена в секундах
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
 }
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET_KEY"))
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Требования к имени пользователя и паролю.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt игнорирует байты сверх 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,32}$`)
+
+// validateCredentials проверяет имя пользователя и пароль на соответствие политике.
+// Возвращает текст ошибки или пустую строку, если данные корректны.
+func validateCredentials(username, password string) string {
+	if !usernamePattern.MatchString(username) {
+		return "Имя пользователя должно содержать от 3 до 32 символов: латинские буквы, цифры, '_', '.', '-'"
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "Пароль должен содержать от 8 до 72 символов"
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return "Пароль должен содержать хотя бы одну букву и одну цифру"
+	}
+	return ""
+}
+
+// @Summary      Регистрация нового пользователя.
+// @Description  Создаёт пользователя с начальным балансом и возвращает JWT-токен.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body RegisterRequest true "Логин и пароль"
+// @Success      201 {object} AuthResponse "Пользователь зарегистрирован."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      409 {object} ErrorResponse "Пользователь уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if msg := validateCredentials(req.Username, req.Password); msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, status, msg := h.createUser(req.Username, req.Password)
+	if msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(status, resp)
+		return
+	}
+
+	tokens, err := services.IssueTokens(h.Db, user)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось создать токен"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newAuthResponse(tokens))
+}
 
 // @Summary      Аутентификация и получение JWT-токена.
-// @Description  Регистрирует нового пользователя или авторизует существующего. Возвращает JWT-токен.
+// @Description  Авторизует существующего пользователя и возвращает JWT-токен. Если включён AUTH_AUTO_REGISTER, неизвестный пользователь регистрируется автоматически.
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
@@ -56,30 +148,25 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 	var user models.User
 	err := h.Db.Where("username = ?", req.Username).First(&user).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			var hash []byte
-			hash, err = bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-			if err != nil {
-				resp := ErrorResponse{Error: "Ошибка при генерации хеша"}
-				c.JSON(http.StatusInternalServerError, resp)
-				return
-			}
-			user = models.User{
-				Username: req.Username,
-				Password: string(hash),
-				Coins:    1000,
-			}
-			if err := h.Db.Create(&user).Error; err != nil {
-				resp := ErrorResponse{Error: "Не удалось создать пользователя"}
-				c.JSON(http.StatusInternalServerError, resp)
-				return
-			}
-		} else {
-			// fmt.Println(err)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			resp := ErrorResponse{Error: "Ошибка при поиске пользователя"}
 			c.JSON(http.StatusInternalServerError, resp)
 			return
 		}
+		if !h.AutoRegister {
+			resp := ErrorResponse{Error: "Пользователь не найден"}
+			c.JSON(http.StatusUnauthorized, resp)
+			return
+		}
+		// Политика паролей действует только для /register: автоматическая регистрация
+		// сохраняет прежнее поведение, чтобы существующие установки продолжали работать
+		created, status, msg := h.createUser(req.Username, req.Password)
+		if msg != "" {
+			resp := ErrorResponse{Error: msg}
+			c.JSON(status, resp)
+			return
+		}
+		user = *created
 	} else {
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 			resp := ErrorResponse{Error: "Неверный пароль"}
@@ -88,18 +175,145 @@ func (h *AuthHandler) Authenticate(c *gin.Context) {
 		}
 	}
 
-	// Создаём JWT-токен с данными пользователя.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": req.Username,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
-	})
-
-	tokenString, err := token.SignedString(jwtSecret)
+	tokens, err := services.IssueTokens(h.Db, &user)
 	if err != nil {
 		resp := ErrorResponse{Error: "Не удалось создать токен"}
 		c.JSON(http.StatusInternalServerError, resp)
 		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{Token: tokenString})
+	c.JSON(http.StatusOK, newAuthResponse(tokens))
+}
+
+// @Summary      Обновление пары токенов.
+// @Description  Обменивает refresh-токен на новую пару токенов. Каждый refresh-токен можно использовать только один раз: повторное использование отзывает всю цепочку токенов.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body RefreshRequest true "Refresh-токен"
+// @Success      200 {object} AuthResponse "Новая пара токенов."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неверный, просроченный или отозванный refresh-токен."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	tokens, err := services.RefreshTokens(h.Db, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			resp := ErrorResponse{Error: "Refresh-токен уже был использован, все сессии отозваны"}
+			c.JSON(http.StatusUnauthorized, resp)
+		case errors.Is(err, services.ErrInvalidRefreshToken):
+			resp := ErrorResponse{Error: "Неверный или просроченный refresh-токен"}
+			c.JSON(http.StatusUnauthorized, resp)
+		default:
+			resp := ErrorResponse{Error: "Не удалось обновить токен"}
+			c.JSON(http.StatusInternalServerError, resp)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthResponse(tokens))
+}
+
+// @Summary      Выход из системы.
+// @Description  Отзывает текущий access-токен. Если передан refresh-токен, отзывает всю его цепочку.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        body body LogoutRequest false "Refresh-токен"
+// @Success      200 {null} nil "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /auth/logout [post]
+// @Security     BearerAuth
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := ErrorResponse{Error: "Неверный запрос"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	// Данные токена сохраняются в контексте JWTAuthMiddleware
+	claimsI, exists := c.Get("accessClaims")
+	if !exists {
+		resp := ErrorResponse{Error: "Пользователь не авторизован"}
+		c.JSON(http.StatusUnauthorized, resp)
+		return
+	}
+	claims, ok := claimsI.(*services.AccessClaims)
+	if !ok {
+		resp := ErrorResponse{Error: "Ошибка авторизации"}
+		c.JSON(http.StatusUnauthorized, resp)
+		return
+	}
+
+	var user models.User
+	if err := h.Db.Where("username = ?", claims.Username).First(&user).Error; err != nil {
+		resp := ErrorResponse{Error: "Пользователь не найден"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	if err := services.Logout(h.Db, claims, user.ID, req.RefreshToken); err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			resp := ErrorResponse{Error: "Неверный refresh-токен"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+		resp := ErrorResponse{Error: "Не удалось выполнить выход"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+}
+
+func newAuthResponse(tokens *services.TokenPair) AuthResponse {
+	return AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	}
+}
+
+// createUser хеширует пароль и создаёт пользователя вместе с записью о начислении стартовых монет.
+// При ошибке возвращает HTTP-статус и текст ошибки.
+func (h *AuthHandler) createUser(username, password string) (*models.User, int, string) {
+	var count int64
+	if err := h.Db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, http.StatusInternalServerError, "Ошибка при поиске пользователя"
+	}
+	if count > 0 {
+		return nil, http.StatusConflict, "Пользователь уже существует"
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Ошибка при генерации хеша"
+	}
+	user := models.User{
+		Username: username,
+		Password: string(hash),
+	}
+	err = h.Db.Transaction(func(tx *gorm.DB) error {
+		return services.CreateUser(tx, &user)
+	})
+	if err != nil {
+		// Пользователь мог быть создан параллельным запросом между проверкой и вставкой
+		if h.Db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error == nil && count > 0 {
+			return nil, http.StatusConflict, "Пользователь уже существует"
+		}
+		return nil, http.StatusInternalServerError, "Не удалось создать пользователя"
+	}
+	return &user, 0, ""
 }
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CartHandler struct {
+	db *gorm.DB
+}
+
+func NewCartHandler(db *gorm.DB) *CartHandler {
+	return &CartHandler{db: db}
+}
+
+type AddCartItemRequest struct {
+	Item     string `json:"item" binding:"required"`
+	Variant  string `json:"variant"`  // обязателен для товаров с вариантами
+	Quantity int    `json:"quantity"` // по умолчанию 1
+}
+
+type CartLine struct {
+	Item      string `json:"item"`
+	Variant   string `json:"variant,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Price     int    `json:"price"`
+	Subtotal  int    `json:"subtotal"`
+	Available bool   `json:"available"` // false, если товар или вариант удалён из каталога или его не хватает на складе
+}
+
+type CartResponse struct {
+	Items []CartLine `json:"items"`
+	Total int        `json:"total"`
+}
+
+type CheckoutResponse struct {
+	Items []CartLine `json:"items"`
+	Total int        `json:"total"`
+}
+
+type CheckoutLineError struct {
+	Item      string `json:"item"`
+	Variant   string `json:"variant,omitempty"`
+	Quantity  int    `json:"quantity"`
+	Available *int   `json:"available,omitempty"` // остаток на складе, если товара не хватает
+	Error     string `json:"error"`
+}
+
+type CheckoutErrorResponse struct {
+	Error string              `json:"error"`
+	Lines []CheckoutLineError `json:"lines"`
+}
+
+// @Summary      Содержимое корзины.
+// @Description  Возвращает позиции корзины текущего пользователя и их общую стоимость.
+// @Tags         Cart
+// @Produce      json
+// @Success      200 {object} CartResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /cart [get]
+// @Security     BearerAuth
+func (h *CartHandler) GetCart(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	items, err := services.GetCart(h.db, user.ID)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить корзину"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	c.JSON(http.StatusOK, newCartResponse(items))
+}
+
+// @Summary      Добавить товар в корзину.
+// @Description  Добавляет указанное количество единиц товара в корзину. Для товара с вариантами нужно выбрать вариант. Если позиция уже в корзине, количество увеличивается.
+// @Tags         Cart
+// @Accept       json
+// @Produce      json
+// @Param        body body AddCartItemRequest true "Товар и количество"
+// @Success      200 {object} CartResponse "Корзина после изменения."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Товар или вариант не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /cart/items [post]
+// @Security     BearerAuth
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var req AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 || req.Quantity > services.MaxCartQuantity {
+		resp := ErrorResponse{Error: "Количество должно быть от 1 до 99"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var merch models.Merch
+	if err := h.db.Where("name = ?", strings.TrimSpace(req.Item)).First(&merch).Error; err != nil {
+		resp := ErrorResponse{Error: "Товар не найден"}
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+	variant, err := services.SelectVariant(h.db, &merch, strings.TrimSpace(req.Variant))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVariantRequired):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Выберите вариант товара"})
+		case errors.Is(err, services.ErrVariantNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Вариант товара не найден"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось изменить корзину"})
+		}
+		return
+	}
+	var variantID *uint
+	if variant != nil {
+		variantID = &variant.ID
+	}
+
+	if err := services.AddToCart(h.db, user.ID, merch.ID, variantID, req.Quantity); err != nil {
+		if errors.Is(err, services.ErrCartQuantityExceeded) {
+			resp := ErrorResponse{Error: "В корзине может быть не больше 99 единиц одного товара"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+		resp := ErrorResponse{Error: "Не удалось изменить корзину"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	h.respondCart(c, user.ID)
+}
+
+// @Summary      Убрать товар из корзины.
+// @Description  Уменьшает количество товара в корзине на quantity единиц. Без параметра quantity позиция удаляется целиком. Для товара с вариантами вариант передаётся параметром variant.
+// @Tags         Cart
+// @Produce      json
+// @Param        item path string true "Название товара"
+// @Param        variant query string false "Название варианта"
+// @Param        quantity query int false "Количество единиц"
+// @Success      200 {object} CartResponse "Корзина после изменения."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Товара нет в корзине."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /cart/items/{item} [delete]
+// @Security     BearerAuth
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	quantity, err := queryInt(c, "quantity", 0)
+	if err != nil || quantity < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверное количество"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	// Ищем и среди удалённых товаров и вариантов: их тоже нужно уметь убрать из корзины
+	var merch models.Merch
+	if err := h.db.Unscoped().Where("name = ?", c.Param("item")).First(&merch).Error; err != nil {
+		resp := ErrorResponse{Error: "Товара нет в корзине"}
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+
+	var variantID *uint
+	if name := c.Query("variant"); name != "" {
+		var variant models.MerchVariant
+		if err := h.db.Unscoped().Where("merch_id = ? AND name = ?", merch.ID, name).First(&variant).Error; err != nil {
+			resp := ErrorResponse{Error: "Товара нет в корзине"}
+			c.JSON(http.StatusNotFound, resp)
+			return
+		}
+		variantID = &variant.ID
+	}
+
+	if err := services.RemoveFromCart(h.db, user.ID, merch.ID, variantID, quantity); err != nil {
+		if errors.Is(err, services.ErrCartItemNotFound) {
+			resp := ErrorResponse{Error: "Товара нет в корзине"}
+			c.JSON(http.StatusNotFound, resp)
+			return
+		}
+		resp := ErrorResponse{Error: "Не удалось изменить корзину"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	h.respondCart(c, user.ID)
+}
+
+// @Summary      Оформить корзину.
+// @Description  Покупает все товары корзины одной транзакцией: списывает общую стоимость и создаёт записи о покупках. Если хотя бы одну позицию оформить нельзя, ничего не покупается, а в ответе перечисляются проблемные позиции.
+// @Tags         Cart
+// @Produce      json
+// @Success      200 {object} CheckoutResponse "Корзина оформлена."
+// @Failure      400 {object} ErrorResponse "Корзина пуста или недостаточно средств."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      409 {object} CheckoutErrorResponse "Некоторые позиции нельзя оформить."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /cart/checkout [post]
+// @Security     BearerAuth
+func (h *CartHandler) Checkout(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	// Начинаем транзакцию
+	tx := h.db.Begin()
+
+	purchases, err := services.Checkout(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		var checkoutErr *services.CheckoutError
+		switch {
+		case errors.As(err, &checkoutErr):
+			c.JSON(http.StatusConflict, newCheckoutErrorResponse(checkoutErr))
+		case errors.Is(err, services.ErrCartEmpty):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Корзина пуста"})
+		case errors.Is(err, services.ErrInsufficientFunds):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Недостаточно средств"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при оформлении покупки"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err := tx.Commit().Error; err != nil {
+		resp := ErrorResponse{Error: "Ошибка при сохранении данных"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCheckoutResponse(purchases))
+}
+
+// currentUser находит пользователя из JWT. При ошибке отвечает клиенту и возвращает false.
+func (h *CartHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Пользователь не найден"})
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h *CartHandler) respondCart(c *gin.Context, userID uint) {
+	items, err := services.GetCart(h.db, userID)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить корзину"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	c.JSON(http.StatusOK, newCartResponse(items))
+}
+
+func newCartResponse(items []models.CartItem) CartResponse {
+	resp := CartResponse{Items: make([]CartLine, 0, len(items))}
+	for _, item := range items {
+		stock := item.Merch.Stock
+		available := !item.Merch.DeletedAt.Valid
+		line := CartLine{Item: item.Merch.Name, Quantity: item.Quantity}
+		if item.Variant != nil {
+			line.Variant = item.Variant.Name
+			stock = item.Variant.Stock
+			available = available && !item.Variant.DeletedAt.Valid
+		}
+		line.Price = services.ItemPrice(&item.Merch, item.Variant)
+		line.Subtotal = line.Price * item.Quantity
+		line.Available = available && (stock == nil || *stock >= item.Quantity)
+		resp.Items = append(resp.Items, line)
+		resp.Total += line.Subtotal
+	}
+	return resp
+}
+
+// cartLineKey — товар и вариант позиции корзины.
+type cartLineKey struct {
+	merchID   uint
+	variantID uint
+}
+
+func newCheckoutResponse(purchases []models.Purchase) CheckoutResponse {
+	resp := CheckoutResponse{Items: []CartLine{}}
+	// Покупки создаются по одной на единицу товара; в ответе группируем их по товарам и вариантам
+	index := map[cartLineKey]int{}
+	for _, purchase := range purchases {
+		key := cartLineKey{merchID: purchase.MerchID}
+		if purchase.VariantID != nil {
+			key.variantID = *purchase.VariantID
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(resp.Items)
+			index[key] = i
+			line := CartLine{
+				Item:      purchase.Merch.Name,
+				Price:     purchase.Price,
+				Available: true,
+			}
+			if purchase.Variant != nil {
+				line.Variant = purchase.Variant.Name
+			}
+			resp.Items = append(resp.Items, line)
+		}
+		resp.Items[i].Quantity++
+		resp.Items[i].Subtotal += purchase.Price
+		resp.Total += purchase.Price
+	}
+	return resp
+}
+
+func newCheckoutErrorResponse(err *services.CheckoutError) CheckoutErrorResponse {
+	resp := CheckoutErrorResponse{Error: "Некоторые позиции корзины нельзя оформить"}
+	for _, line := range err.Lines {
+		lineErr := CheckoutLineError{
+			Item:      line.Item,
+			Variant:   line.Variant,
+			Quantity:  line.Quantity,
+			Available: line.Available,
+		}
+		switch {
+		case errors.Is(line.Err, services.ErrOutOfStock):
+			lineErr.Error = "Недостаточно товара на складе"
+		case errors.Is(line.Err, services.ErrMerchNotFound):
+			lineErr.Error = "Товар больше не продаётся"
+		case errors.Is(line.Err, services.ErrVariantRequired):
+			lineErr.Error = "Выберите вариант товара"
+		case errors.Is(line.Err, services.ErrVariantNotFound):
+			lineErr.Error = "Вариант товара больше не продаётся"
+		default:
+			lineErr.Error = "Позицию нельзя оформить"
+		}
+		resp.Lines = append(resp.Lines, lineErr)
+	}
+	return resp
+}
@@ -0,0 +1,761 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CatalogHandler struct {
+	db *gorm.DB
+}
+
+func NewCatalogHandler(db *gorm.DB) *CatalogHandler {
+	return &CatalogHandler{db: db}
+}
+
+type MerchResponse struct {
+	ID       uint              `json:"id"`
+	Name     string            `json:"name"`
+	Price    int               `json:"price"`
+	Stock    *int              `json:"stock"` // null — запас не ограничен
+	Deleted  bool              `json:"deleted,omitempty"`
+	Variants []VariantResponse `json:"variants,omitempty"` // варианты товара; у товара с вариантами остаток учитывается по ним
+
+	CategoryID *uint    `json:"categoryId,omitempty"`
+	Category   string   `json:"category,omitempty"` // полное название категории, например "Одежда / Худи"
+	Tags       []string `json:"tags,omitempty"`
+}
+
+type CreateMerchRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Price int    `json:"price" binding:"required"`
+	Stock *int   `json:"stock"` // если не передан, запас не ограничен
+}
+
+type UpdateMerchRequest struct {
+	Name  *string `json:"name"`
+	Price *int    `json:"price"`
+	Stock *int    `json:"stock"`
+}
+
+type SetMerchCategoryRequest struct {
+	CategoryID *uint `json:"categoryId"` // null — убрать товар из категории
+}
+
+type SetMerchTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"` // заменяет метки целиком; пустой список удаляет все метки
+}
+
+type RestockRequest struct {
+	Quantity int `json:"quantity" binding:"required"`
+}
+
+type CatalogChangeEntry struct {
+	ID        uint            `json:"id"`
+	MerchID   uint            `json:"merchId"`
+	Action    string          `json:"action"`
+	OldValue  json.RawMessage `json:"oldValue,omitempty" swaggertype:"object"`
+	NewValue  json.RawMessage `json:"newValue,omitempty" swaggertype:"object"`
+	ChangedBy string          `json:"changedBy"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// LowStockItem — строка отчёта о заканчивающихся товарах. Для варианта указываются
+// его ID и название, а stock и price относятся к варианту.
+type LowStockItem struct {
+	MerchResponse
+	VariantID *uint  `json:"variantId,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+}
+
+type MerchListResponse struct {
+	Items    []MerchResponse `json:"items"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+	Total    int64           `json:"total"`
+}
+
+const maxMerchNameLength = 64
+
+// defaultLowStockThreshold — порог отчёта об остатках, если он не задан ни в запросе, ни в LOW_STOCK_THRESHOLD.
+const defaultLowStockThreshold = 5
+
+// Параметры постраничного вывода каталога.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// merchEffectivePrice — SQL-выражение цены, по которой товар фактически продаётся:
+// наименьшая цена среди вариантов в наличии (а если в наличии нет ни одного — среди
+// всех вариантов); у товара без вариантов — его собственная цена.
+const merchEffectivePrice = "COALESCE(" +
+	"(SELECT MIN(COALESCE(merch_variants.price, merches.price)) FROM merch_variants WHERE merch_variants.merch_id = merches.id " +
+	"AND merch_variants.deleted_at IS NULL AND (merch_variants.stock IS NULL OR merch_variants.stock > 0)), " +
+	"(SELECT MIN(COALESCE(merch_variants.price, merches.price)) FROM merch_variants WHERE merch_variants.merch_id = merches.id " +
+	"AND merch_variants.deleted_at IS NULL), " +
+	"merches.price)"
+
+// merchSortColumns сопоставляет значения параметра sort с выражениями для сортировки.
+var merchSortColumns = map[string]string{
+	"name":  "name",
+	"price": merchEffectivePrice,
+}
+
+// validateMerch проверяет название, цену и остаток товара. Nil-поля не проверяются.
+// Возвращает текст ошибки или пустую строку, если данные корректны.
+func validateMerch(name *string, price *int, stock *int) string {
+	if name != nil {
+		if *name == "" {
+			return "Название товара не может быть пустым"
+		}
+		if utf8.RuneCountInString(*name) > maxMerchNameLength {
+			return "Название товара должно быть не длиннее 64 символов"
+		}
+	}
+	if price != nil && *price <= 0 {
+		return "Цена товара должна быть положительной"
+	}
+	if stock != nil && *stock < 0 {
+		return "Остаток товара не может быть отрицательным"
+	}
+	return ""
+}
+
+func newMerchResponse(merch *models.Merch) MerchResponse {
+	return MerchResponse{
+		ID:         merch.ID,
+		Name:       merch.Name,
+		Price:      merch.Price,
+		Stock:      merch.Stock,
+		Deleted:    merch.DeletedAt.Valid,
+		CategoryID: merch.CategoryID,
+	}
+}
+
+// merchResponses формирует ответы для товаров вместе с их вариантами, метками
+// и категориями; каждые из них загружаются одним запросом.
+func (h *CatalogHandler) merchResponses(merches []models.Merch) ([]MerchResponse, error) {
+	ids := make([]uint, 0, len(merches))
+	for i := range merches {
+		ids = append(ids, merches[i].ID)
+	}
+	variants, err := services.MerchVariants(h.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := services.MerchTags(h.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := services.LoadCategoryTree(h.db)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]MerchResponse, 0, len(merches))
+	for i := range merches {
+		item := newMerchResponse(&merches[i])
+		for j := range variants[merches[i].ID] {
+			item.Variants = append(item.Variants, newVariantResponse(&merches[i], &variants[merches[i].ID][j]))
+		}
+		if item.CategoryID != nil {
+			item.Category = tree.Path(*item.CategoryID)
+		}
+		item.Tags = tags[merches[i].ID]
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// @Summary      Каталог мерча.
+// @Description  Возвращает товары каталога вместе с их вариантами постранично. Поддерживает фильтры по цене (у товаров с вариантами — по наименьшей цене варианта в наличии), названию, категории (вместе с вложенными категориями) и меткам, сортировку, отбор товаров в наличии и доступных по текущему балансу.
+// @Tags         Merch
+// @Produce      json
+// @Param        page query int false "Номер страницы (с 1)" default(1)
+// @Param        pageSize query int false "Размер страницы (не больше 100)" default(20)
+// @Param        minPrice query int false "Минимальная цена"
+// @Param        maxPrice query int false "Максимальная цена"
+// @Param        q query string false "Поиск по названию"
+// @Param        category query int false "ID категории; в выдачу попадают и товары вложенных категорий"
+// @Param        tag query []string false "Метка; при нескольких метках товар должен иметь все" collectionFormat(multi)
+// @Param        sort query string false "Поле сортировки" Enums(name, price) default(name)
+// @Param        order query string false "Направление сортировки" Enums(asc, desc) default(asc)
+// @Param        affordable query bool false "Только товары, которые можно купить на текущий баланс"
+// @Param        inStock query bool false "Только товары в наличии"
+// @Success      200 {object} MerchListResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /merch [get]
+// @Security     BearerAuth
+func (h *CatalogHandler) ListMerch(c *gin.Context) {
+	page, err := queryInt(c, "page", 1)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверный номер страницы"})
+		return
+	}
+	pageSize, err := queryInt(c, "pageSize", defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Размер страницы должен быть от 1 до 100"})
+		return
+	}
+
+	query := h.db.Model(&models.Merch{})
+
+	if c.Query("minPrice") != "" {
+		minPrice, err := queryInt(c, "minPrice", 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная минимальная цена"})
+			return
+		}
+		query = query.Where(merchEffectivePrice+" >= ?", minPrice)
+	}
+	if c.Query("maxPrice") != "" {
+		maxPrice, err := queryInt(c, "maxPrice", 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная максимальная цена"})
+			return
+		}
+		query = query.Where(merchEffectivePrice+" <= ?", maxPrice)
+	}
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(search))+"%")
+	}
+
+	if c.Query("category") != "" {
+		categoryID, err := strconv.ParseUint(c.Query("category"), 10, 64)
+		if err != nil || categoryID == 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная категория"})
+			return
+		}
+		tree, err := services.LoadCategoryTree(h.db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить каталог"})
+			return
+		}
+		query = query.Where("category_id IN ?", tree.Subtree(uint(categoryID)))
+	}
+	for _, tag := range c.QueryArray("tag") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM merch_tags WHERE merch_tags.merch_id = merches.id AND merch_tags.tag = ? AND merch_tags.deleted_at IS NULL)", tag)
+	}
+
+	if c.Query("affordable") != "" {
+		affordable, err := strconv.ParseBool(c.Query("affordable"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверное значение affordable"})
+			return
+		}
+		if affordable {
+			var user models.User
+			if err := h.db.Where("username = ?", c.GetString("username")).First(&user).Error; err != nil {
+				c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+				return
+			}
+			query = query.Where(merchEffectivePrice+" <= ?", user.Coins)
+		}
+	}
+
+	if c.Query("inStock") != "" {
+		inStock, err := strconv.ParseBool(c.Query("inStock"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверное значение inStock"})
+			return
+		}
+		if inStock {
+			// Товар с вариантами в наличии, если в наличии хотя бы один вариант
+			query = query.Where("CASE WHEN EXISTS (SELECT 1 FROM merch_variants WHERE merch_variants.merch_id = merches.id AND merch_variants.deleted_at IS NULL) " +
+				"THEN EXISTS (SELECT 1 FROM merch_variants WHERE merch_variants.merch_id = merches.id AND merch_variants.deleted_at IS NULL " +
+				"AND (merch_variants.stock IS NULL OR merch_variants.stock > 0)) " +
+				"ELSE merches.stock IS NULL OR merches.stock > 0 END")
+		}
+	}
+
+	sortColumn, ok := merchSortColumns[c.DefaultQuery("sort", "name")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Сортировка возможна только по name или price"})
+		return
+	}
+	order := c.DefaultQuery("order", "asc")
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Направление сортировки должно быть asc или desc"})
+		return
+	}
+
+	// Запрос используется дважды: для подсчёта и для выборки страницы
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить каталог"})
+		return
+	}
+
+	var merches []models.Merch
+	err = query.
+		Order(sortColumn + " " + order).
+		Order("id").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&merches).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить каталог"})
+		return
+	}
+
+	items, err := h.merchResponses(merches)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить каталог"})
+		return
+	}
+	c.JSON(http.StatusOK, MerchListResponse{
+		Items:    items,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// @Summary      Список товаров для администратора.
+// @Description  Возвращает все товары каталога, включая удалённые, вместе с вариантами.
+// @Tags         Admin
+// @Produce      json
+// @Success      200 {array} MerchResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch [get]
+// @Security     BearerAuth
+func (h *CatalogHandler) ListAllMerch(c *gin.Context) {
+	var merches []models.Merch
+	if err := h.db.Unscoped().Order("id").Find(&merches).Error; err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить каталог"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	items, err := h.merchResponses(merches)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить каталог"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary      Добавить товар в каталог.
+// @Description  Создаёт товар с уникальным названием и положительной ценой. Если остаток не передан, запас товара не ограничен.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateMerchRequest true "Товар"
+// @Success      201 {object} MerchResponse "Товар создан."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      409 {object} ErrorResponse "Товар с таким названием уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch [post]
+// @Security     BearerAuth
+func (h *CatalogHandler) CreateMerch(c *gin.Context) {
+	var req CreateMerchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if msg := validateMerch(&req.Name, &req.Price, req.Stock); msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	merch, err := services.CreateMerch(h.db, c.GetString("username"), req.Name, req.Price, req.Stock)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, newMerchResponse(merch))
+}
+
+// @Summary      Изменить товар.
+// @Description  Изменяет название, цену и (или) остаток товара. Переданные поля проверяются так же, как при создании.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        body body UpdateMerchRequest true "Изменяемые поля"
+// @Success      200 {object} MerchResponse "Товар изменён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар не найден."
+// @Failure      409 {object} ErrorResponse "Товар с таким названием уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id} [put]
+// @Security     BearerAuth
+func (h *CatalogHandler) UpdateMerch(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateMerchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		req.Name = &trimmed
+	}
+	if msg := validateMerch(req.Name, req.Price, req.Stock); msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	merch, err := services.UpdateMerch(h.db, c.GetString("username"), id, services.MerchChanges{
+		Name:  req.Name,
+		Price: req.Price,
+		Stock: req.Stock,
+	})
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newMerchResponse(merch))
+}
+
+// @Summary      Удалить товар.
+// @Description  Мягко удаляет товар: купить его больше нельзя, история покупок сохраняется. Товар можно восстановить.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Success      200 {null} nil "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id} [delete]
+// @Security     BearerAuth
+func (h *CatalogHandler) DeleteMerch(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteMerch(h.db, c.GetString("username"), id); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+}
+
+// @Summary      Восстановить удалённый товар.
+// @Description  Возвращает мягко удалённый товар в каталог.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Success      200 {object} MerchResponse "Товар восстановлен."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар не найден."
+// @Failure      409 {object} ErrorResponse "Товар не удалён."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/restore [post]
+// @Security     BearerAuth
+func (h *CatalogHandler) RestoreMerch(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	merch, err := services.RestoreMerch(h.db, c.GetString("username"), id)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newMerchResponse(merch))
+}
+
+// @Summary      Пополнить остаток товара.
+// @Description  Увеличивает остаток товара на указанное количество. Пополнение фиксируется в журнале изменений. Остаток товара с вариантами пополняется по вариантам.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        body body RestockRequest true "Количество"
+// @Success      200 {object} MerchResponse "Остаток пополнен."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар не найден."
+// @Failure      409 {object} ErrorResponse "Запас товара не ограничен или учитывается по вариантам."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/restock [post]
+// @Security     BearerAuth
+func (h *CatalogHandler) RestockMerch(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RestockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Quantity <= 0 {
+		resp := ErrorResponse{Error: "Количество должно быть положительным"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	merch, err := services.RestockMerch(h.db, c.GetString("username"), id, req.Quantity)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newMerchResponse(merch))
+}
+
+// @Summary      Отчёт о заканчивающихся товарах.
+// @Description  Возвращает товары и варианты товаров с ограниченным запасом, остаток которых не превышает порог. По умолчанию порог берётся из LOW_STOCK_THRESHOLD.
+// @Tags         Admin
+// @Produce      json
+// @Param        threshold query int false "Порог остатка"
+// @Success      200 {array} LowStockItem "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/low-stock [get]
+// @Security     BearerAuth
+func (h *CatalogHandler) GetLowStock(c *gin.Context) {
+	threshold, err := queryInt(c, "threshold", config.GetInt("LOW_STOCK_THRESHOLD", defaultLowStockThreshold))
+	if err != nil || threshold < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверный порог остатка"})
+		return
+	}
+
+	lowStock, err := services.LowStockMerch(h.db, threshold)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить отчёт об остатках"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	items := make([]LowStockItem, 0, len(lowStock))
+	for i := range lowStock {
+		item := LowStockItem{MerchResponse: newMerchResponse(&lowStock[i].Merch)}
+		if variant := lowStock[i].Variant; variant != nil {
+			item.VariantID = &variant.ID
+			item.Variant = variant.Name
+			item.Price = services.ItemPrice(&lowStock[i].Merch, variant)
+			item.Stock = variant.Stock
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary      История изменений товара.
+// @Description  Возвращает журнал изменений товара: действие, старое и новое значения и автора изменения.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Success      200 {array} CatalogChangeEntry "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/changes [get]
+// @Security     BearerAuth
+func (h *CatalogHandler) GetMerchChanges(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var changes []models.CatalogChange
+	if err := h.db.Where("merch_id = ?", id).Order("id").Find(&changes).Error; err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить историю изменений"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	entries := make([]CatalogChangeEntry, 0, len(changes))
+	for _, change := range changes {
+		entry := CatalogChangeEntry{
+			ID:        change.ID,
+			MerchID:   change.MerchID,
+			Action:    change.Action,
+			ChangedBy: change.ChangedBy,
+			CreatedAt: change.CreatedAt,
+		}
+		if change.OldValue != "" {
+			entry.OldValue = json.RawMessage(change.OldValue)
+		}
+		if change.NewValue != "" {
+			entry.NewValue = json.RawMessage(change.NewValue)
+		}
+		entries = append(entries, entry)
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Поместить товар в категорию.
+// @Description  Помещает товар в категорию или убирает его из категории, если categoryId равен null. Изменение фиксируется в журнале.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        body body SetMerchCategoryRequest true "Категория"
+// @Success      200 {object} MerchResponse "Категория товара изменена."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар или категория не найдены."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/category [put]
+// @Security     BearerAuth
+func (h *CatalogHandler) SetMerchCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SetMerchCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if _, err := services.SetMerchCategory(h.db, c.GetString("username"), id, req.CategoryID); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	h.respondMerch(c, id)
+}
+
+// @Summary      Задать метки товара.
+// @Description  Заменяет метки товара. Метки приводятся к нижнему регистру, повторы убираются; у товара может быть не больше 20 меток длиной до 32 символов. Изменение фиксируется в журнале.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        body body SetMerchTagsRequest true "Метки"
+// @Success      200 {object} MerchResponse "Метки товара изменены."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/tags [put]
+// @Security     BearerAuth
+func (h *CatalogHandler) SetMerchTags(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SetMerchTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	if _, err := services.SetMerchTags(h.db, c.GetString("username"), id, req.Tags); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	h.respondMerch(c, id)
+}
+
+// respondMerch отвечает товаром вместе с вариантами, категорией и метками.
+func (h *CatalogHandler) respondMerch(c *gin.Context, id uint) {
+	var merch models.Merch
+	if err := h.db.First(&merch, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить товар"})
+		return
+	}
+	items, err := h.merchResponses([]models.Merch{merch})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить товар"})
+		return
+	}
+	c.JSON(http.StatusOK, items[0])
+}
+
+// respondCatalogError преобразует ошибку изменения каталога в HTTP-ответ.
+func respondCatalogError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMerchNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Товар не найден"})
+	case errors.Is(err, services.ErrMerchNameTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Товар с таким названием уже существует"})
+	case errors.Is(err, services.ErrMerchNotDeleted):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Товар не удалён"})
+	case errors.Is(err, services.ErrUnlimitedStock):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Запас товара не ограничен"})
+	case errors.Is(err, services.ErrStockByVariants):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Остаток товара учитывается по вариантам, пополните нужный вариант"})
+	case errors.Is(err, services.ErrVariantNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Вариант товара не найден"})
+	case errors.Is(err, services.ErrVariantNameTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Вариант с таким названием уже существует"})
+	case errors.Is(err, services.ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Категория не найдена"})
+	case errors.Is(err, services.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Метки должны быть непустыми и не длиннее 32 символов, у товара может быть не больше 20 меток"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при изменении каталога"})
+	}
+}
+
+// queryInt разбирает целочисленный query-параметр; если параметр не передан, возвращает def.
+func queryInt(c *gin.Context, name string, def int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE.
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// parseIDParam разбирает числовой идентификатор из параметра пути.
+// При ошибке отвечает 400 и возвращает false.
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверный идентификатор"})
+		return 0, false
+	}
+	return uint(id), true
+}
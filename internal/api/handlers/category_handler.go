@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CategoryHandler struct {
+	db *gorm.DB
+}
+
+func NewCategoryHandler(db *gorm.DB) *CategoryHandler {
+	return &CategoryHandler{db: db}
+}
+
+type CategoryResponse struct {
+	ID       uint               `json:"id"`
+	Name     string             `json:"name"`
+	ParentID *uint              `json:"parentId,omitempty"`
+	Path     string             `json:"path"` // полное название, например "Одежда / Худи"
+	Children []CategoryResponse `json:"children,omitempty"`
+}
+
+type CreateCategoryRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parentId"` // если не передан, категория верхнего уровня
+}
+
+type UpdateCategoryRequest struct {
+	Name     *string `json:"name"`
+	ParentID *uint   `json:"parentId"` // 0 — перенести на верхний уровень
+}
+
+const maxCategoryNameLength = 64
+
+// validateCategoryName проверяет название категории. Возвращает текст ошибки
+// или пустую строку, если название корректно.
+func validateCategoryName(name string) string {
+	if name == "" {
+		return "Название категории не может быть пустым"
+	}
+	if utf8.RuneCountInString(name) > maxCategoryNameLength {
+		return "Название категории должно быть не длиннее 64 символов"
+	}
+	return ""
+}
+
+// @Summary      Категории каталога.
+// @Description  Возвращает дерево категорий каталога: категории верхнего уровня с вложенными категориями.
+// @Tags         Merch
+// @Produce      json
+// @Success      200 {array} CategoryResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /categories [get]
+// @Security     BearerAuth
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	tree, err := services.LoadCategoryTree(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить категории"})
+		return
+	}
+	c.JSON(http.StatusOK, categoryResponses(tree, tree.Roots()))
+}
+
+// @Summary      Создать категорию.
+// @Description  Создаёт категорию каталога. Если передан parentId, категория вкладывается в родительскую. Название должно быть уникальным среди категорий с тем же родителем.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateCategoryRequest true "Категория"
+// @Success      201 {object} CategoryResponse "Категория создана."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Родительская категория не найдена."
+// @Failure      409 {object} ErrorResponse "Категория с таким названием уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/categories [post]
+// @Security     BearerAuth
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if msg := validateCategoryName(req.Name); msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	category, err := services.CreateCategory(h.db, req.Name, req.ParentID)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	h.respondCategory(c, http.StatusCreated, category.ID)
+}
+
+// @Summary      Изменить категорию.
+// @Description  Переименовывает категорию и (или) переносит её в другую родительскую категорию вместе с вложенными категориями и товарами.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID категории"
+// @Param        body body UpdateCategoryRequest true "Изменяемые поля"
+// @Success      200 {object} CategoryResponse "Категория изменена."
+// @Failure      400 {object} ErrorResponse "Неверный запрос или категория вкладывается в себя."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Категория не найдена."
+// @Failure      409 {object} ErrorResponse "Категория с таким названием уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/categories/{id} [put]
+// @Security     BearerAuth
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		if msg := validateCategoryName(trimmed); msg != "" {
+			resp := ErrorResponse{Error: msg}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+		req.Name = &trimmed
+	}
+
+	_, err := services.UpdateCategory(h.db, id, services.CategoryChanges{Name: req.Name, ParentID: req.ParentID})
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	h.respondCategory(c, http.StatusOK, id)
+}
+
+// @Summary      Удалить категорию.
+// @Description  Удаляет пустую категорию. Категорию с товарами или вложенными категориями удалить нельзя.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID категории"
+// @Success      200 {null} nil "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Категория не найдена."
+// @Failure      409 {object} ErrorResponse "В категории есть товары или вложенные категории."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/categories/{id} [delete]
+// @Security     BearerAuth
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteCategory(h.db, id); err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+}
+
+// respondCategory отвечает категорией вместе с вложенными категориями.
+func (h *CategoryHandler) respondCategory(c *gin.Context, status int, id uint) {
+	tree, err := services.LoadCategoryTree(h.db)
+	if err != nil || tree.Category(id) == nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить категорию"})
+		return
+	}
+	c.JSON(status, categoryResponses(tree, []uint{id})[0])
+}
+
+// categoryResponses формирует ответы для категорий ids вместе со всеми вложенными категориями.
+func categoryResponses(tree *services.CategoryTree, ids []uint) []CategoryResponse {
+	responses := make([]CategoryResponse, 0, len(ids))
+	for _, id := range ids {
+		category := tree.Category(id)
+		responses = append(responses, CategoryResponse{
+			ID:       category.ID,
+			Name:     category.Name,
+			ParentID: category.ParentID,
+			Path:     tree.Path(id),
+			Children: categoryResponses(tree, tree.Children(id)),
+		})
+	}
+	return responses
+}
+
+// respondCategoryError преобразует ошибку изменения категорий в HTTP-ответ.
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Категория не найдена"})
+	case errors.Is(err, services.ErrCategoryNameTaken):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Категория с таким названием уже существует"})
+	case errors.Is(err, services.ErrCategoryCycle):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Категорию нельзя вложить в саму себя или в её подкатегорию"})
+	case errors.Is(err, services.ErrCategoryTooDeep):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Слишком глубокая вложенность категорий"})
+	case errors.Is(err, services.ErrCategoryInUse):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "В категории есть товары или вложенные категории"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при изменении категорий"})
+	}
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CoinRequestHandler struct {
+	db *gorm.DB
+}
+
+func NewCoinRequestHandler(db *gorm.DB) *CoinRequestHandler {
+	return &CoinRequestHandler{db: db}
+}
+
+type CreateCoinRequestRequest struct {
+	ToUser string `json:"toUser" binding:"required"` // у кого просят монеты
+	Amount int    `json:"amount" binding:"required"`
+	Note   string `json:"note"`
+}
+
+type CoinRequestResponse struct {
+	ID            uint       `json:"id"`
+	FromUser      string     `json:"fromUser"` // кто просит монеты
+	ToUser        string     `json:"toUser"`   // у кого просят монеты
+	Amount        int        `json:"amount"`
+	Note          string     `json:"note,omitempty"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	RespondedAt   *time.Time `json:"respondedAt,omitempty"`
+	TransactionID *uint      `json:"transactionId,omitempty"`
+}
+
+// Папки запросов монет относительно текущего пользователя.
+const (
+	coinRequestsIncoming = "incoming" // просят у пользователя
+	coinRequestsOutgoing = "outgoing" // просит пользователь
+)
+
+// @Summary      Запросить монеты.
+// @Description  Создаёт запрос монет к другому пользователю. Тот может оплатить, отклонить запрос или дождаться его истечения (срок задаётся COIN_REQUEST_TTL).
+// @Tags         CoinRequests
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateCoinRequestRequest true "Пользователь, сумма и комментарий"
+// @Success      201 {object} CoinRequestResponse "Запрос создан."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Пользователь не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /coin-requests [post]
+// @Security     BearerAuth
+func (h *CoinRequestHandler) CreateRequest(c *gin.Context) {
+	var req CreateCoinRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Amount <= 0 {
+		resp := ErrorResponse{Error: "Сумма должна быть положительной"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	// Комментарий запроса станет комментарием перевода, поэтому очищается так же
+	note, err := services.SanitizeMessage(req.Note)
+	if err != nil {
+		resp := ErrorResponse{Error: "Комментарий должен быть не длиннее 200 символов"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	requester, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var payer models.User
+	if err := h.db.Where("username = ?", req.ToUser).First(&payer).Error; err != nil {
+		resp := ErrorResponse{Error: "Пользователь не найден"}
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+	if payer.ID == requester.ID {
+		resp := ErrorResponse{Error: "Нельзя запросить монеты у самого себя"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	request, err := services.CreateCoinRequest(h.db, requester.ID, payer.ID, req.Amount, note)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось создать запрос монет"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	c.JSON(http.StatusCreated, newCoinRequestResponse(request, requester.Username, payer.Username))
+}
+
+// @Summary      Список запросов монет.
+// @Description  Возвращает входящие (у пользователя просят монеты) или исходящие (пользователь просит монеты) запросы, начиная с последних.
+// @Tags         CoinRequests
+// @Produce      json
+// @Param        box query string false "Входящие или исходящие" Enums(incoming, outgoing) default(incoming)
+// @Param        status query string false "Статус запроса" Enums(pending, accepted, declined, cancelled, expired)
+// @Success      200 {array} CoinRequestResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /coin-requests [get]
+// @Security     BearerAuth
+func (h *CoinRequestHandler) ListRequests(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Table("coin_requests").
+		Select("coin_requests.*, requesters.username AS from_user, payers.username AS to_user").
+		Joins("JOIN users requesters ON requesters.id = coin_requests.from_user_id").
+		Joins("JOIN users payers ON payers.id = coin_requests.to_user_id").
+		Where("coin_requests.deleted_at IS NULL")
+
+	switch c.DefaultQuery("box", coinRequestsIncoming) {
+	case coinRequestsIncoming:
+		query = query.Where("coin_requests.to_user_id = ?", user.ID)
+	case coinRequestsOutgoing:
+		query = query.Where("coin_requests.from_user_id = ?", user.ID)
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Параметр box должен быть incoming или outgoing"})
+		return
+	}
+
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.CoinRequestPending, models.CoinRequestAccepted, models.CoinRequestDeclined,
+			models.CoinRequestCancelled, models.CoinRequestExpired:
+			query = query.Where("coin_requests.status = ?", status)
+		default:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неизвестный статус запроса"})
+			return
+		}
+	}
+
+	// Статус просроченных запросов обновляется перед выборкой
+	if err := services.ExpireCoinRequests(h.db); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить запросы монет"})
+		return
+	}
+
+	var rows []struct {
+		models.CoinRequest
+		FromUser string
+		ToUser   string
+	}
+	if err := query.Order("coin_requests.id DESC").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить запросы монет"})
+		return
+	}
+
+	entries := make([]CoinRequestResponse, 0, len(rows))
+	for i := range rows {
+		entries = append(entries, newCoinRequestResponse(&rows[i].CoinRequest, rows[i].FromUser, rows[i].ToUser))
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Оплатить запрос монет.
+// @Description  Переводит запрошенную сумму тому, кто её запросил. Перевод выполняется так же, как в /sendCoin.
+// @Tags         CoinRequests
+// @Produce      json
+// @Param        id path int true "ID запроса"
+// @Success      200 {object} CoinRequestResponse "Запрос оплачен."
+// @Failure      400 {object} TransferLimitErrorResponse "Недостаточно средств или превышен лимит переводов."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Запрос не найден."
+// @Failure      409 {object} ErrorResponse "Запрос уже закрыт или истёк."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /coin-requests/{id}/accept [post]
+// @Security     BearerAuth
+func (h *CoinRequestHandler) AcceptRequest(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	// Начинаем транзакцию
+	tx := h.db.Begin()
+
+	request, _, err := services.AcceptCoinRequest(tx, user.ID, id)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrInsufficientFunds) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Недостаточно средств"})
+			return
+		}
+		if respondTransferLimitError(c, err) {
+			return
+		}
+		respondCoinRequestError(c, err)
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err := tx.Commit().Error; err != nil {
+		resp := ErrorResponse{Error: "Ошибка при сохранении данных"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	h.respondRequest(c, request)
+}
+
+// @Summary      Отклонить запрос монет.
+// @Description  Отклоняет входящий запрос монет.
+// @Tags         CoinRequests
+// @Produce      json
+// @Param        id path int true "ID запроса"
+// @Success      200 {object} CoinRequestResponse "Запрос отклонён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Запрос не найден."
+// @Failure      409 {object} ErrorResponse "Запрос уже закрыт или истёк."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /coin-requests/{id}/decline [post]
+// @Security     BearerAuth
+func (h *CoinRequestHandler) DeclineRequest(c *gin.Context) {
+	h.closeRequest(c, services.DeclineCoinRequest)
+}
+
+// @Summary      Отменить запрос монет.
+// @Description  Отменяет собственный исходящий запрос монет.
+// @Tags         CoinRequests
+// @Produce      json
+// @Param        id path int true "ID запроса"
+// @Success      200 {object} CoinRequestResponse "Запрос отменён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Запрос не найден."
+// @Failure      409 {object} ErrorResponse "Запрос уже закрыт или истёк."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /coin-requests/{id}/cancel [post]
+// @Security     BearerAuth
+func (h *CoinRequestHandler) CancelRequest(c *gin.Context) {
+	h.closeRequest(c, services.CancelCoinRequest)
+}
+
+func (h *CoinRequestHandler) closeRequest(c *gin.Context, close func(db *gorm.DB, userID, id uint) (*models.CoinRequest, error)) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	request, err := close(h.db, user.ID, id)
+	if err != nil {
+		respondCoinRequestError(c, err)
+		return
+	}
+	h.respondRequest(c, request)
+}
+
+// currentUser находит пользователя из JWT. При ошибке отвечает клиенту и возвращает false.
+func (h *CoinRequestHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Пользователь не найден"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// respondRequest отвечает запросом монет с именами обоих участников.
+func (h *CoinRequestHandler) respondRequest(c *gin.Context, request *models.CoinRequest) {
+	var users []models.User
+	if err := h.db.Where("id IN ?", []uint{request.FromUserID, request.ToUserID}).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить запрос монет"})
+		return
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+	c.JSON(http.StatusOK, newCoinRequestResponse(request, names[request.FromUserID], names[request.ToUserID]))
+}
+
+func newCoinRequestResponse(request *models.CoinRequest, fromUser, toUser string) CoinRequestResponse {
+	return CoinRequestResponse{
+		ID:            request.ID,
+		FromUser:      fromUser,
+		ToUser:        toUser,
+		Amount:        request.Amount,
+		Note:          request.Note,
+		Status:        request.Status,
+		CreatedAt:     request.CreatedAt,
+		ExpiresAt:     request.ExpiresAt,
+		RespondedAt:   request.RespondedAt,
+		TransactionID: request.TransactionID,
+	}
+}
+
+// respondCoinRequestError преобразует ошибку обработки запроса монет в HTTP-ответ.
+func respondCoinRequestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCoinRequestNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Запрос монет не найден"})
+	case errors.Is(err, services.ErrCoinRequestExpired):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Срок запроса монет истёк"})
+	case errors.Is(err, services.ErrCoinRequestNotPending):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Запрос монет уже закрыт"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при обработке запроса монет"})
+	}
+}
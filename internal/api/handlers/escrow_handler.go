@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EscrowHandler struct {
+	db *gorm.DB
+}
+
+func NewEscrowHandler(db *gorm.DB) *EscrowHandler {
+	return &EscrowHandler{db: db}
+}
+
+type CreateEscrowRequest struct {
+	ToUser  string `json:"toUser" binding:"required"`
+	Amount  int    `json:"amount" binding:"required"`
+	Message string `json:"message"`
+	// Timeout — срок подтверждения, например 48h; по умолчанию ESCROW_TIMEOUT.
+	Timeout string `json:"timeout"`
+}
+
+type EscrowResponse struct {
+	ID        uint      `json:"id"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	Status    string    `json:"status"` // pending, settled, released, held или rejected
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Папки переводов с удержанием относительно текущего пользователя.
+const (
+	escrowIncoming = "incoming" // пользователь — получатель
+	escrowOutgoing = "outgoing" // пользователь — отправитель
+)
+
+// @Summary      Перевод с удержанием.
+// @Description  Списывает монеты с отправителя и удерживает их до подтверждения. Получатель видит входящий перевод в статусе pending. Отправитель подтверждает перевод, получатель может от него отказаться; если перевод не подтверждён за timeout (по умолчанию ESCROW_TIMEOUT), монеты возвращаются отправителю.
+// @Tags         Escrow
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateEscrowRequest true "Получатель, сумма, комментарий и срок подтверждения"
+// @Success      201 {object} EscrowResponse "Перевод создан."
+// @Failure      400 {object} TransferLimitErrorResponse "Неверный запрос, недостаточно средств или превышен лимит переводов."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Получатель не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /escrow [post]
+// @Security     BearerAuth
+func (h *EscrowHandler) CreateEscrow(c *gin.Context) {
+	var req CreateEscrowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Amount <= 0 {
+		resp := ErrorResponse{Error: "Сумма перевода должна быть положительной"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	message, err := services.SanitizeMessage(req.Message)
+	if err != nil {
+		resp := ErrorResponse{Error: "Комментарий должен быть не длиннее 200 символов"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	var timeout time.Duration
+	if req.Timeout != "" {
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+			resp := ErrorResponse{Error: "Неверный срок подтверждения"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	sender, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var receiver models.User
+	if err := h.db.Where("username = ?", req.ToUser).First(&receiver).Error; err != nil {
+		resp := ErrorResponse{Error: "Получатель не найден"}
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+	if receiver.ID == sender.ID {
+		resp := ErrorResponse{Error: "Перевод самому себе не поддерживается"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	// Начинаем транзакцию
+	tx := h.db.Begin()
+
+	transaction, err := services.CreateEscrow(tx, sender.ID, receiver.ID, req.Amount, message, timeout)
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, services.ErrInsufficientFunds):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Недостаточно средств"})
+		case errors.Is(err, services.ErrInvalidEscrowTimeout):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Срок подтверждения больше допустимого"})
+		case respondTransferLimitError(c, err):
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при выполнении перевода"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err := tx.Commit().Error; err != nil {
+		resp := ErrorResponse{Error: "Ошибка при сохранении транзакции"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	h.respondEscrow(c, http.StatusCreated, transaction.ID)
+}
+
+// @Summary      Переводы с удержанием.
+// @Description  Возвращает входящие или исходящие переводы с удержанием, начиная с последних.
+// @Tags         Escrow
+// @Produce      json
+// @Param        box query string false "Входящие или исходящие" Enums(incoming, outgoing) default(incoming)
+// @Param        status query string false "Статус перевода" Enums(pending, settled, released, held, rejected)
+// @Success      200 {array} EscrowResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /escrow [get]
+// @Security     BearerAuth
+func (h *EscrowHandler) ListEscrows(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	query := h.escrowQuery()
+	switch c.DefaultQuery("box", escrowIncoming) {
+	case escrowIncoming:
+		query = query.Where("transactions.to_user_id = ?", user.ID)
+	case escrowOutgoing:
+		query = query.Where("transactions.from_user_id = ?", user.ID)
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Параметр box должен быть incoming или outgoing"})
+		return
+	}
+
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.TransactionPending, models.TransactionSettled, models.TransactionReleased,
+			models.TransactionHeld, models.TransactionRejected:
+			query = query.Where("transactions.status = ?", status)
+		default:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неизвестный статус перевода"})
+			return
+		}
+	}
+
+	var entries []EscrowResponse
+	if err := query.Order("transactions.id DESC").Scan(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить переводы"})
+		return
+	}
+	if entries == nil {
+		entries = []EscrowResponse{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Подтвердить перевод с удержанием.
+// @Description  Зачисляет удержанные монеты получателю. Подтвердить перевод может только отправитель.
+// @Tags         Escrow
+// @Produce      json
+// @Param        id path int true "ID перевода"
+// @Success      200 {object} EscrowResponse "Перевод подтверждён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Перевод не найден."
+// @Failure      409 {object} ErrorResponse "Перевод уже завершён."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /escrow/{id}/confirm [post]
+// @Security     BearerAuth
+func (h *EscrowHandler) ConfirmEscrow(c *gin.Context) {
+	h.complete(c, services.ConfirmEscrow)
+}
+
+// @Summary      Отказаться от перевода с удержанием.
+// @Description  Возвращает удержанные монеты отправителю. Отказаться от перевода может только получатель.
+// @Tags         Escrow
+// @Produce      json
+// @Param        id path int true "ID перевода"
+// @Success      200 {object} EscrowResponse "Монеты возвращены отправителю."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Перевод не найден."
+// @Failure      409 {object} ErrorResponse "Перевод уже завершён."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /escrow/{id}/release [post]
+// @Security     BearerAuth
+func (h *EscrowHandler) ReleaseEscrow(c *gin.Context) {
+	h.complete(c, services.ReleaseEscrow)
+}
+
+func (h *EscrowHandler) complete(c *gin.Context, action func(db *gorm.DB, userID, id uint) (*models.Transaction, error)) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if _, err := action(h.db, user.ID, id); err != nil {
+		switch {
+		case errors.Is(err, services.ErrEscrowNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Перевод не найден"})
+		case errors.Is(err, services.ErrEscrowNotPending):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Перевод уже завершён"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при завершении перевода"})
+		}
+		return
+	}
+	h.respondEscrow(c, http.StatusOK, id)
+}
+
+// escrowQuery возвращает запрос переводов с удержанием с именами участников.
+func (h *EscrowHandler) escrowQuery() *gorm.DB {
+	return h.db.Table("transactions").
+		Select("transactions.id, senders.username AS from_user, receivers.username AS to_user, transactions.amount, " +
+			"transactions.message, transactions.status, transactions.expires_at, transactions.created_at").
+		Joins("JOIN users senders ON senders.id = transactions.from_user_id").
+		Joins("JOIN users receivers ON receivers.id = transactions.to_user_id").
+		Where("transactions.deleted_at IS NULL AND transactions.expires_at IS NOT NULL")
+}
+
+func (h *EscrowHandler) respondEscrow(c *gin.Context, status int, id uint) {
+	var entry EscrowResponse
+	res := h.escrowQuery().Where("transactions.id = ?", id).Scan(&entry)
+	if res.Error != nil || res.RowsAffected == 0 {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить перевод"})
+		return
+	}
+	c.JSON(status, entry)
+}
+
+// currentUser находит пользователя из JWT. При ошибке отвечает клиенту и возвращает false.
+func (h *EscrowHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Пользователь не найден"})
+		return nil, false
+	}
+	return &user, true
+}
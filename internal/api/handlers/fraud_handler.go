@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FraudHandler struct {
+	db *gorm.DB
+}
+
+func NewFraudHandler(db *gorm.DB) *FraudHandler {
+	return &FraudHandler{db: db}
+}
+
+type ReviewFraudFlagRequest struct {
+	Comment string `json:"comment"`
+}
+
+type FraudFlagResponse struct {
+	ID                uint       `json:"id"`
+	TransactionID     uint       `json:"transactionId"`
+	FromUser          string     `json:"fromUser"`
+	ToUser            string     `json:"toUser"`
+	Amount            int        `json:"amount"`
+	TransactionStatus string     `json:"transactionStatus"` // settled, held, pending, released или rejected
+	Rules             []string   `json:"rules"`
+	Details           string     `json:"details"`
+	Status            string     `json:"status"`
+	ReviewedBy        string     `json:"reviewedBy,omitempty"`
+	ReviewedAt        *time.Time `json:"reviewedAt,omitempty"`
+	Comment           string     `json:"comment,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// @Summary      Очередь подозрительных переводов.
+// @Description  Возвращает переводы, на которых сработали правила обнаружения мошенничества, по умолчанию — ожидающие проверки.
+// @Tags         Admin
+// @Produce      json
+// @Param        status query string false "Статус проверки" Enums(open, approved, rejected) default(open)
+// @Success      200 {array} FraudFlagResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/fraud-flags [get]
+// @Security     BearerAuth
+func (h *FraudHandler) ListFlags(c *gin.Context) {
+	status := c.DefaultQuery("status", models.FraudFlagOpen)
+	switch status {
+	case models.FraudFlagOpen, models.FraudFlagApproved, models.FraudFlagRejected:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неизвестный статус проверки"})
+		return
+	}
+
+	flags, err := h.loadFlags(h.db.Where("fraud_flags.status = ?", status))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить подозрительные переводы"})
+		return
+	}
+	c.JSON(http.StatusOK, flags)
+}
+
+// @Summary      Одобрить подозрительный перевод.
+// @Description  Признаёт перевод законным. Задержанный перевод зачисляется получателю.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID записи в очереди"
+// @Param        body body ReviewFraudFlagRequest false "Комментарий"
+// @Success      200 {object} FraudFlagResponse "Перевод одобрен."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Запись не найдена."
+// @Failure      409 {object} ErrorResponse "Перевод уже рассмотрен."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/fraud-flags/{id}/approve [post]
+// @Security     BearerAuth
+func (h *FraudHandler) ApproveFlag(c *gin.Context) {
+	h.review(c, services.ApproveFraudFlag)
+}
+
+// @Summary      Отклонить подозрительный перевод.
+// @Description  Признаёт перевод мошенническим. Задержанный перевод отклоняется, монеты возвращаются отправителю; уже зачисленный перевод можно отменить через /admin/transactions/{id}/reverse.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID записи в очереди"
+// @Param        body body ReviewFraudFlagRequest false "Комментарий"
+// @Success      200 {object} FraudFlagResponse "Перевод отклонён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Запись не найдена."
+// @Failure      409 {object} ErrorResponse "Перевод уже рассмотрен."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/fraud-flags/{id}/reject [post]
+// @Security     BearerAuth
+func (h *FraudHandler) RejectFlag(c *gin.Context) {
+	h.review(c, services.RejectFraudFlag)
+}
+
+func (h *FraudHandler) review(c *gin.Context, decide func(db *gorm.DB, actor string, id uint, comment string) (*models.FraudFlag, error)) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req ReviewFraudFlagRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := ErrorResponse{Error: "Неверный запрос"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	if _, err := decide(h.db, c.GetString("username"), id, strings.TrimSpace(req.Comment)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrFraudFlagNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Запись не найдена"})
+		case errors.Is(err, services.ErrFraudFlagReviewed):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Перевод уже рассмотрен"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при рассмотрении перевода"})
+		}
+		return
+	}
+
+	flags, err := h.loadFlags(h.db.Where("fraud_flags.id = ?", id))
+	if err != nil || len(flags) == 0 {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить запись"})
+		return
+	}
+	c.JSON(http.StatusOK, flags[0])
+}
+
+// loadFlags загружает записи очереди вместе с переводом и именами его участников.
+func (h *FraudHandler) loadFlags(query *gorm.DB) ([]FraudFlagResponse, error) {
+	var rows []struct {
+		models.FraudFlag
+		FromUser          string
+		ToUser            string
+		Amount            int
+		TransactionStatus string
+	}
+	err := query.Model(&models.FraudFlag{}).
+		Select("fraud_flags.*, senders.username AS from_user, receivers.username AS to_user, " +
+			"transactions.amount, transactions.status AS transaction_status").
+		Joins("JOIN transactions ON transactions.id = fraud_flags.transaction_id").
+		Joins("JOIN users senders ON senders.id = transactions.from_user_id").
+		Joins("JOIN users receivers ON receivers.id = transactions.to_user_id").
+		Order("fraud_flags.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]FraudFlagResponse, 0, len(rows))
+	for _, row := range rows {
+		flags = append(flags, FraudFlagResponse{
+			ID:                row.ID,
+			TransactionID:     row.TransactionID,
+			FromUser:          row.FromUser,
+			ToUser:            row.ToUser,
+			Amount:            row.Amount,
+			TransactionStatus: row.TransactionStatus,
+			Rules:             strings.Split(row.Rules, ","),
+			Details:           row.Details,
+			Status:            row.Status,
+			ReviewedBy:        row.ReviewedBy,
+			ReviewedAt:        row.ReviewedAt,
+			Comment:           row.Comment,
+			CreatedAt:         row.CreatedAt,
+		})
+	}
+	return flags, nil
+}
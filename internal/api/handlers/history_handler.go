@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HistoryHandler struct {
+	db *gorm.DB
+}
+
+func NewHistoryHandler(db *gorm.DB) *HistoryHandler {
+	return &HistoryHandler{db: db}
+}
+
+type HistoryResponse struct {
+	Items []CoinHistoryEntry `json:"items"`
+	// NextCursor передаётся в параметре cursor для получения следующей страницы; пуст на последней странице.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Направления перевода относительно текущего пользователя.
+const (
+	directionSent     = "sent"
+	directionReceived = "received"
+)
+
+// Параметры постраничного вывода истории.
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// historyRow — перевод вместе с именами отправителя и получателя.
+type historyRow struct {
+	ID         uint
+	CreatedAt  time.Time
+	FromUserID uint
+	ToUserID   uint
+	FromUser   string
+	ToUser     string
+	Amount     int
+	Message    string
+	ReversalOf *uint
+	Reversed   bool
+	Status     string
+}
+
+// historyQuery возвращает запрос переводов пользователя с именами участников.
+// Имена присоединяются в том же запросе, а признак отмены вычисляется подзапросом.
+func historyQuery(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Table("transactions").
+		Select("transactions.id, transactions.created_at, transactions.from_user_id, transactions.to_user_id, "+
+			"senders.username AS from_user, receivers.username AS to_user, transactions.amount, transactions.message, transactions.reversal_of, transactions.status, "+
+			"EXISTS (SELECT 1 FROM transactions reversals WHERE reversals.reversal_of = transactions.id AND reversals.deleted_at IS NULL) AS reversed").
+		Joins("JOIN users senders ON senders.id = transactions.from_user_id").
+		Joins("JOIN users receivers ON receivers.id = transactions.to_user_id").
+		Where("transactions.deleted_at IS NULL").
+		Where("transactions.from_user_id = ? OR transactions.to_user_id = ?", userID, userID)
+}
+
+// entry преобразует строку истории в запись с точки зрения пользователя userID:
+// для отправленного перевода указывается получатель, для полученного — отправитель.
+func (row *historyRow) entry(userID uint) CoinHistoryEntry {
+	entry := CoinHistoryEntry{
+		ID:        row.ID,
+		Amount:    row.Amount,
+		Message:   row.Message,
+		CreatedAt: row.CreatedAt,
+		Reversal:  row.ReversalOf != nil,
+		Reversed:  row.Reversed,
+	}
+	if row.Status != models.TransactionSettled {
+		entry.Status = row.Status
+	}
+	if row.FromUserID == userID {
+		entry.ToUser = row.ToUser
+	} else {
+		entry.FromUser = row.FromUser
+	}
+	return entry
+}
+
+// @Summary      История переводов.
+// @Description  Возвращает переводы пользователя постранично, начиная с последних. Поддерживает фильтры по дате, направлению, второй стороне перевода, сумме и комментарию. Для следующей страницы передайте nextCursor из ответа в параметре cursor.
+// @Tags         Info
+// @Produce      json
+// @Param        cursor query string false "Курсор следующей страницы"
+// @Param        limit query int false "Размер страницы (не больше 100)" default(20)
+// @Param        from query string false "Начало периода (RFC 3339 или YYYY-MM-DD)"
+// @Param        to query string false "Конец периода (RFC 3339 или YYYY-MM-DD включительно)"
+// @Param        direction query string false "Направление" Enums(sent, received)
+// @Param        counterparty query string false "Имя второй стороны перевода"
+// @Param        minAmount query int false "Минимальная сумма"
+// @Param        maxAmount query int false "Максимальная сумма"
+// @Param        q query string false "Поиск по тексту комментария"
+// @Param        hasMessage query bool false "Только переводы с комментарием (true) или без него (false)"
+// @Success      200 {object} HistoryResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /history [get]
+// @Security     BearerAuth
+func (h *HistoryHandler) GetHistory(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+		return
+	}
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось найти пользователя"})
+		return
+	}
+
+	limit, err := queryInt(c, "limit", defaultHistoryLimit)
+	if err != nil || limit < 1 || limit > maxHistoryLimit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Размер страницы должен быть от 1 до 100"})
+		return
+	}
+
+	query := historyQuery(h.db, user.ID)
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		lastID, ok := decodeHistoryCursor(cursor)
+		if !ok {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверный курсор"})
+			return
+		}
+		query = query.Where("transactions.id < ?", lastID)
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, _, ok := parseHistoryTime(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная дата начала периода"})
+			return
+		}
+		query = query.Where("transactions.created_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, ok := parseHistoryTime(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная дата конца периода"})
+			return
+		}
+		if dateOnly {
+			// Дата без времени включает весь день
+			query = query.Where("transactions.created_at < ?", to.AddDate(0, 0, 1))
+		} else {
+			query = query.Where("transactions.created_at <= ?", to)
+		}
+	}
+
+	switch c.Query("direction") {
+	case "":
+	case directionSent:
+		query = query.Where("transactions.from_user_id = ?", user.ID)
+	case directionReceived:
+		query = query.Where("transactions.to_user_id = ?", user.ID)
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Направление должно быть sent или received"})
+		return
+	}
+
+	if counterparty := c.Query("counterparty"); counterparty != "" {
+		query = query.Where(
+			"(transactions.from_user_id = ? AND receivers.username = ?) OR (transactions.to_user_id = ? AND senders.username = ?)",
+			user.ID, counterparty, user.ID, counterparty)
+	}
+
+	if c.Query("minAmount") != "" {
+		minAmount, err := queryInt(c, "minAmount", 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная минимальная сумма"})
+			return
+		}
+		query = query.Where("transactions.amount >= ?", minAmount)
+	}
+	if c.Query("maxAmount") != "" {
+		maxAmount, err := queryInt(c, "maxAmount", 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверная максимальная сумма"})
+			return
+		}
+		query = query.Where("transactions.amount <= ?", maxAmount)
+	}
+
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		query = query.Where("LOWER(transactions.message) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(search))+"%")
+	}
+	if c.Query("hasMessage") != "" {
+		hasMessage, err := strconv.ParseBool(c.Query("hasMessage"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неверное значение hasMessage"})
+			return
+		}
+		if hasMessage {
+			query = query.Where("transactions.message <> ''")
+		} else {
+			query = query.Where("transactions.message = ''")
+		}
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	var rows []historyRow
+	if err := query.Order("transactions.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить историю переводов"})
+		return
+	}
+
+	resp := HistoryResponse{Items: make([]CoinHistoryEntry, 0, limit)}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		resp.NextCursor = encodeHistoryCursor(rows[limit-1].ID)
+	}
+	for i := range rows {
+		entry := rows[i].entry(user.ID)
+		if rows[i].FromUserID == user.ID {
+			entry.Direction = directionSent
+		} else {
+			entry.Direction = directionReceived
+		}
+		resp.Items = append(resp.Items, entry)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// parseHistoryTime разбирает момент времени в формате RFC 3339 или дату YYYY-MM-DD.
+// dateOnly сообщает, что время не было указано.
+func parseHistoryTime(value string) (t time.Time, dateOnly bool, ok bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, true
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, true
+	}
+	return time.Time{}, false, false
+}
+
+// Курсор скрывает от клиента, что страницы разбиваются по идентификатору перевода.
+func encodeHistoryCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeHistoryCursor(cursor string) (uint, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -17,25 +20,80 @@ func NewInfoHandler(db *gorm.DB) *InfoHandler {
 }
 
 type InfoResponse struct {
-	Coins       int             `json:"coins"`
-	Inventory   []InventoryItem `json:"inventory"`
-	CoinHistory CoinHistory     `json:"coinHistory"`
+	Coins     int `json:"coins"`
+	Available int `json:"available"` // монеты, которые можно потратить; совпадает с coins
+	// Отправленные монеты, удерживаемые до подтверждения перевода или проверки администратором
+	Held int `json:"held"`
+	// Входящие переводы с удержанием, ещё не подтверждённые отправителем
+	PendingIncoming int             `json:"pendingIncoming"`
+	Debt            int             `json:"debt,omitempty"` // долг после отмены перевода; погашается из будущих зачислений
+	Inventory       []InventoryItem `json:"inventory"`
+	// Инвентарь, сгруппированный по категориям товаров; заполняется при groupBy=category
+	InventoryByCategory []InventoryCategory `json:"inventoryByCategory,omitempty"`
+	CoinHistory         CoinHistory         `json:"coinHistory"`
+	Gifts               Gifts               `json:"gifts"`
+	// Монеты, которые сгорят в ближайшее время (COIN_EXPIRY_NOTICE), по срокам действия
+	ExpiringCoins []services.ExpiringCoins `json:"expiringCoins"`
 }
 
 type InventoryItem struct {
 	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"` // вариант товара; не заполняется для товаров без вариантов
 	Quantity int    `json:"quantity"`
+
+	CategoryID *uint  `json:"categoryId,omitempty"`
+	Category   string `json:"category,omitempty"` // полное название категории товара
+}
+
+// InventoryCategory — предметы инвентаря из одной категории. Для товаров без категории
+// categoryId и category не заполняются.
+type InventoryCategory struct {
+	CategoryID *uint           `json:"categoryId,omitempty"`
+	Category   string          `json:"category,omitempty"`
+	Quantity   int             `json:"quantity"`
+	Items      []InventoryItem `json:"items"`
 }
 
 type CoinHistory struct {
 	Received []CoinHistoryEntry `json:"received"`
 	Sent     []CoinHistoryEntry `json:"sent"`
+	Refunds  []RefundEntry      `json:"refunds"`
+}
+
+// RefundEntry — монеты, возвращённые за одобренный возврат покупки.
+type RefundEntry struct {
+	PurchaseID uint   `json:"purchaseId"`
+	Item       string `json:"item"`
+	Amount     int    `json:"amount"`
+}
+
+// Gifts — предметы, подаренные пользователем и подаренные ему.
+type Gifts struct {
+	Given    []GiftEntry `json:"given"`
+	Received []GiftEntry `json:"received"`
+}
+
+// GiftEntry — подарок; для подаренного указывается получатель, для полученного — даритель.
+type GiftEntry struct {
+	PurchaseID uint      `json:"purchaseId"`
+	Item       string    `json:"item"`
+	FromUser   string    `json:"fromUser,omitempty"`
+	ToUser     string    `json:"toUser,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
 
 type CoinHistoryEntry struct {
-	FromUser string `json:"fromUser,omitempty"`
-	ToUser   string `json:"toUser,omitempty"`
-	Amount   int    `json:"amount"`
+	ID        uint      `json:"id"`
+	FromUser  string    `json:"fromUser,omitempty"`
+	ToUser    string    `json:"toUser,omitempty"`
+	Direction string    `json:"direction,omitempty"` // sent или received; заполняется в /history
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"` // комментарий отправителя
+	CreatedAt time.Time `json:"createdAt"`
+	Reversal  bool      `json:"reversal,omitempty"` // компенсирующий перевод, отменяющий другой перевод
+	Reversed  bool      `json:"reversed,omitempty"` // перевод отменён администратором
+	Status    string    `json:"status,omitempty"`   // pending, held, released или rejected; для зачисленных переводов не заполняется
 }
 
 type ErrorResponse struct {
@@ -43,9 +101,10 @@ type ErrorResponse struct {
 }
 
 // @Summary      Получить информацию о монетах, инвентаре и истории транзакций.
-// @Description  Возвращает баланс монет, инвентарь и список транзакций.
+// @Description  Возвращает баланс монет (доступные и удерживаемые), инвентарь (при groupBy=category — ещё и по категориям товаров), список транзакций, подарки и монеты, которые скоро сгорят.
 // @Tags         Info
 // @Produce      json
+// @Param        groupBy query string false "Дополнительно сгруппировать инвентарь" Enums(category)
 // @Success      200 {object} InfoResponse "Успешный ответ."
 // @Failure      400 {object} ErrorResponse "Неверный запрос."
 // @Failure      401 {object} ErrorResponse "Неавторизован."
@@ -67,38 +126,46 @@ func (h *InfoHandler) GetInfo(c *gin.Context) {
 		return
 	}
 
-	// Извлекаем пользователя из БД с покупками и товарами
+	groupBy := c.Query("groupBy")
+	if groupBy != "" && groupBy != "category" {
+		resp := ErrorResponse{Error: "Группировка возможна только по category"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
 	var user models.User
-	if err := h.Db.Preload("Purchases.Merch").Where("username = ?", username).First(&user).Error; err != nil {
+	if err := h.Db.Where("username = ?", username).First(&user).Error; err != nil {
 		resp := ErrorResponse{Error: "Не удалось найти пользователя"}
 		c.JSON(http.StatusInternalServerError, resp)
 		return
 	}
 
-	// Формируем инвентарь на основе покупок
-	inventoryMap := make(map[string]int)
-	for _, purchase := range user.Purchases {
-		inventoryMap[purchase.Merch.Name]++
-	}
+	// Инвентарь считается агрегатом в БД по товарам и их вариантам; возвращённые покупки в него не попадают
 	var inventory []InventoryItem
-	for merchName, quantity := range inventoryMap {
-		inventory = append(inventory, InventoryItem{
-			Type:     merchName,
-			Quantity: quantity,
-		})
+	err := h.Db.Table("purchases").
+		Select("merches.name AS type, COALESCE(merch_variants.name, '') AS variant, merches.category_id, COUNT(*) AS quantity").
+		Joins("JOIN merches ON merches.id = purchases.merch_id").
+		Joins("LEFT JOIN merch_variants ON merch_variants.id = purchases.variant_id").
+		Where("purchases.user_id = ? AND purchases.returned_at IS NULL AND purchases.deleted_at IS NULL", user.ID).
+		Group("merches.name, merches.category_id, merch_variants.name").
+		Order("merches.name, variant").
+		Scan(&inventory).Error
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить инвентарь"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
 	}
-
-	// Получаем переводы: отправленные и полученные
-	var sentTxs []models.Transaction
-	var receivedTxs []models.Transaction
-
-	if err := h.Db.Where("from_user_id = ?", user.ID).Find(&sentTxs).Error; err != nil {
-		resp := ErrorResponse{Error: "Не удалось получить отправленные транзакции"}
+	inventoryByCategory, err := h.categorizeInventory(inventory, groupBy == "category")
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить категории инвентаря"}
 		c.JSON(http.StatusInternalServerError, resp)
 		return
 	}
-	if err := h.Db.Where("to_user_id = ?", user.ID).Find(&receivedTxs).Error; err != nil {
-		resp := ErrorResponse{Error: "Не удалось получить полученные транзакции"}
+
+	// Переводы загружаются одним запросом вместе с именами участников
+	var rows []historyRow
+	if err := historyQuery(h.Db, user.ID).Order("transactions.id").Scan(&rows).Error; err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить историю переводов"}
 		c.JSON(http.StatusInternalServerError, resp)
 		return
 	}
@@ -107,34 +174,137 @@ func (h *InfoHandler) GetInfo(c *gin.Context) {
 	coinHistory := CoinHistory{
 		Received: []CoinHistoryEntry{},
 		Sent:     []CoinHistoryEntry{},
+		Refunds:  []RefundEntry{},
 	}
-
-	for _, tx := range sentTxs {
-		var toUser models.User
-		if err := h.Db.First(&toUser, tx.ToUserID).Error; err != nil {
-			continue
+	held, pendingIncoming := 0, 0
+	for i := range rows {
+		switch {
+		case rows[i].FromUserID == user.ID && (rows[i].Status == models.TransactionPending || rows[i].Status == models.TransactionHeld):
+			held += rows[i].Amount
+		case rows[i].ToUserID == user.ID && rows[i].Status == models.TransactionPending:
+			pendingIncoming += rows[i].Amount
+		}
+		if rows[i].FromUserID == user.ID {
+			coinHistory.Sent = append(coinHistory.Sent, rows[i].entry(user.ID))
+		} else {
+			coinHistory.Received = append(coinHistory.Received, rows[i].entry(user.ID))
 		}
-		coinHistory.Sent = append(coinHistory.Sent, CoinHistoryEntry{
-			ToUser: toUser.Username,
-			Amount: tx.Amount,
-		})
 	}
 
-	for _, tx := range receivedTxs {
-		var fromUser models.User
-		if err := h.Db.First(&fromUser, tx.FromUserID).Error; err != nil {
-			continue
+	// Возвраты монет за одобренные возвраты покупок
+	err = h.Db.Model(&models.ReturnRequest{}).
+		Select("return_requests.purchase_id, merches.name AS item, return_requests.amount").
+		Joins("JOIN purchases ON purchases.id = return_requests.purchase_id").
+		Joins("JOIN merches ON merches.id = purchases.merch_id").
+		Where("return_requests.user_id = ? AND return_requests.status = ?", user.ID, models.ReturnStatusApproved).
+		Order("return_requests.reviewed_at").
+		Scan(&coinHistory.Refunds).Error
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить возвраты"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	// Подаренные и полученные в подарок предметы вместе с именами дарителя и получателя
+	var giftRows []struct {
+		GiftEntry
+		UserID uint
+	}
+	err = h.Db.Table("purchases").
+		Select("purchases.id AS purchase_id, merches.name AS item, givers.username AS from_user, "+
+			"recipients.username AS to_user, purchases.gift_note AS note, purchases.created_at, purchases.user_id").
+		Joins("JOIN merches ON merches.id = purchases.merch_id").
+		Joins("JOIN users givers ON givers.id = purchases.gift_from_id").
+		Joins("JOIN users recipients ON recipients.id = purchases.user_id").
+		Where("purchases.deleted_at IS NULL").
+		Where("purchases.user_id = ? OR purchases.gift_from_id = ?", user.ID, user.ID).
+		Order("purchases.id").
+		Scan(&giftRows).Error
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить подарки"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	gifts := Gifts{Given: []GiftEntry{}, Received: []GiftEntry{}}
+	for _, row := range giftRows {
+		if row.UserID == user.ID {
+			row.ToUser = ""
+			gifts.Received = append(gifts.Received, row.GiftEntry)
+		} else {
+			row.FromUser = ""
+			gifts.Given = append(gifts.Given, row.GiftEntry)
 		}
-		coinHistory.Received = append(coinHistory.Received, CoinHistoryEntry{
-			FromUser: fromUser.Username,
-			Amount:   tx.Amount,
-		})
+	}
+
+	expiring, err := services.UpcomingExpirations(h.Db, user.ID, time.Now())
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить сроки действия монет"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
 	}
 
 	resp := InfoResponse{
-		Coins:       user.Coins,
-		Inventory:   inventory,
-		CoinHistory: coinHistory,
+		Coins:           user.Coins,
+		Available:       user.Coins,
+		Held:            held,
+		PendingIncoming: pendingIncoming,
+		Debt:            user.Debt,
+		Inventory:       inventory,
+		CoinHistory:     coinHistory,
+		Gifts:           gifts,
+		ExpiringCoins:   expiring,
+
+		InventoryByCategory: inventoryByCategory,
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// categorizeInventory заполняет у предметов инвентаря полные названия категорий и, если
+// group равен true, группирует предметы по категориям в порядке их названий; предметы
+// без категории идут последними. Категории загружаются одним запросом и только если
+// они нужны.
+func (h *InfoHandler) categorizeInventory(inventory []InventoryItem, group bool) ([]InventoryCategory, error) {
+	categorized := false
+	for i := range inventory {
+		categorized = categorized || inventory[i].CategoryID != nil
+	}
+	if categorized {
+		tree, err := services.LoadCategoryTree(h.Db)
+		if err != nil {
+			return nil, err
+		}
+		for i := range inventory {
+			if inventory[i].CategoryID != nil {
+				inventory[i].Category = tree.Path(*inventory[i].CategoryID)
+			}
+		}
+	}
+	if !group {
+		return nil, nil
+	}
+
+	// Ключ 0 соответствует товарам без категории: ID категорий начинаются с 1
+	groups := []InventoryCategory{}
+	index := map[uint]int{}
+	for _, item := range inventory {
+		var key uint
+		if item.CategoryID != nil {
+			key = *item.CategoryID
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, InventoryCategory{CategoryID: item.CategoryID, Category: item.Category})
+		}
+		groups[i].Quantity += item.Quantity
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if (groups[i].CategoryID == nil) != (groups[j].CategoryID == nil) {
+			return groups[j].CategoryID == nil
+		}
+		return groups[i].Category < groups[j].Category
+	})
+	return groups, nil
+}
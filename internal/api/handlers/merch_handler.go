@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -16,16 +19,29 @@ func NewMerchHandler(db *gorm.DB) *MerchHandler {
 	return &MerchHandler{db: db}
 }
 
+// BuyItemRequest — необязательное тело POST /buy/{item} для покупки в подарок или выбора варианта.
+type BuyItemRequest struct {
+	Recipient string `json:"recipient"` // имя получателя подарка
+	Note      string `json:"note"`      // поздравление, до 200 символов
+	Variant   string `json:"variant"`   // название варианта; можно передать и параметром запроса variant
+}
+
 // @Summary      Купить предмет за монеты.
-// @Description  Списывает монеты и добавляет предмет в инвентарь.
+// @Description  Списывает монеты, уменьшает остаток товара на складе и добавляет предмет в инвентарь. Если в POST-запросе указан recipient, предмет покупается в подарок: монеты списываются с покупателя, а предмет попадает в инвентарь получателя. У товара с вариантами (размер, цвет) нужно выбрать вариант параметром variant: остаток уменьшается у варианта, цена берётся из варианта, если она задана.
 // @Tags         Merch
+// @Accept       json
 // @Produce      json
 // @Param        item path string true "Название товара"
+// @Param        variant query string false "Название варианта товара"
+// @Param        body body BuyItemRequest false "Получатель подарка, поздравление и вариант (только для POST)"
 // @Success 	 200 {null} nil "Успешный ответ"
 // @Failure      400 {object} ErrorResponse "Неверный запрос."
 // @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Товар, вариант или получатель не найден."
+// @Failure      409 {object} ErrorResponse "Товар закончился."
 // @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
 // @Router       /buy/{item} [get]
+// @Router       /buy/{item} [post]
 // @Security     BearerAuth
 func (h *MerchHandler) BuyItem(c *gin.Context) {
 	item := c.Param("item")
@@ -35,6 +51,25 @@ func (h *MerchHandler) BuyItem(c *gin.Context) {
 		return
 	}
 
+	// Тело запроса необязательно: без него предмет покупается себе
+	var req BuyItemRequest
+	if c.Request != nil && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := ErrorResponse{Error: "Неверный запрос"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+	if req.Variant == "" {
+		req.Variant = c.Query("variant")
+	}
+	note, err := services.SanitizeMessage(req.Note)
+	if err != nil {
+		resp := ErrorResponse{Error: "Поздравление должно быть не длиннее 200 символов"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
 	// Получаем имя пользователя из JWT
 	usernameI, exists := c.Get("username")
 	if !exists {
@@ -56,6 +91,18 @@ func (h *MerchHandler) BuyItem(c *gin.Context) {
 		c.JSON(http.StatusNotFound, resp)
 		return
 	}
+	variant, err := services.SelectVariant(h.db, &merch, strings.TrimSpace(req.Variant))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVariantRequired):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Выберите вариант товара"})
+		case errors.Is(err, services.ErrVariantNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Вариант товара не найден"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить вариант товара"})
+		}
+		return
+	}
 
 	// Начинаем транзакцию
 	tx := h.db.Begin()
@@ -69,30 +116,44 @@ func (h *MerchHandler) BuyItem(c *gin.Context) {
 		return
 	}
 
-	// Проверяем баланс
-	if user.Coins < merch.Price {
-		tx.Rollback()
-		resp := ErrorResponse{Error: "Недостаточно средств"}
-		c.JSON(http.StatusBadRequest, resp)
-		return
-	}
-
-	// Обновляем баланс пользователя
-	if err := tx.Model(&user).Update("coins", user.Coins-merch.Price).Error; err != nil {
-		tx.Rollback()
-		resp := ErrorResponse{Error: "Ошибка при списании монет"}
-		c.JSON(http.StatusInternalServerError, resp)
-		return
+	// Получатель подарка
+	var recipient *models.User
+	if req.Recipient != "" {
+		recipient = &models.User{}
+		if err := tx.Where("username = ?", req.Recipient).First(recipient).Error; err != nil {
+			tx.Rollback()
+			resp := ErrorResponse{Error: "Получатель не найден"}
+			c.JSON(http.StatusNotFound, resp)
+			return
+		}
+		if recipient.ID == user.ID {
+			tx.Rollback()
+			resp := ErrorResponse{Error: "Нельзя подарить предмет самому себе"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
 	}
 
-	// Создаем запись о покупке
-	purchase := models.Purchase{
-		UserID:  user.ID,
-		MerchID: merch.ID,
+	// Списываем монеты условным UPDATE, чтобы параллельные покупки не увели баланс в минус,
+	// создаём запись о покупке и отражаем её в журнале
+	if recipient != nil {
+		_, err = services.BuyGift(tx, user.ID, recipient.ID, &merch, variant, note)
+	} else {
+		_, err = services.BuyMerch(tx, user.ID, &merch, variant)
 	}
-	if err := tx.Create(&purchase).Error; err != nil {
+	if err != nil {
 		tx.Rollback()
-		resp := ErrorResponse{Error: "Ошибка при создании записи покупки"}
+		if errors.Is(err, services.ErrInsufficientFunds) {
+			resp := ErrorResponse{Error: "Недостаточно средств"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+		if errors.Is(err, services.ErrOutOfStock) {
+			resp := ErrorResponse{Error: "Товар закончился"}
+			c.JSON(http.StatusConflict, resp)
+			return
+		}
+		resp := ErrorResponse{Error: "Ошибка при оформлении покупки"}
 		c.JSON(http.StatusInternalServerError, resp)
 		return
 	}
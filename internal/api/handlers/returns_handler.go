@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReturnsHandler struct {
+	db *gorm.DB
+}
+
+func NewReturnsHandler(db *gorm.DB) *ReturnsHandler {
+	return &ReturnsHandler{db: db}
+}
+
+type PurchaseEntry struct {
+	ID         uint       `json:"id"`
+	Item       string     `json:"item"`
+	Variant    string     `json:"variant,omitempty"` // вариант товара, если у товара есть варианты
+	Price      int        `json:"price"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReturnedAt *time.Time `json:"returnedAt,omitempty"`
+}
+
+type CreateReturnRequest struct {
+	PurchaseID uint   `json:"purchaseId" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+type ReviewReturnRequest struct {
+	Comment string `json:"comment"`
+}
+
+type ReturnResponse struct {
+	ID         uint       `json:"id"`
+	PurchaseID uint       `json:"purchaseId"`
+	Username   string     `json:"username,omitempty"`
+	Item       string     `json:"item"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	Amount     int        `json:"amount,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+}
+
+const maxReturnReasonLength = 500
+
+// @Summary      Список покупок.
+// @Description  Возвращает покупки текущего пользователя с идентификаторами, по которым можно оформить возврат.
+// @Tags         Returns
+// @Produce      json
+// @Success      200 {array} PurchaseEntry "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /purchases [get]
+// @Security     BearerAuth
+func (h *ReturnsHandler) ListPurchases(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var purchases []models.Purchase
+	unscoped := func(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+	err := h.db.Preload("Merch", unscoped).
+		Preload("Variant", unscoped).
+		Where("user_id = ?", user.ID).
+		Order("id DESC").
+		Find(&purchases).Error
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить покупки"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	entries := make([]PurchaseEntry, 0, len(purchases))
+	for _, purchase := range purchases {
+		entry := PurchaseEntry{
+			ID:         purchase.ID,
+			Item:       purchase.Merch.Name,
+			Price:      purchase.Price,
+			CreatedAt:  purchase.CreatedAt,
+			ReturnedAt: purchase.ReturnedAt,
+		}
+		if purchase.Variant != nil {
+			entry.Variant = purchase.Variant.Name
+		}
+		entries = append(entries, entry)
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Оформить заявку на возврат.
+// @Description  Создаёт заявку на возврат покупки. Вернуть покупку можно один раз в пределах срока, заданного RETURN_WINDOW. Монеты зачисляются после одобрения заявки администратором.
+// @Tags         Returns
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateReturnRequest true "Покупка и причина возврата"
+// @Success      201 {object} ReturnResponse "Заявка создана."
+// @Failure      400 {object} ErrorResponse "Неверный запрос или срок возврата истёк."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Покупка не найдена."
+// @Failure      409 {object} ErrorResponse "Покупка уже возвращена или ожидает возврата."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /returns [post]
+// @Security     BearerAuth
+func (h *ReturnsHandler) CreateReturn(c *gin.Context) {
+	var req CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxReturnReasonLength {
+		resp := ErrorResponse{Error: "Причина возврата должна содержать от 1 до 500 символов"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	request, err := services.RequestReturn(h.db, user.ID, req.PurchaseID, req.Reason)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	h.respondReturn(c, http.StatusCreated, request.ID)
+}
+
+// @Summary      Заявки на возврат пользователя.
+// @Description  Возвращает заявки на возврат текущего пользователя, начиная с последних.
+// @Tags         Returns
+// @Produce      json
+// @Success      200 {array} ReturnResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /returns [get]
+// @Security     BearerAuth
+func (h *ReturnsHandler) ListReturns(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	h.respondReturns(c, h.db.Where("return_requests.user_id = ?", user.ID))
+}
+
+// @Summary      Заявки на возврат для администратора.
+// @Description  Возвращает заявки на возврат всех пользователей, по умолчанию — ожидающие рассмотрения.
+// @Tags         Admin
+// @Produce      json
+// @Param        status query string false "Статус заявки" Enums(pending, approved, rejected) default(pending)
+// @Success      200 {array} ReturnResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/returns [get]
+// @Security     BearerAuth
+func (h *ReturnsHandler) ListAllReturns(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReturnStatusPending)
+	switch status {
+	case models.ReturnStatusPending, models.ReturnStatusApproved, models.ReturnStatusRejected:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неизвестный статус заявки"})
+		return
+	}
+	h.respondReturns(c, h.db.Where("return_requests.status = ?", status))
+}
+
+// @Summary      Одобрить возврат.
+// @Description  Зачисляет пользователю цену покупки, возвращает товар на склад и помечает покупку возвращённой.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID заявки"
+// @Success      200 {object} ReturnResponse "Возврат одобрен."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Заявка не найдена."
+// @Failure      409 {object} ErrorResponse "Заявка уже рассмотрена."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/returns/{id}/approve [post]
+// @Security     BearerAuth
+func (h *ReturnsHandler) ApproveReturn(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if _, err := services.ApproveReturn(h.db, c.GetString("username"), id); err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	h.respondReturn(c, http.StatusOK, id)
+}
+
+// @Summary      Отклонить возврат.
+// @Description  Отклоняет заявку на возврат с комментарием администратора.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID заявки"
+// @Param        body body ReviewReturnRequest false "Комментарий"
+// @Success      200 {object} ReturnResponse "Возврат отклонён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Заявка не найдена."
+// @Failure      409 {object} ErrorResponse "Заявка уже рассмотрена."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/returns/{id}/reject [post]
+// @Security     BearerAuth
+func (h *ReturnsHandler) RejectReturn(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ReviewReturnRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := ErrorResponse{Error: "Неверный запрос"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+	}
+
+	if _, err := services.RejectReturn(h.db, c.GetString("username"), id, strings.TrimSpace(req.Comment)); err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	h.respondReturn(c, http.StatusOK, id)
+}
+
+// currentUser находит пользователя из JWT. При ошибке отвечает клиенту и возвращает false.
+func (h *ReturnsHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Пользователь не найден"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// returnRow — заявка на возврат вместе с названием товара и именем пользователя.
+type returnRow struct {
+	models.ReturnRequest
+	Item     string
+	Username string
+}
+
+// findReturns выбирает заявки на возврат одним запросом, присоединяя товар и пользователя.
+func findReturns(query *gorm.DB) ([]returnRow, error) {
+	var rows []returnRow
+	err := query.Model(&models.ReturnRequest{}).
+		Select("return_requests.*, merches.name AS item, users.username AS username").
+		Joins("JOIN purchases ON purchases.id = return_requests.purchase_id").
+		Joins("JOIN merches ON merches.id = purchases.merch_id").
+		Joins("JOIN users ON users.id = return_requests.user_id").
+		Order("return_requests.id DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (h *ReturnsHandler) respondReturns(c *gin.Context, query *gorm.DB) {
+	rows, err := findReturns(query)
+	if err != nil {
+		resp := ErrorResponse{Error: "Не удалось получить заявки на возврат"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+
+	entries := make([]ReturnResponse, 0, len(rows))
+	for i := range rows {
+		entries = append(entries, newReturnResponse(&rows[i]))
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *ReturnsHandler) respondReturn(c *gin.Context, status int, id uint) {
+	rows, err := findReturns(h.db.Where("return_requests.id = ?", id))
+	if err != nil || len(rows) == 0 {
+		resp := ErrorResponse{Error: "Не удалось получить заявку на возврат"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	c.JSON(status, newReturnResponse(&rows[0]))
+}
+
+func newReturnResponse(row *returnRow) ReturnResponse {
+	return ReturnResponse{
+		ID:         row.ID,
+		PurchaseID: row.PurchaseID,
+		Username:   row.Username,
+		Item:       row.Item,
+		Reason:     row.Reason,
+		Status:     row.Status,
+		Amount:     row.Amount,
+		Comment:    row.Comment,
+		CreatedAt:  row.CreatedAt,
+		ReviewedAt: row.ReviewedAt,
+	}
+}
+
+// respondReturnError преобразует ошибку обработки возврата в HTTP-ответ.
+func respondReturnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPurchaseNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Покупка не найдена"})
+	case errors.Is(err, services.ErrReturnWindowExpired):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Срок возврата истёк"})
+	case errors.Is(err, services.ErrGiftNotReturnable):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Подарок нельзя вернуть"})
+	case errors.Is(err, services.ErrAlreadyReturned):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Покупка уже возвращена или ожидает возврата"})
+	case errors.Is(err, services.ErrReturnNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Заявка на возврат не найдена"})
+	case errors.Is(err, services.ErrReturnNotPending):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Заявка на возврат уже рассмотрена"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при обработке возврата"})
+	}
+}
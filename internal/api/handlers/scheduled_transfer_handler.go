@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ScheduledTransferHandler struct {
+	db *gorm.DB
+}
+
+func NewScheduledTransferHandler(db *gorm.DB) *ScheduledTransferHandler {
+	return &ScheduledTransferHandler{db: db}
+}
+
+type CreateScheduledTransferRequest struct {
+	ToUser  string `json:"toUser" binding:"required"`
+	Amount  int    `json:"amount" binding:"required"`
+	Message string `json:"message"`
+	// RunAt — время разового перевода или первого запуска повторяющегося (RFC 3339).
+	RunAt *time.Time `json:"runAt"`
+	// Schedule — cron-выражение повторения в UTC, например "0 9 1 * *" или "@monthly".
+	Schedule string `json:"schedule"`
+}
+
+type ScheduledTransferResponse struct {
+	ID                uint       `json:"id"`
+	ToUser            string     `json:"toUser"`
+	Amount            int        `json:"amount"`
+	Message           string     `json:"message,omitempty"`
+	Schedule          string     `json:"schedule,omitempty"`
+	Status            string     `json:"status"`
+	NextRunAt         time.Time  `json:"nextRunAt"`
+	Attempts          int        `json:"attempts"`
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+	LastError         string     `json:"lastError,omitempty"`
+	LastTransactionID *uint      `json:"lastTransactionId,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// @Summary      Запланировать перевод.
+// @Description  Создаёт разовый (runAt) или повторяющийся (schedule в формате cron, время UTC) перевод монет. Переводы выполняет фоновый обработчик; при ошибке попытка повторяется.
+// @Tags         ScheduledTransfers
+// @Accept       json
+// @Produce      json
+// @Param        body body CreateScheduledTransferRequest true "Получатель, сумма и расписание"
+// @Success      201 {object} ScheduledTransferResponse "Перевод запланирован."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Получатель не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /scheduled-transfers [post]
+// @Security     BearerAuth
+func (h *ScheduledTransferHandler) CreateSchedule(c *gin.Context) {
+	var req CreateScheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Amount <= 0 {
+		resp := ErrorResponse{Error: "Сумма перевода должна быть положительной"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.RunAt == nil && req.Schedule == "" {
+		resp := ErrorResponse{Error: "Укажите время перевода (runAt) или расписание (schedule)"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.RunAt != nil && req.RunAt.Before(time.Now()) {
+		resp := ErrorResponse{Error: "Время перевода уже прошло"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	message, err := services.SanitizeMessage(req.Message)
+	if err != nil {
+		resp := ErrorResponse{Error: "Комментарий должен быть не длиннее 200 символов"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	sender, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var receiver models.User
+	if err := h.db.Where("username = ?", req.ToUser).First(&receiver).Error; err != nil {
+		resp := ErrorResponse{Error: "Получатель не найден"}
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+	if receiver.ID == sender.ID {
+		resp := ErrorResponse{Error: "Перевод самому себе не поддерживается"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	transfer, err := services.CreateScheduledTransfer(h.db, sender.ID, receiver.ID, req.Amount, message, req.Schedule, req.RunAt)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) || errors.Is(err, services.ErrScheduleNeverRuns) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		resp := ErrorResponse{Error: "Не удалось запланировать перевод"}
+		c.JSON(http.StatusInternalServerError, resp)
+		return
+	}
+	c.JSON(http.StatusCreated, newScheduledTransferResponse(transfer, receiver.Username))
+}
+
+// @Summary      Запланированные переводы.
+// @Description  Возвращает запланированные переводы текущего пользователя, начиная с последних.
+// @Tags         ScheduledTransfers
+// @Produce      json
+// @Param        status query string false "Статус" Enums(active, paused, cancelled, completed, failed)
+// @Success      200 {array} ScheduledTransferResponse "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /scheduled-transfers [get]
+// @Security     BearerAuth
+func (h *ScheduledTransferHandler) ListSchedules(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Table("scheduled_transfers").
+		Select("scheduled_transfers.*, receivers.username AS to_user").
+		Joins("JOIN users receivers ON receivers.id = scheduled_transfers.to_user_id").
+		Where("scheduled_transfers.deleted_at IS NULL AND scheduled_transfers.from_user_id = ?", user.ID)
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.ScheduledTransferActive, models.ScheduledTransferPaused, models.ScheduledTransferCancelled,
+			models.ScheduledTransferCompleted, models.ScheduledTransferFailed:
+			query = query.Where("scheduled_transfers.status = ?", status)
+		default:
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Неизвестный статус перевода"})
+			return
+		}
+	}
+
+	var rows []struct {
+		models.ScheduledTransfer
+		ToUser string
+	}
+	if err := query.Order("scheduled_transfers.id DESC").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить запланированные переводы"})
+		return
+	}
+
+	entries := make([]ScheduledTransferResponse, 0, len(rows))
+	for i := range rows {
+		entries = append(entries, newScheduledTransferResponse(&rows[i].ScheduledTransfer, rows[i].ToUser))
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary      Приостановить запланированный перевод.
+// @Description  Приостанавливает активный перевод. Пока перевод приостановлен, он не выполняется.
+// @Tags         ScheduledTransfers
+// @Produce      json
+// @Param        id path int true "ID запланированного перевода"
+// @Success      200 {object} ScheduledTransferResponse "Перевод приостановлен."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Перевод не найден."
+// @Failure      409 {object} ErrorResponse "Перевод не активен."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /scheduled-transfers/{id}/pause [post]
+// @Security     BearerAuth
+func (h *ScheduledTransferHandler) PauseSchedule(c *gin.Context) {
+	h.changeStatus(c, services.PauseScheduledTransfer)
+}
+
+// @Summary      Возобновить запланированный перевод.
+// @Description  Возобновляет приостановленный перевод. Запуски повторяющегося перевода, пропущенные за время паузы, не выполняются.
+// @Tags         ScheduledTransfers
+// @Produce      json
+// @Param        id path int true "ID запланированного перевода"
+// @Success      200 {object} ScheduledTransferResponse "Перевод возобновлён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Перевод не найден."
+// @Failure      409 {object} ErrorResponse "Перевод не приостановлен."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /scheduled-transfers/{id}/resume [post]
+// @Security     BearerAuth
+func (h *ScheduledTransferHandler) ResumeSchedule(c *gin.Context) {
+	h.changeStatus(c, services.ResumeScheduledTransfer)
+}
+
+// @Summary      Отменить запланированный перевод.
+// @Description  Отменяет активный или приостановленный перевод. Уже выполненные переводы не отменяются.
+// @Tags         ScheduledTransfers
+// @Produce      json
+// @Param        id path int true "ID запланированного перевода"
+// @Success      200 {object} ScheduledTransferResponse "Перевод отменён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      404 {object} ErrorResponse "Перевод не найден."
+// @Failure      409 {object} ErrorResponse "Перевод уже завершён или отменён."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /scheduled-transfers/{id}/cancel [post]
+// @Security     BearerAuth
+func (h *ScheduledTransferHandler) CancelSchedule(c *gin.Context) {
+	h.changeStatus(c, services.CancelScheduledTransfer)
+}
+
+func (h *ScheduledTransferHandler) changeStatus(c *gin.Context, change func(db *gorm.DB, userID, id uint) (*models.ScheduledTransfer, error)) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	transfer, err := change(h.db, user.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrScheduledTransferNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Запланированный перевод не найден"})
+		case errors.Is(err, services.ErrScheduledTransferStatus):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "Действие недоступно в текущем статусе перевода"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось изменить запланированный перевод"})
+		}
+		return
+	}
+
+	var receiver models.User
+	if err := h.db.Unscoped().First(&receiver, transfer.ToUserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось найти получателя"})
+		return
+	}
+	c.JSON(http.StatusOK, newScheduledTransferResponse(transfer, receiver.Username))
+}
+
+// currentUser находит пользователя из JWT. При ошибке отвечает клиенту и возвращает false.
+func (h *ScheduledTransferHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Пользователь не авторизован"})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Пользователь не найден"})
+		return nil, false
+	}
+	return &user, true
+}
+
+func newScheduledTransferResponse(transfer *models.ScheduledTransfer, toUser string) ScheduledTransferResponse {
+	return ScheduledTransferResponse{
+		ID:                transfer.ID,
+		ToUser:            toUser,
+		Amount:            transfer.Amount,
+		Message:           transfer.Message,
+		Schedule:          transfer.Schedule,
+		Status:            transfer.Status,
+		NextRunAt:         transfer.NextRunAt,
+		Attempts:          transfer.Attempts,
+		LastRunAt:         transfer.LastRunAt,
+		LastError:         transfer.LastError,
+		LastTransactionID: transfer.LastTransactionID,
+		CreatedAt:         transfer.CreatedAt,
+	}
+}
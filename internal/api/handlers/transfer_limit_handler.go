@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TransferLimitHandler struct {
+	db *gorm.DB
+}
+
+func NewTransferLimitHandler(db *gorm.DB) *TransferLimitHandler {
+	return &TransferLimitHandler{db: db}
+}
+
+// SetTransferLimitsRequest задаёт индивидуальные лимиты. Незаданное поле означает
+// лимит по умолчанию, 0 — отсутствие ограничения.
+type SetTransferLimitsRequest struct {
+	MaxAmount       *int `json:"maxAmount"`
+	DailyLimit      *int `json:"dailyLimit"`
+	WeeklyLimit     *int `json:"weeklyLimit"`
+	DailyRecipients *int `json:"dailyRecipients"`
+}
+
+type TransferLimitsResponse struct {
+	Username  string                         `json:"username"`
+	Defaults  services.TransferLimits        `json:"defaults"`
+	Override  *TransferLimitOverrideResponse `json:"override,omitempty"`
+	Effective services.TransferLimits        `json:"effective"`
+	Usage     services.TransferUsage         `json:"usage"` // переводы за последние 24 часа и 7 дней
+}
+
+// TransferLimitOverrideResponse — индивидуальные лимиты пользователя; незаданное поле
+// означает лимит по умолчанию.
+type TransferLimitOverrideResponse struct {
+	MaxAmount       *int      `json:"maxAmount,omitempty"`
+	DailyLimit      *int      `json:"dailyLimit,omitempty"`
+	WeeklyLimit     *int      `json:"weeklyLimit,omitempty"`
+	DailyRecipients *int      `json:"dailyRecipients,omitempty"`
+	UpdatedBy       string    `json:"updatedBy"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// @Summary      Лимиты переводов пользователя.
+// @Description  Возвращает лимиты переводов по умолчанию, индивидуальные лимиты пользователя, действующие лимиты и использованную часть. 0 означает отсутствие ограничения.
+// @Tags         Admin
+// @Produce      json
+// @Param        username path string true "Имя пользователя"
+// @Success      200 {object} TransferLimitsResponse "Успешный ответ."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Пользователь не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/users/{username}/transfer-limits [get]
+// @Security     BearerAuth
+func (h *TransferLimitHandler) GetLimits(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	h.respondLimits(c, user)
+}
+
+// @Summary      Задать лимиты переводов пользователя.
+// @Description  Задаёт пользователю индивидуальные лимиты переводов, заменяя прежние. Незаданное поле означает лимит по умолчанию, 0 — отсутствие ограничения.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        username path string true "Имя пользователя"
+// @Param        body body SetTransferLimitsRequest true "Индивидуальные лимиты"
+// @Success      200 {object} TransferLimitsResponse "Лимиты заданы."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Пользователь не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/users/{username}/transfer-limits [put]
+// @Security     BearerAuth
+func (h *TransferLimitHandler) SetLimits(c *gin.Context) {
+	var req SetTransferLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	_, err := services.SetTransferLimitOverride(h.db, c.GetString("username"), user.ID, models.TransferLimitOverride{
+		MaxAmount:       req.MaxAmount,
+		DailyLimit:      req.DailyLimit,
+		WeeklyLimit:     req.WeeklyLimit,
+		DailyRecipients: req.DailyRecipients,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrNegativeTransferLimit) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Лимит не может быть отрицательным"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при сохранении лимитов"})
+		return
+	}
+	h.respondLimits(c, user)
+}
+
+// @Summary      Сбросить лимиты переводов пользователя.
+// @Description  Удаляет индивидуальные лимиты переводов пользователя; для него снова действуют лимиты по умолчанию.
+// @Tags         Admin
+// @Produce      json
+// @Param        username path string true "Имя пользователя"
+// @Success      200 {object} TransferLimitsResponse "Лимиты сброшены."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Пользователь или индивидуальные лимиты не найдены."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/users/{username}/transfer-limits [delete]
+// @Security     BearerAuth
+func (h *TransferLimitHandler) DeleteLimits(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteTransferLimitOverride(h.db, user.ID); err != nil {
+		if errors.Is(err, services.ErrTransferLimitOverrideNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Индивидуальные лимиты не заданы"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при сбросе лимитов"})
+		return
+	}
+	h.respondLimits(c, user)
+}
+
+func (h *TransferLimitHandler) findUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := h.db.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Пользователь не найден"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Ошибка при получении пользователя"})
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h *TransferLimitHandler) respondLimits(c *gin.Context, user *models.User) {
+	override, err := services.FindTransferLimitOverride(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить лимиты"})
+		return
+	}
+	usage, err := services.TransferUsageAt(h.db, user.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить лимиты"})
+		return
+	}
+
+	defaults := services.DefaultTransferLimits()
+	resp := TransferLimitsResponse{
+		Username:  user.Username,
+		Defaults:  defaults,
+		Effective: defaults.Apply(override),
+		Usage:     usage,
+	}
+	if override != nil {
+		resp.Override = &TransferLimitOverrideResponse{
+			MaxAmount:       override.MaxAmount,
+			DailyLimit:      override.DailyLimit,
+			WeeklyLimit:     override.WeeklyLimit,
+			DailyRecipients: override.DailyRecipients,
+			UpdatedBy:       override.UpdatedBy,
+			UpdatedAt:       override.UpdatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
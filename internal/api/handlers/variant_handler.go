@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type VariantHandler struct {
+	db *gorm.DB
+}
+
+func NewVariantHandler(db *gorm.DB) *VariantHandler {
+	return &VariantHandler{db: db}
+}
+
+// VariantResponse — вариант товара; price — цена с учётом цены варианта.
+type VariantResponse struct {
+	ID         uint              `json:"id"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Price      int               `json:"price"`
+	Stock      *int              `json:"stock"` // null — запас не ограничен
+}
+
+type CreateVariantRequest struct {
+	Name       string            `json:"name" binding:"required"`
+	Attributes map[string]string `json:"attributes"`
+	Stock      *int              `json:"stock"` // если не передан, запас не ограничен
+	Price      *int              `json:"price"` // если не передана, вариант продаётся по цене товара
+}
+
+type UpdateVariantRequest struct {
+	Name       *string           `json:"name"`
+	Attributes map[string]string `json:"attributes"` // заменяет атрибуты целиком
+	Stock      *int              `json:"stock"`
+	Price      *int              `json:"price"` // 0 — продавать по цене товара
+}
+
+// validateVariant проверяет название, цену и остаток варианта. Nil-поля не проверяются;
+// цена 0 допускается только при изменении (allowZeroPrice) и означает цену товара.
+func validateVariant(name *string, price *int, stock *int, allowZeroPrice bool) string {
+	if name != nil {
+		if *name == "" {
+			return "Название варианта не может быть пустым"
+		}
+		if utf8.RuneCountInString(*name) > maxMerchNameLength {
+			return "Название варианта должно быть не длиннее 64 символов"
+		}
+	}
+	if price != nil && (*price < 0 || *price == 0 && !allowZeroPrice) {
+		return "Цена варианта должна быть положительной"
+	}
+	if stock != nil && *stock < 0 {
+		return "Остаток варианта не может быть отрицательным"
+	}
+	return ""
+}
+
+func newVariantResponse(merch *models.Merch, variant *models.MerchVariant) VariantResponse {
+	return VariantResponse{
+		ID:         variant.ID,
+		Name:       variant.Name,
+		Attributes: variant.Attributes,
+		Price:      services.ItemPrice(merch, variant),
+		Stock:      variant.Stock,
+	}
+}
+
+// @Summary      Добавить вариант товара.
+// @Description  Добавляет товару вариант (например, размер или цвет) с уникальным в пределах товара названием. После этого товар покупается только с выбором варианта, а остаток учитывается по вариантам.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        body body CreateVariantRequest true "Вариант"
+// @Success      201 {object} VariantResponse "Вариант создан."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Товар не найден."
+// @Failure      409 {object} ErrorResponse "Вариант с таким названием уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/variants [post]
+// @Security     BearerAuth
+func (h *VariantHandler) CreateVariant(c *gin.Context) {
+	merchID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if msg := validateVariant(&req.Name, req.Price, req.Stock, false); msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	variant, err := services.CreateVariant(h.db, c.GetString("username"), merchID, req.Name, req.Attributes, req.Stock, req.Price)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	h.respondVariant(c, http.StatusCreated, variant)
+}
+
+// @Summary      Изменить вариант товара.
+// @Description  Изменяет название, атрибуты, остаток и (или) цену варианта. Цена 0 сбрасывает цену варианта на цену товара.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        variantId path int true "ID варианта"
+// @Param        body body UpdateVariantRequest true "Изменяемые поля"
+// @Success      200 {object} VariantResponse "Вариант изменён."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Вариант не найден."
+// @Failure      409 {object} ErrorResponse "Вариант с таким названием уже существует."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/variants/{variantId} [put]
+// @Security     BearerAuth
+func (h *VariantHandler) UpdateVariant(c *gin.Context) {
+	merchID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "variantId")
+	if !ok {
+		return
+	}
+
+	var req UpdateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		req.Name = &trimmed
+	}
+	if msg := validateVariant(req.Name, req.Price, req.Stock, true); msg != "" {
+		resp := ErrorResponse{Error: msg}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	variant, err := services.UpdateVariant(h.db, c.GetString("username"), merchID, id, services.VariantChanges{
+		Name:       req.Name,
+		Attributes: req.Attributes,
+		Stock:      req.Stock,
+		Price:      req.Price,
+	})
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	h.respondVariant(c, http.StatusOK, variant)
+}
+
+// @Summary      Удалить вариант товара.
+// @Description  Мягко удаляет вариант: купить его больше нельзя, покупки варианта остаются в инвентаре.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        variantId path int true "ID варианта"
+// @Success      200 {null} nil "Успешный ответ."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Вариант не найден."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/variants/{variantId} [delete]
+// @Security     BearerAuth
+func (h *VariantHandler) DeleteVariant(c *gin.Context) {
+	merchID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "variantId")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteVariant(h.db, c.GetString("username"), merchID, id); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+}
+
+// @Summary      Пополнить остаток варианта.
+// @Description  Увеличивает остаток варианта на указанное количество. Пополнение фиксируется в журнале изменений.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID товара"
+// @Param        variantId path int true "ID варианта"
+// @Param        body body RestockRequest true "Количество"
+// @Success      200 {object} VariantResponse "Остаток пополнен."
+// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Failure      401 {object} ErrorResponse "Неавторизован."
+// @Failure      403 {object} ErrorResponse "Недостаточно прав."
+// @Failure      404 {object} ErrorResponse "Вариант не найден."
+// @Failure      409 {object} ErrorResponse "Запас варианта не ограничен."
+// @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
+// @Router       /admin/merch/{id}/variants/{variantId}/restock [post]
+// @Security     BearerAuth
+func (h *VariantHandler) RestockVariant(c *gin.Context) {
+	merchID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "variantId")
+	if !ok {
+		return
+	}
+
+	var req RestockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := ErrorResponse{Error: "Неверный запрос"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if req.Quantity <= 0 {
+		resp := ErrorResponse{Error: "Количество должно быть положительным"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
+	variant, err := services.RestockVariant(h.db, c.GetString("username"), merchID, id, req.Quantity)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	h.respondVariant(c, http.StatusOK, variant)
+}
+
+// respondVariant отвечает вариантом с ценой, вычисленной с учётом цены товара.
+func (h *VariantHandler) respondVariant(c *gin.Context, status int, variant *models.MerchVariant) {
+	var merch models.Merch
+	if err := h.db.Unscoped().First(&merch, variant.MerchID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Не удалось получить товар"})
+		return
+	}
+	c.JSON(status, newVariantResponse(&merch, variant))
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -19,16 +21,60 @@ func NewWalletHandler(db *gorm.DB) *WalletHandler {
 type SendCoinRequest struct {
 	ToUser string `json:"toUser" binding:"required"`
 	Amount int    `json:"amount" binding:"required"`
+	// Message — необязательный комментарий к переводу (до 200 символов).
+	// Управляющие символы удаляются, переводы строк заменяются пробелами.
+	Message string `json:"message"`
+}
+
+// TransferHeldResponse — ответ на перевод, задержанный до проверки администратором.
+type TransferHeldResponse struct {
+	TransactionID uint   `json:"transactionId"`
+	Status        string `json:"status"` // held
+	Message       string `json:"message"`
+}
+
+// TransferLimitErrorResponse — ответ на перевод, превышающий лимит отправителя.
+type TransferLimitErrorResponse struct {
+	Error  string `json:"error"`
+	Limit  string `json:"limit"`  // amount, daily, weekly или daily_recipients
+	Max    int    `json:"max"`    // значение лимита
+	Used   int    `json:"used"`   // использовано до этого перевода (монет или получателей)
+	Amount int    `json:"amount"` // сумма отклонённого перевода
+}
+
+var transferLimitMessages = map[string]string{
+	services.TransferLimitAmount:          "Превышена максимальная сумма одного перевода",
+	services.TransferLimitDaily:           "Превышен лимит переводов за сутки",
+	services.TransferLimitWeekly:          "Превышен лимит переводов за неделю",
+	services.TransferLimitDailyRecipients: "Превышено число получателей за сутки",
+}
+
+// respondTransferLimitError отвечает 400 с описанием лимита, если err — превышение лимита
+// переводов, и возвращает false для остальных ошибок.
+func respondTransferLimitError(c *gin.Context, err error) bool {
+	var limitErr *services.TransferLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, TransferLimitErrorResponse{
+		Error:  transferLimitMessages[limitErr.Limit],
+		Limit:  limitErr.Limit,
+		Max:    limitErr.Max,
+		Used:   limitErr.Used,
+		Amount: limitErr.Amount,
+	})
+	return true
 }
 
 // @Summary      Отправить монеты другому пользователю.
-// @Description  Передаёт монеты от авторизованного пользователя другому.
+// @Description  Передаёт монеты от авторизованного пользователя другому. К переводу можно приложить комментарий, который увидят обе стороны в истории. Перевод, превышающий лимиты отправителя, отклоняется с указанием лимита. Подозрительный перевод может быть задержан до проверки администратором.
 // @Tags         Wallet
 // @Accept       json
 // @Produce      json
 // @Param        sendCoin body SendCoinRequest true "Данные отправки монет"
 // @Success      200 {null} nil "Успешный ответ."
-// @Failure      400 {object} ErrorResponse "Неверный запрос."
+// @Success      202 {object} TransferHeldResponse "Перевод задержан до проверки администратором."
+// @Failure      400 {object} TransferLimitErrorResponse "Неверный запрос, недостаточно средств или превышен лимит переводов."
 // @Failure      401 {object} ErrorResponse "Неавторизован."
 // @Failure      500 {object} ErrorResponse "Внутренняя ошибка сервера."
 // @Router       /sendCoin [post]
@@ -62,6 +108,13 @@ func (h *WalletHandler) SendCoin(c *gin.Context) {
 		return
 	}
 
+	message, err := services.SanitizeMessage(req.Message)
+	if err != nil {
+		resp := ErrorResponse{Error: "Комментарий должен быть не длиннее 200 символов"}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+
 	// Начинаем транзакцию
 	tx := h.db.Begin()
 
@@ -74,14 +127,6 @@ func (h *WalletHandler) SendCoin(c *gin.Context) {
 		return
 	}
 
-	// Проверяем баланс
-	if sender.Coins < req.Amount {
-		tx.Rollback()
-		resp := ErrorResponse{Error: "Недостаточно средств"}
-		c.JSON(http.StatusBadRequest, resp)
-		return
-	}
-
 	// Получаем получателя
 	var receiver models.User
 	if err := tx.Where("username = ?", req.ToUser).First(&receiver).Error; err != nil {
@@ -99,29 +144,19 @@ func (h *WalletHandler) SendCoin(c *gin.Context) {
 		return
 	}
 
-	// Обновляем балансы
-	if err := tx.Model(&sender).Update("coins", sender.Coins-req.Amount).Error; err != nil {
-		tx.Rollback()
-		resp := ErrorResponse{Error: "Ошибка при обновлении баланса отправителя"}
-		c.JSON(http.StatusInternalServerError, resp)
-		return
-	}
-	if err := tx.Model(&receiver).Update("coins", receiver.Coins+req.Amount).Error; err != nil {
+	// Блокируем строки отправителя и получателя, атомарно обновляем балансы и записываем транзакцию
+	transaction, err := services.Transfer(tx, sender.ID, receiver.ID, req.Amount, message)
+	if err != nil {
 		tx.Rollback()
-		resp := ErrorResponse{Error: "Ошибка при обновлении баланса получателя"}
-		c.JSON(http.StatusInternalServerError, resp)
-		return
-	}
-
-	// Записываем транзакцию
-	transaction := models.Transaction{
-		FromUserID: sender.ID,
-		ToUserID:   receiver.ID,
-		Amount:     req.Amount,
-	}
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		resp := ErrorResponse{Error: "Ошибка при создании записи транзакции"}
+		if errors.Is(err, services.ErrInsufficientFunds) {
+			resp := ErrorResponse{Error: "Недостаточно средств"}
+			c.JSON(http.StatusBadRequest, resp)
+			return
+		}
+		if respondTransferLimitError(c, err) {
+			return
+		}
+		resp := ErrorResponse{Error: "Ошибка при выполнении перевода"}
 		c.JSON(http.StatusInternalServerError, resp)
 		return
 	}
@@ -133,5 +168,14 @@ func (h *WalletHandler) SendCoin(c *gin.Context) {
 		return
 	}
 
+	if transaction.Status == models.TransactionHeld {
+		c.JSON(http.StatusAccepted, TransferHeldResponse{
+			TransactionID: transaction.ID,
+			Status:        transaction.Status,
+			Message:       "Перевод задержан до проверки администратором",
+		})
+		return
+	}
+
 	// c.JSON(http.StatusOK, gin.H{"message": "Монетки успешно отправлены"})
 }
@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyHeader — заголовок, в котором клиент передаёт ключ идемпотентности.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// responseRecorder дублирует тело ответа в буфер, чтобы его можно было сохранить.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware обеспечивает идемпотентность запросов с заголовком Idempotency-Key.
+// Повторный запрос того же пользователя с тем же ключом получает сохранённый статус и тело
+// ответа без повторного вызова обработчика. Повтор ключа с другим телом запроса отклоняется с 409.
+// Записи хранятся ttl, после чего ключ можно использовать заново.
+// Должен подключаться после JWTAuthMiddleware.
+func IdempotencyMiddleware(db *gorm.DB, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Слишком длинный Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		username := c.GetString("username")
+		if username == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь не авторизован"})
+			c.Abort()
+			return
+		}
+
+		// Читаем тело, чтобы посчитать отпечаток запроса, и возвращаем его обработчику
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать тело запроса"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashRequest(c.Request.Method, c.Request.URL.RequestURI(), body)
+
+		now := time.Now()
+		// Удаляем просроченные записи пользователя, чтобы ключи можно было переиспользовать
+		if err := db.Unscoped().
+			Where("username = ? AND expires_at < ?", username, now).
+			Delete(&models.IdempotencyRecord{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при проверке Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		// Резервируем ключ. Если запись уже есть, запрос является повтором
+		record := models.IdempotencyRecord{
+			Username:       username,
+			IdempotencyKey: key,
+			RequestHash:    requestHash,
+			ExpiresAt:      now.Add(ttl),
+		}
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при сохранении Idempotency-Key"})
+			c.Abort()
+			return
+		}
+		if res.RowsAffected == 0 {
+			replayIdempotentResponse(c, db, username, key, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+		c.Next()
+
+		// Серверные ошибки не сохраняем: клиент должен иметь возможность повторить запрос
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			db.Unscoped().Delete(&record)
+			return
+		}
+		db.Model(&record).Updates(map[string]interface{}{
+			"completed":     true,
+			"status_code":   status,
+			"content_type":  c.Writer.Header().Get("Content-Type"),
+			"response_body": recorder.body.Bytes(),
+		})
+	}
+}
+
+// replayIdempotentResponse отвечает на повторный запрос сохранённым результатом.
+func replayIdempotentResponse(c *gin.Context, db *gorm.DB, username, key, requestHash string) {
+	var existing models.IdempotencyRecord
+	if err := db.Where("username = ? AND idempotency_key = ?", username, key).First(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при проверке Idempotency-Key"})
+		c.Abort()
+		return
+	}
+
+	if existing.RequestHash != requestHash {
+		c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key уже использован с другим запросом"})
+		c.Abort()
+		return
+	}
+	if !existing.Completed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Запрос с этим Idempotency-Key ещё обрабатывается"})
+		c.Abort()
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+	c.Abort()
+}
+
+func hashRequest(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte(" "))
+	h.Write([]byte(uri))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -2,16 +2,14 @@ package middlewares
 
 import (
 	"net/http"
-	"os"
 	"strings"
 
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET_KEY"))
-
-func JWTAuthMiddleware() gin.HandlerFunc {
+func JWTAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -28,21 +26,34 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
+		// Проверяем подпись, срок действия и то, что токен не был отозван
+		claims, err := services.ParseAccessToken(db, parts[1])
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Неверный или просроченный токен"})
 			c.Abort()
 			return
 		}
 
-		// При необходимости можно сохранить данные токена в контексте (например, username)
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("username", claims["username"])
-		}
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("accessClaims", claims)
 
 		c.Next()
 	}
 }
+
+// RequireRole пропускает запрос, только если роль из JWT входит в список разрешённых.
+// Должен подключаться после JWTAuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"errors": "Недостаточно прав"})
+		c.Abort()
+	}
+}
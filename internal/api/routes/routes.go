@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -8,30 +10,146 @@ import (
 
 	"github.com/defskela/merchmarket/internal/api/handlers"
 	"github.com/defskela/merchmarket/internal/api/middlewares"
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
 )
 
 func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	// Публичный эндпоинт для аутентификации
+	// Публичные эндпоинты для регистрации и аутентификации
 	authHandler := handlers.NewAuthHandler(db)
+	router.POST("/api/register", authHandler.Register)
 	router.POST("/api/auth", authHandler.Authenticate)
+	router.POST("/api/auth/refresh", authHandler.Refresh)
 
 	// Защищённая группа – все остальные эндпоинты требуют JWT
 	api := router.Group("/api")
-	api.Use(middlewares.JWTAuthMiddleware())
+	api.Use(middlewares.JWTAuthMiddleware(db))
 	{
+		// Повтор запроса с тем же Idempotency-Key не списывает монеты повторно
+		idempotency := middlewares.IdempotencyMiddleware(db, config.GetDuration("IDEMPOTENCY_TTL", 24*time.Hour))
+
+		// Выход: отзыв текущего access-токена и цепочки refresh-токенов
+		api.POST("/auth/logout", authHandler.Logout)
+
 		// Получение информации о монетах, инвентаре и истории транзакций
 		infoHandler := handlers.NewInfoHandler(db)
 		api.GET("/info", infoHandler.GetInfo)
 
+		// История переводов с фильтрами и постраничным выводом
+		historyHandler := handlers.NewHistoryHandler(db)
+		api.GET("/history", historyHandler.GetHistory)
+
 		// Отправка монет другому пользователю
 		walletHandler := handlers.NewWalletHandler(db)
-		api.POST("/sendCoin", walletHandler.SendCoin)
+		api.POST("/sendCoin", idempotency, walletHandler.SendCoin)
+
+		// Каталог мерча с фильтрами и сортировкой
+		catalogHandler := handlers.NewCatalogHandler(db)
+		api.GET("/merch", catalogHandler.ListMerch)
+		categoryHandler := handlers.NewCategoryHandler(db)
+		api.GET("/categories", categoryHandler.ListCategories)
 
-		// Покупка мерча – параметр item передаётся в пути
+		// Покупка мерча – параметр item передаётся в пути; POST с recipient оформляет подарок
 		merchHandler := handlers.NewMerchHandler(db)
-		api.GET("/buy/:item", merchHandler.BuyItem)
+		api.GET("/buy/:item", idempotency, merchHandler.BuyItem)
+		api.POST("/buy/:item", idempotency, merchHandler.BuyItem)
+
+		// Корзина: несколько товаров оформляются одной транзакцией
+		cartHandler := handlers.NewCartHandler(db)
+		api.GET("/cart", cartHandler.GetCart)
+		api.POST("/cart/items", cartHandler.AddItem)
+		api.DELETE("/cart/items/:item", cartHandler.RemoveItem)
+		api.POST("/cart/checkout", idempotency, cartHandler.Checkout)
+
+		// Покупки и заявки на их возврат
+		returnsHandler := handlers.NewReturnsHandler(db)
+		api.GET("/purchases", returnsHandler.ListPurchases)
+		api.GET("/returns", returnsHandler.ListReturns)
+		api.POST("/returns", returnsHandler.CreateReturn)
+
+		// Запросы монет у других пользователей
+		coinRequestHandler := handlers.NewCoinRequestHandler(db)
+		api.GET("/coin-requests", coinRequestHandler.ListRequests)
+		api.POST("/coin-requests", coinRequestHandler.CreateRequest)
+		api.POST("/coin-requests/:id/accept", idempotency, coinRequestHandler.AcceptRequest)
+		api.POST("/coin-requests/:id/decline", coinRequestHandler.DeclineRequest)
+		api.POST("/coin-requests/:id/cancel", coinRequestHandler.CancelRequest)
+
+		// Переводы с удержанием до подтверждения отправителем
+		escrowHandler := handlers.NewEscrowHandler(db)
+		api.GET("/escrow", escrowHandler.ListEscrows)
+		api.POST("/escrow", idempotency, escrowHandler.CreateEscrow)
+		api.POST("/escrow/:id/confirm", idempotency, escrowHandler.ConfirmEscrow)
+		api.POST("/escrow/:id/release", escrowHandler.ReleaseEscrow)
+
+		// Запланированные и повторяющиеся переводы; выполняются фоновым обработчиком
+		scheduledTransferHandler := handlers.NewScheduledTransferHandler(db)
+		api.GET("/scheduled-transfers", scheduledTransferHandler.ListSchedules)
+		api.POST("/scheduled-transfers", scheduledTransferHandler.CreateSchedule)
+		api.POST("/scheduled-transfers/:id/pause", scheduledTransferHandler.PauseSchedule)
+		api.POST("/scheduled-transfers/:id/resume", scheduledTransferHandler.ResumeSchedule)
+		api.POST("/scheduled-transfers/:id/cancel", scheduledTransferHandler.CancelSchedule)
+
+		// Операторские функции доступны только администраторам
+		admin := api.Group("/admin")
+		admin.Use(middlewares.RequireRole(models.RoleAdmin))
+		{
+			adminHandler := handlers.NewAdminHandler(db)
+			admin.GET("/reconciliation", adminHandler.GetReconciliation)
+			admin.PUT("/users/:username/role", adminHandler.SetUserRole)
+
+			// Индивидуальные лимиты переводов
+			transferLimitHandler := handlers.NewTransferLimitHandler(db)
+			admin.GET("/users/:username/transfer-limits", transferLimitHandler.GetLimits)
+			admin.PUT("/users/:username/transfer-limits", transferLimitHandler.SetLimits)
+			admin.DELETE("/users/:username/transfer-limits", transferLimitHandler.DeleteLimits)
+
+			// Просмотр и отмена переводов
+			admin.GET("/transactions", adminHandler.ListTransactions)
+			admin.POST("/transactions/:id/reverse", adminHandler.ReverseTransaction)
+
+			// Очередь переводов, помеченных правилами обнаружения мошенничества
+			fraudHandler := handlers.NewFraudHandler(db)
+			admin.GET("/fraud-flags", fraudHandler.ListFlags)
+			admin.POST("/fraud-flags/:id/approve", fraudHandler.ApproveFlag)
+			admin.POST("/fraud-flags/:id/reject", fraudHandler.RejectFlag)
+
+			// Управление каталогом мерча
+			admin.GET("/merch", catalogHandler.ListAllMerch)
+			admin.POST("/merch", catalogHandler.CreateMerch)
+			admin.PUT("/merch/:id", catalogHandler.UpdateMerch)
+			admin.DELETE("/merch/:id", catalogHandler.DeleteMerch)
+			admin.POST("/merch/:id/restore", catalogHandler.RestoreMerch)
+			admin.GET("/merch/:id/changes", catalogHandler.GetMerchChanges)
+			admin.POST("/merch/:id/restock", catalogHandler.RestockMerch)
+			admin.GET("/merch/low-stock", catalogHandler.GetLowStock)
+			variantHandler := handlers.NewVariantHandler(db)
+			admin.POST("/merch/:id/variants", variantHandler.CreateVariant)
+			admin.PUT("/merch/:id/variants/:variantId", variantHandler.UpdateVariant)
+			admin.DELETE("/merch/:id/variants/:variantId", variantHandler.DeleteVariant)
+			admin.POST("/merch/:id/variants/:variantId/restock", variantHandler.RestockVariant)
+			admin.PUT("/merch/:id/category", catalogHandler.SetMerchCategory)
+			admin.PUT("/merch/:id/tags", catalogHandler.SetMerchTags)
+
+			// Дерево категорий каталога
+			admin.POST("/categories", categoryHandler.CreateCategory)
+			admin.PUT("/categories/:id", categoryHandler.UpdateCategory)
+			admin.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+
+			// Рассмотрение заявок на возврат
+			admin.GET("/returns", returnsHandler.ListAllReturns)
+			admin.POST("/returns/:id/approve", returnsHandler.ApproveReturn)
+			admin.POST("/returns/:id/reject", returnsHandler.RejectReturn)
 
+			// Политики регулярного начисления монет
+			allowanceHandler := handlers.NewAllowanceHandler(db)
+			admin.GET("/allowances", allowanceHandler.ListPolicies)
+			admin.POST("/allowances", allowanceHandler.CreatePolicy)
+			admin.PUT("/allowances/:id", allowanceHandler.UpdatePolicy)
+			admin.DELETE("/allowances/:id", allowanceHandler.DeletePolicy)
+			admin.POST("/allowances/run", allowanceHandler.RunAllowances)
+		}
 	}
 }
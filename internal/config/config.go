@@ -0,0 +1,61 @@
+// Package config читает настройки приложения из переменных окружения.
+// Для каждой настройки задаётся значение по умолчанию, которое используется,
+// если переменная не задана или не может быть разобрана.
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetString возвращает значение переменной окружения или значение по умолчанию.
+func GetString(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// GetInt возвращает целочисленное значение переменной окружения.
+func GetInt(key string, def int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используется %d", key, value, def)
+		return def
+	}
+	return parsed
+}
+
+// GetBool возвращает логическое значение переменной окружения (true/false, 1/0).
+func GetBool(key string, def bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используется %t", key, value, def)
+		return def
+	}
+	return parsed
+}
+
+// GetDuration возвращает длительность из переменной окружения в формате time.ParseDuration (например, "24h").
+func GetDuration(key string, def time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используется %s", key, value, def)
+		return def
+	}
+	return parsed
+}
@@ -1,35 +1,402 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Роли пользователей.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 // User представляет сотрудника системы.
 type User struct {
 	gorm.Model
 	Username  string     `gorm:"unique;not null" json:"username"`
 	Password  string     `gorm:"not null" json:"-"` // хранится в виде хэша
-	Coins     int        `gorm:"not null;default:1000" json:"coins"`
+	Coins     int        `gorm:"not null;default:1000;check:coins >= 0" json:"coins"`
+	Role      string     `gorm:"not null;default:user" json:"role"`
+	Debt      int        `gorm:"not null;default:0;check:debt >= 0" json:"debt"` // долг после отмены перевода; погашается из будущих зачислений
 	Purchases []Purchase `json:"purchases"`
 }
 
 // Merch представляет товар в магазине.
 type Merch struct {
 	gorm.Model
-	Name  string `gorm:"unique;not null" json:"name"`
-	Price int    `gorm:"not null" json:"price"`
+	Name       string `gorm:"unique;not null" json:"name"`
+	Price      int    `gorm:"not null" json:"price"`
+	Stock      *int   `gorm:"check:stock >= 0" json:"stock"`     // остаток на складе; nil — без ограничений
+	CategoryID *uint  `gorm:"index" json:"categoryId,omitempty"` // категория товара; nil — без категории
+}
+
+// Category — категория каталога. Категории образуют дерево: у вложенной категории
+// задан ParentID, а товар из вложенной категории относится и ко всем её предкам.
+type Category struct {
+	gorm.Model
+	Name     string `gorm:"not null;uniqueIndex:idx_category_parent_name" json:"name"`
+	ParentID *uint  `gorm:"uniqueIndex:idx_category_parent_name;index" json:"parentId,omitempty"`
+}
+
+// MerchTag — произвольная метка товара, например "новинка" или "эко". Метки хранятся
+// в нижнем регистре, у одного товара метка не повторяется.
+type MerchTag struct {
+	gorm.Model
+	MerchID uint   `gorm:"not null;uniqueIndex:idx_merch_tag" json:"merchId"`
+	Tag     string `gorm:"size:32;not null;uniqueIndex:idx_merch_tag;index" json:"tag"`
+}
+
+// MerchVariant — вариант товара, например размер или цвет. У товара с вариантами
+// остаток учитывается по вариантам, а покупатель выбирает вариант по названию.
+// Товар без вариантов продаётся как раньше.
+type MerchVariant struct {
+	gorm.Model
+	MerchID    uint              `gorm:"not null;uniqueIndex:idx_merch_variant_name" json:"merchId"`
+	Name       string            `gorm:"not null;uniqueIndex:idx_merch_variant_name" json:"name"` // например, "L" или "M/black"
+	Attributes map[string]string `gorm:"serializer:json" json:"attributes"`                       // например, {"size": "L", "color": "black"}
+	Stock      *int              `gorm:"check:stock >= 0" json:"stock"`                           // остаток на складе; nil — без ограничений
+	Price      *int              `json:"price,omitempty"`                                         // цена варианта; nil — цена товара
 }
 
 // Purchase фиксирует покупку мерча пользователем.
+// Для подарка UserID — получатель (товар попадает в его инвентарь), а GiftFromID — покупатель, оплативший товар.
 type Purchase struct {
 	gorm.Model
-	UserID  uint  `gorm:"not null" json:"userId"`
-	MerchID uint  `gorm:"not null" json:"merchId"`
-	Merch   Merch `gorm:"foreignKey:MerchID" json:"merch"`
+	UserID     uint          `gorm:"not null;index" json:"userId"`
+	MerchID    uint          `gorm:"not null" json:"merchId"`
+	Merch      Merch         `gorm:"foreignKey:MerchID" json:"merch"`
+	VariantID  *uint         `gorm:"index" json:"variantId,omitempty"` // вариант товара; nil у товаров без вариантов
+	Variant    *MerchVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	Price      int           `gorm:"not null;default:0" json:"price"` // цена на момент покупки; 0 у покупок, сделанных до появления поля
+	ReturnedAt *time.Time    `json:"returnedAt,omitempty"`            // товар возвращён, монеты зачислены обратно
+	GiftFromID *uint         `gorm:"index" json:"giftFromId,omitempty"`
+	GiftNote   string        `gorm:"size:200;not null;default:''" json:"giftNote,omitempty"`
+}
+
+// Статусы заявки на возврат.
+const (
+	ReturnStatusPending  = "pending"
+	ReturnStatusApproved = "approved"
+	ReturnStatusRejected = "rejected"
+)
+
+// ReturnRequest — заявка пользователя на возврат купленного товара.
+type ReturnRequest struct {
+	gorm.Model
+	PurchaseID uint       `gorm:"not null;index" json:"purchaseId"`
+	Purchase   Purchase   `gorm:"foreignKey:PurchaseID" json:"purchase"`
+	UserID     uint       `gorm:"not null;index" json:"userId"`
+	Reason     string     `gorm:"not null" json:"reason"`
+	Status     string     `gorm:"not null;default:pending;index" json:"status"`
+	Amount     int        `gorm:"not null;default:0" json:"amount"` // сумма возврата; заполняется при одобрении
+	ReviewedBy string     `json:"reviewedBy,omitempty"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+	Comment    string     `json:"comment,omitempty"` // комментарий администратора
+}
+
+// CartItem — позиция корзины пользователя: товар, его вариант и количество единиц.
+type CartItem struct {
+	gorm.Model
+	UserID    uint          `gorm:"not null;uniqueIndex:idx_cart_user_merch_variant" json:"userId"`
+	MerchID   uint          `gorm:"not null;uniqueIndex:idx_cart_user_merch_variant" json:"merchId"`
+	Merch     Merch         `gorm:"foreignKey:MerchID" json:"merch"`
+	VariantID *uint         `gorm:"uniqueIndex:idx_cart_user_merch_variant" json:"variantId,omitempty"` // вариант товара; nil у товаров без вариантов
+	Variant   *MerchVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	Quantity  int           `gorm:"not null;check:quantity > 0" json:"quantity"`
 }
 
+// Статусы перевода.
+const (
+	TransactionSettled  = "settled"  // монеты зачислены получателю
+	TransactionPending  = "pending"  // перевод с удержанием ждёт подтверждения отправителем
+	TransactionReleased = "released" // перевод с удержанием не подтверждён, монеты возвращены отправителю
+	TransactionHeld     = "held"     // перевод задержан до проверки администратором
+	TransactionRejected = "rejected" // задержанный перевод отклонён, монеты возвращены отправителю
+)
+
 // Transaction представляет перевод монет между пользователями.
 type Transaction struct {
 	gorm.Model
-	FromUserID uint `gorm:"not null" json:"fromUserId"`
-	ToUserID   uint `gorm:"not null" json:"toUserId"`
-	Amount     int  `gorm:"not null" json:"amount"`
+	FromUserID uint   `gorm:"not null;index" json:"fromUserId"`
+	ToUserID   uint   `gorm:"not null;index" json:"toUserId"`
+	Amount     int    `gorm:"not null" json:"amount"`
+	ReversalOf *uint  `gorm:"uniqueIndex" json:"reversalOf,omitempty"`               // перевод, который отменяет эта компенсирующая транзакция
+	Message    string `gorm:"size:200;not null;default:''" json:"message,omitempty"` // комментарий отправителя
+	Status     string `gorm:"size:20;not null;default:settled;index" json:"status"`
+	// ExpiresAt — срок подтверждения перевода с удержанием; после него монеты возвращаются отправителю.
+	ExpiresAt *time.Time `gorm:"index" json:"expiresAt,omitempty"`
+}
+
+// Статусы подозрительного перевода в очереди проверки.
+const (
+	FraudFlagOpen     = "open"
+	FraudFlagApproved = "approved" // перевод признан законным
+	FraudFlagRejected = "rejected" // перевод признан мошенническим
+)
+
+// FraudFlag — перевод, на котором сработали правила обнаружения мошенничества.
+// Помеченные переводы попадают в очередь проверки администратором.
+type FraudFlag struct {
+	gorm.Model
+	TransactionID uint       `gorm:"not null;uniqueIndex" json:"transactionId"`
+	Rules         string     `gorm:"not null" json:"rules"`   // сработавшие правила через запятую
+	Details       string     `gorm:"not null" json:"details"` // пояснения правил
+	Status        string     `gorm:"not null;default:open;index" json:"status"`
+	ReviewedBy    string     `json:"reviewedBy,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewedAt,omitempty"`
+	Comment       string     `json:"comment,omitempty"` // комментарий администратора
+}
+
+// Статусы запроса монет.
+const (
+	CoinRequestPending   = "pending"
+	CoinRequestAccepted  = "accepted"
+	CoinRequestDeclined  = "declined"
+	CoinRequestCancelled = "cancelled"
+	CoinRequestExpired   = "expired"
+)
+
+// CoinRequest — просьба пользователя FromUserID перевести ему монеты от пользователя ToUserID.
+type CoinRequest struct {
+	gorm.Model
+	FromUserID    uint       `gorm:"not null;index" json:"fromUserId"` // кто просит монеты
+	ToUserID      uint       `gorm:"not null;index" json:"toUserId"`   // у кого просят монеты
+	Amount        int        `gorm:"not null" json:"amount"`
+	Note          string     `json:"note"`
+	Status        string     `gorm:"not null;default:pending;index" json:"status"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expiresAt"`
+	TransactionID *uint      `json:"transactionId,omitempty"` // перевод, которым оплачен запрос
+	RespondedAt   *time.Time `json:"respondedAt,omitempty"`
+}
+
+// Статусы запланированного перевода.
+const (
+	ScheduledTransferActive    = "active"
+	ScheduledTransferPaused    = "paused"
+	ScheduledTransferCancelled = "cancelled"
+	ScheduledTransferCompleted = "completed" // разовый перевод выполнен
+	ScheduledTransferFailed    = "failed"    // разовый перевод не выполнен после всех попыток
+)
+
+// ScheduledTransfer — перевод монет, который фоновый обработчик выполняет в NextRunAt.
+// Пустое Schedule означает разовый перевод, иначе это cron-выражение повторения.
+type ScheduledTransfer struct {
+	gorm.Model
+	FromUserID        uint       `gorm:"not null;index" json:"fromUserId"`
+	ToUserID          uint       `gorm:"not null;index" json:"toUserId"`
+	Amount            int        `gorm:"not null;check:amount > 0" json:"amount"`
+	Message           string     `gorm:"size:200;not null;default:''" json:"message,omitempty"`
+	Schedule          string     `gorm:"not null;default:''" json:"schedule,omitempty"`
+	Status            string     `gorm:"not null;default:active;index" json:"status"`
+	NextRunAt         time.Time  `gorm:"not null;index" json:"nextRunAt"`
+	Runs              int        `gorm:"not null;default:0" json:"runs"`     // число попыток выполнения; защищает от повторного выполнения
+	Attempts          int        `gorm:"not null;default:0" json:"attempts"` // неудачные попытки подряд для текущего запуска
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+	LastError         string     `gorm:"not null;default:''" json:"lastError,omitempty"`
+	LastTransactionID *uint      `json:"lastTransactionId,omitempty"`
+}
+
+// Периоды начисления монет по политике.
+const (
+	AllowancePeriodDay   = "day"
+	AllowancePeriodWeek  = "week"
+	AllowancePeriodMonth = "month"
+)
+
+// AllowancePolicy — правило регулярного начисления монет. Политика применяется
+// к одному пользователю (UserID), ко всем пользователям роли (Role) или, если
+// не задано ни то ни другое, ко всем пользователям.
+type AllowancePolicy struct {
+	gorm.Model
+	Name   string `gorm:"not null" json:"name"`
+	Amount int    `gorm:"not null;check:amount > 0" json:"amount"`
+	Period string `gorm:"not null" json:"period"`
+	Role   string `gorm:"not null;default:''" json:"role,omitempty"`
+	UserID *uint  `gorm:"index" json:"userId,omitempty"`
+	Active bool   `gorm:"not null" json:"active"`
+}
+
+// AllowanceGrant фиксирует начисление по политике за период. Уникальный индекс
+// не даёт начислить монеты дважды за один период, даже если обработчик перезапущен.
+type AllowanceGrant struct {
+	gorm.Model
+	PolicyID      uint   `gorm:"not null;uniqueIndex:idx_allowance_grant_period" json:"policyId"`
+	UserID        uint   `gorm:"not null;uniqueIndex:idx_allowance_grant_period" json:"userId"`
+	Period        string `gorm:"not null;uniqueIndex:idx_allowance_grant_period" json:"period"` // например 2025-03, 2025-W11 или 2025-03-14
+	Amount        int    `gorm:"not null" json:"amount"`
+	LedgerEntryID uint   `gorm:"not null" json:"ledgerEntryId"`
+}
+
+// TransferLimitOverride — индивидуальные лимиты переводов пользователя, заданные администратором.
+// Незаданное поле означает лимит по умолчанию из конфигурации, 0 — отсутствие ограничения.
+type TransferLimitOverride struct {
+	gorm.Model
+	UserID          uint   `gorm:"not null;uniqueIndex" json:"userId"`
+	MaxAmount       *int   `json:"maxAmount,omitempty"`       // максимальная сумма одного перевода
+	DailyLimit      *int   `json:"dailyLimit,omitempty"`      // сумма исходящих переводов за 24 часа
+	WeeklyLimit     *int   `json:"weeklyLimit,omitempty"`     // сумма исходящих переводов за 7 дней
+	DailyRecipients *int   `json:"dailyRecipients,omitempty"` // число разных получателей за 24 часа
+	UpdatedBy       string `gorm:"not null" json:"updatedBy"`
+}
+
+// IdempotencyRecord хранит результат обработки запроса с заголовком Idempotency-Key,
+// чтобы повтор запроса с тем же ключом вернул исходный ответ, а не выполнился ещё раз.
+type IdempotencyRecord struct {
+	gorm.Model
+	Username       string    `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"username"`
+	IdempotencyKey string    `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"idempotencyKey"`
+	RequestHash    string    `gorm:"not null" json:"-"` // SHA-256 от метода, пути и тела запроса
+	Completed      bool      `gorm:"not null;default:false" json:"completed"`
+	StatusCode     int       `json:"statusCode"`
+	ContentType    string    `json:"contentType"`
+	ResponseBody   []byte    `json:"-"`
+	ExpiresAt      time.Time `gorm:"not null;index" json:"expiresAt"`
+}
+
+// Виды записей журнала монет.
+const (
+	LedgerKindGrant    = "grant"    // начисление монет пользователю
+	LedgerKindTransfer = "transfer" // перевод между пользователями
+	LedgerKindPurchase = "purchase" // покупка мерча
+	LedgerKindRefund   = "refund"   // возврат монет за покупку
+	LedgerKindReversal = "reversal" // отмена перевода администратором
+	LedgerKindExpiry   = "expiry"   // сгорание монет по истечении срока
+	LedgerKindRelease  = "release"  // зачисление или возврат удержанных монет перевода
+)
+
+// Счета журнала. Счёт пользователя задаётся полем UserID проводки,
+// остальные счета — системные.
+const (
+	LedgerAccountUser     = "user"
+	LedgerAccountIssuance = "system:issuance" // источник начисляемых монет
+	LedgerAccountShop     = "system:shop"     // монеты, потраченные на мерч
+	LedgerAccountExpired  = "system:expired"  // сгоревшие монеты
+	LedgerAccountHeld     = "system:held"     // монеты задержанных переводов и переводов с удержанием
+)
+
+// LedgerEntry — запись журнала, описывающая одно движение монет.
+// Сумма проводок записи всегда равна нулю.
+type LedgerEntry struct {
+	gorm.Model
+	Kind          string          `gorm:"not null;index" json:"kind"`
+	TransactionID *uint           `gorm:"index" json:"transactionId,omitempty"`
+	PurchaseID    *uint           `gorm:"index" json:"purchaseId,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	Postings      []LedgerPosting `gorm:"foreignKey:EntryID" json:"postings"`
+}
+
+// LedgerPosting — проводка по одному счёту.
+// Положительная сумма зачисляет монеты на счёт (кредит), отрицательная — списывает (дебет).
+type LedgerPosting struct {
+	gorm.Model
+	EntryID uint   `gorm:"not null;index" json:"entryId"`
+	Account string `gorm:"not null;index" json:"account"`
+	UserID  *uint  `gorm:"index" json:"userId,omitempty"`
+	Amount  int    `gorm:"not null" json:"amount"`
+}
+
+// CoinLot — партия монет на балансе пользователя с общим сроком действия.
+// Сумма Remaining непогашенных партий пользователя равна его балансу User.Coins.
+// Монеты тратятся начиная с партий, которые сгорят раньше всех; при переводе
+// партии переходят к получателю вместе со сроком действия.
+type CoinLot struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	Amount    int        `gorm:"not null" json:"amount"`
+	Remaining int        `gorm:"not null;check:remaining >= 0" json:"remaining"`
+	ExpiresAt *time.Time `gorm:"index" json:"expiresAt,omitempty"` // nil — монеты не сгорают
+	ExpiredAt *time.Time `json:"expiredAt,omitempty"`              // остаток партии сгорел
+}
+
+// HeldCoinLot — часть партии монет, списанная с отправителя перевода, который ещё не
+// завершён (задержан до проверки или ждёт подтверждения). При завершении перевода
+// монеты переходят получателю или возвращаются отправителю с исходным сроком действия;
+// монеты, срок которых истёк за время удержания, сгорают.
+type HeldCoinLot struct {
+	gorm.Model
+	TransactionID uint       `gorm:"not null;index" json:"transactionId"`
+	Amount        int        `gorm:"not null" json:"amount"`
+	Remaining     int        `gorm:"not null;check:remaining >= 0" json:"remaining"`
+	ExpiresAt     *time.Time `gorm:"index" json:"expiresAt,omitempty"`
+	ExpiredAt     *time.Time `json:"expiredAt,omitempty"`
+}
+
+// RefreshToken хранит хеш выданного refresh-токена.
+// Токены одной цепочки обновлений объединены общим FamilyID.
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	TokenHash string     `gorm:"not null;uniqueIndex" json:"-"` // SHA-256 от токена
+	FamilyID  string     `gorm:"not null;index" json:"familyId"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"` // токен уже обменян на новую пару
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// RevokedToken — идентификатор (jti) отозванного access-токена.
+// Запись нужна только до истечения срока действия самого токена.
+type RevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"not null;uniqueIndex" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+}
+
+// Действия, фиксируемые в журнале изменений каталога.
+const (
+	CatalogActionCreate  = "create"
+	CatalogActionUpdate  = "update"
+	CatalogActionDelete  = "delete"
+	CatalogActionRestore = "restore"
+	CatalogActionRestock = "restock"
+
+	// Изменения вариантов товара; снимки содержат вариант, а не товар
+	CatalogActionVariantCreate  = "variant_create"
+	CatalogActionVariantUpdate  = "variant_update"
+	CatalogActionVariantDelete  = "variant_delete"
+	CatalogActionVariantRestock = "variant_restock"
+
+	CatalogActionCategorize = "categorize" // смена категории товара
+	CatalogActionTag        = "tag"        // смена меток товара
+)
+
+// CatalogChange фиксирует изменение товара в каталоге.
+// Старое и новое значения хранятся в виде JSON-снимков товара или его варианта.
+type CatalogChange struct {
+	gorm.Model
+	MerchID   uint   `gorm:"not null;index" json:"merchId"`
+	Action    string `gorm:"not null" json:"action"`
+	OldValue  string `json:"oldValue,omitempty"`
+	NewValue  string `json:"newValue,omitempty"`
+	ChangedBy string `gorm:"not null" json:"changedBy"` // имя пользователя, внёсшего изменение
+}
+
+// All возвращает все модели приложения для создания и миграции схемы БД.
+func All() []interface{} {
+	return []interface{}{
+		&Merch{},
+		&MerchVariant{},
+		&Category{},
+		&MerchTag{},
+		&Purchase{},
+		&Transaction{},
+		&User{},
+		&IdempotencyRecord{},
+		&LedgerEntry{},
+		&LedgerPosting{},
+		&RefreshToken{},
+		&RevokedToken{},
+		&CatalogChange{},
+		&CartItem{},
+		&ReturnRequest{},
+		&CoinRequest{},
+		&ScheduledTransfer{},
+		&AllowancePolicy{},
+		&AllowanceGrant{},
+		&CoinLot{},
+		&HeldCoinLot{},
+		&TransferLimitOverride{},
+		&FraudFlag{},
+	}
 }
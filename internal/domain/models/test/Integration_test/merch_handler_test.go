@@ -1,12 +1,15 @@
 package integrationtest
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/defskela/merchmarket/internal/api/handlers"
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -17,7 +20,7 @@ import (
 func setupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&models.User{}, &models.Merch{}, &models.Purchase{}, models.Transaction{})
+	err = db.AutoMigrate(models.All()...)
 	assert.NoError(t, err)
 	return db
 }
@@ -72,3 +75,68 @@ func TestBuyMerchIntegration(t *testing.T) {
 	err = db.Where("user_id = ? AND merch_id = ?", user.ID, merch.ID).First(&purchase).Error
 	assert.NoError(t, err)
 }
+
+// TestBuyLimitedMerchConcurrently проверяет, что при параллельных покупках
+// товара с ограниченным запасом продаётся ровно столько единиц, сколько есть на складе.
+func TestBuyLimitedMerchConcurrently(t *testing.T) {
+	const (
+		buyers = 20
+		stock  = 5
+	)
+
+	db := setupFileDB(t)
+
+	available := stock
+	merch := models.Merch{Name: "pink-hoody", Price: 500, Stock: &available}
+	assert.NoError(t, db.Create(&merch).Error)
+	for i := 0; i < buyers; i++ {
+		user := models.User{Username: fmt.Sprintf("buyer%d", i)}
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			return services.CreateUser(tx, &user)
+		}))
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	router.GET("/buy/:item", handlers.NewMerchHandler(db).BuyItem)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		statuses = map[int]int{}
+	)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/buy/pink-hoody", nil)
+			req.Header.Set("X-Test-User", fmt.Sprintf("buyer%d", i))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			mu.Lock()
+			statuses[w.Code]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, stock, statuses[http.StatusOK])
+	assert.Equal(t, buyers-stock, statuses[http.StatusConflict])
+
+	var stored models.Merch
+	assert.NoError(t, db.First(&stored, merch.ID).Error)
+	assert.Equal(t, 0, *stored.Stock)
+
+	var purchases int64
+	db.Model(&models.Purchase{}).Where("merch_id = ?", merch.ID).Count(&purchases)
+	assert.Equal(t, int64(stock), purchases)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -0,0 +1,128 @@
+package integrationtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupFileDB создаёт файловую БД, к которой могут одновременно обращаться несколько соединений.
+// In-memory БД для этого не подходит: каждое соединение получает собственную пустую базу.
+func setupFileDB(t *testing.T) *gorm.DB {
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=10000&_txlock=immediate&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(models.All()...)
+	assert.NoError(t, err)
+	return db
+}
+
+// TestSendCoinConcurrentTransfers запускает сотни параллельных переводов между
+// несколькими пользователями и проверяет, что:
+//
+//	общее количество монет в системе не изменилось;
+//	ни один баланс не ушёл в минус;
+//	итоговый баланс каждого пользователя совпадает с историей его переводов и с журналом.
+func TestSendCoinConcurrentTransfers(t *testing.T) {
+	const (
+		usersCount   = 5
+		initialCoins = services.InitialCoins
+		transfers    = 300
+	)
+
+	db := setupFileDB(t)
+
+	users := make([]models.User, usersCount)
+	for i := range users {
+		users[i] = models.User{Username: fmt.Sprintf("user%d", i)}
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			return services.CreateUser(tx, &users[i])
+		}))
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	// Имитируем middleware: отправитель передаётся в тестовом заголовке
+	router.Use(func(c *gin.Context) {
+		c.Set("username", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+
+	walletHandler := handlers.NewWalletHandler(db)
+	router.POST("/sendCoin", walletHandler.SendCoin)
+
+	var wg sync.WaitGroup
+	codes := make([]int, transfers)
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Переводы идут в обе стороны, а суммы подобраны так, чтобы часть из них
+			// упиралась в нехватку средств
+			from := users[i%usersCount]
+			to := users[(i*3+1)%usersCount]
+			if from.ID == to.ID {
+				to = users[(i+1)%usersCount]
+			}
+			payload, _ := json.Marshal(handlers.SendCoinRequest{ToUser: to.Username, Amount: 50 + (i*37)%400})
+
+			req := httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBuffer(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Test-User", from.Username)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		assert.Containsf(t, []int{http.StatusOK, http.StatusBadRequest}, code, "перевод %d завершился с кодом %d", i, code)
+	}
+
+	var finalUsers []models.User
+	assert.NoError(t, db.Find(&finalUsers).Error)
+
+	total := 0
+	for _, user := range finalUsers {
+		assert.GreaterOrEqual(t, user.Coins, 0)
+		total += user.Coins
+	}
+	assert.Equal(t, usersCount*initialCoins, total)
+
+	// Баланс каждого пользователя должен сходиться с записанными транзакциями
+	var transactions []models.Transaction
+	assert.NoError(t, db.Find(&transactions).Error)
+
+	expected := make(map[uint]int, usersCount)
+	for _, user := range users {
+		expected[user.ID] = initialCoins
+	}
+	for _, tx := range transactions {
+		expected[tx.FromUserID] -= tx.Amount
+		expected[tx.ToUserID] += tx.Amount
+	}
+	for _, user := range finalUsers {
+		assert.Equal(t, expected[user.ID], user.Coins, "баланс пользователя %s", user.Username)
+	}
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
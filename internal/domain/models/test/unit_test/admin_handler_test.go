@@ -0,0 +1,97 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/api/routes"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupRouter собирает маршруты приложения через routes.SetupRoutes и возвращает
+// access-токен администратора admin.
+func setupRouter(t *testing.T) (*gorm.DB, *gin.Engine, string) {
+	db := setupTestDB(t)
+
+	router := gin.New()
+	routes.SetupRoutes(router, db)
+	return db, router, accessToken(t, db, "admin", models.RoleAdmin)
+}
+
+// accessToken создаёт пользователя с указанной ролью и возвращает его access-токен.
+func accessToken(t *testing.T, db *gorm.DB, username, role string) string {
+	user := models.User{Username: username, Role: role}
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return services.CreateUser(tx, &user)
+	}))
+	tokens, err := services.IssueTokens(db, &user)
+	assert.NoError(t, err)
+	return tokens.AccessToken
+}
+
+func TestAdmin_ForbiddenForUser(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	token := accessToken(t, db, "testuser", models.RoleUser)
+
+	w := doJSON(router, http.MethodGet, "/api/admin/reconciliation", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAdmin_Reconciliation(t *testing.T) {
+	db, router, token := setupRouter(t)
+
+	// Баланс, изменённый в обход журнала, попадает в отчёт
+	drifted := models.User{Username: "drifted", Coins: 50}
+	assert.NoError(t, db.Create(&drifted).Error)
+
+	w := doJSON(router, http.MethodGet, "/api/admin/reconciliation", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp handlers.ReconciliationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Mismatches, 1)
+	assert.Equal(t, "drifted", resp.Mismatches[0].Username)
+}
+
+func TestAdmin_SetUserRole(t *testing.T) {
+	db, router, token := setupRouter(t)
+	accessToken(t, db, "testuser", models.RoleUser)
+
+	w := doJSON(router, http.MethodPut, "/api/admin/users/testuser/role", token, handlers.SetRoleRequest{Role: "superuser"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodPut, "/api/admin/users/nobody/role", token, handlers.SetRoleRequest{Role: models.RoleAdmin})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodPut, "/api/admin/users/testuser/role", token, handlers.SetRoleRequest{Role: models.RoleAdmin})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var user models.User
+	assert.NoError(t, db.Where("username = ?", "testuser").First(&user).Error)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+}
+
+func TestEnsureAdmin(t *testing.T) {
+	db := setupTestDB(t)
+
+	assert.ErrorIs(t, services.EnsureAdmin(db, "root", ""), services.ErrEmptyAdminPassword)
+
+	assert.NoError(t, services.EnsureAdmin(db, "root", "password123"))
+	var admin models.User
+	assert.NoError(t, db.Where("username = ?", "root").First(&admin).Error)
+	assert.Equal(t, models.RoleAdmin, admin.Role)
+	assert.Equal(t, services.InitialCoins, admin.Coins)
+
+	// Существующий пользователь получает роль администратора
+	accessToken(t, db, "operator", models.RoleUser)
+	assert.NoError(t, services.EnsureAdmin(db, "operator", ""))
+	var operator models.User
+	assert.NoError(t, db.Where("username = ?", "operator").First(&operator).Error)
+	assert.Equal(t, models.RoleAdmin, operator.Role)
+}
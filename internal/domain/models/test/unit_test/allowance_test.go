@@ -0,0 +1,109 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func runAllowances(t *testing.T, router *gin.Engine, token, query string) handlers.AllowanceRunResponse {
+	w := doJSON(router, http.MethodPost, "/api/admin/allowances/run"+query, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp handlers.AllowanceRunResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestAllowances_DryRunAndIdempotentGrant(t *testing.T) {
+	db, router, token := setupRouter(t)
+	alice := createLedgerUser(t, db, "alice")
+	createLedgerUser(t, db, "bob")
+
+	w := doJSON(router, http.MethodPost, "/api/admin/allowances", token,
+		handlers.CreateAllowancePolicyRequest{Name: "Зарплата", Amount: 100, Period: "fortnight"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/admin/allowances", token,
+		handlers.CreateAllowancePolicyRequest{Name: "Зарплата", Amount: 100, Period: models.AllowancePeriodMonth, Role: models.RoleUser})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/admin/allowances", token,
+		handlers.CreateAllowancePolicyRequest{Name: "Наставник", Amount: 30, Period: models.AllowancePeriodWeek, Username: "alice"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	inactive := false
+	w = doJSON(router, http.MethodPost, "/api/admin/allowances", token,
+		handlers.CreateAllowancePolicyRequest{Name: "Премия", Amount: 500, Period: models.AllowancePeriodDay, Active: &inactive})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Предпросмотр ничего не начисляет
+	preview := runAllowances(t, router, token, "?dryRun=true")
+	assert.True(t, preview.DryRun)
+	assert.Len(t, preview.Grants, 3)
+	assert.Equal(t, 230, preview.Total)
+	assertBalances(t, db, alice, 1000, 0)
+
+	granted := runAllowances(t, router, token, "")
+	assert.False(t, granted.DryRun)
+	assert.Equal(t, preview.Grants, granted.Grants)
+	assertBalances(t, db, alice, 1130, 0)
+
+	// Повторный запуск в том же периоде (например, после перезапуска) ничего не начисляет
+	assert.Empty(t, runAllowances(t, router, token, "").Grants)
+	again, err := services.GrantAllowances(db, time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, again)
+	assertBalances(t, db, alice, 1130, 0)
+
+	// В следующем периоде начисление выполняется снова
+	next, err := services.GrantAllowances(db, time.Now().AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Len(t, next, 3)
+
+	var grants int64
+	assert.NoError(t, db.Model(&models.LedgerEntry{}).Where("kind = ? AND description LIKE ?", models.LedgerKindGrant, "Начисление по политике%").Count(&grants).Error)
+	assert.Equal(t, int64(6), grants)
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestAllowances_UpdateAndDelete(t *testing.T) {
+	db, router, token := setupRouter(t)
+	alice := createLedgerUser(t, db, "alice")
+
+	w := doJSON(router, http.MethodPost, "/api/admin/allowances", token,
+		handlers.CreateAllowancePolicyRequest{Name: "Зарплата", Amount: 100, Period: models.AllowancePeriodMonth, Username: "alice"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var policy handlers.AllowancePolicyResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &policy))
+	assert.Equal(t, "alice", policy.Username)
+	assert.True(t, policy.Active)
+
+	amount := 250
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/allowances/%d", policy.ID), token,
+		handlers.UpdateAllowancePolicyRequest{Amount: &amount})
+	assert.Equal(t, http.StatusOK, w.Code)
+	runAllowances(t, router, token, "")
+	assertBalances(t, db, alice, 1250, 0)
+
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/allowances/%d", policy.ID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/allowances/%d", policy.ID), token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	next, err := services.GrantAllowances(db, time.Now().AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+
+	var list []handlers.AllowancePolicyResponse
+	w = doJSON(router, http.MethodGet, "/api/admin/allowances", token, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Empty(t, list)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/api/middlewares"
 	"github.com/defskela/merchmarket/internal/domain/models"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -20,7 +21,7 @@ import (
 func setupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&models.User{}, &models.Purchase{}, &models.Merch{}, &models.Transaction{})
+	err = db.AutoMigrate(models.All()...)
 	assert.NoError(t, err)
 	return db
 }
@@ -45,6 +46,22 @@ func performAuthRequest(handler *handlers.AuthHandler, requestBody interface{})
 	return w, nil
 }
 
+func performRegisterRequest(handler *handlers.AuthHandler, requestBody interface{}) (*httptest.ResponseRecorder, error) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Register(c)
+	return w, nil
+}
+
 func TestAuthenticate_InvalidJSON(t *testing.T) {
 	db := setupTestDB(t)
 	handler := &handlers.AuthHandler{Db: db}
@@ -67,7 +84,7 @@ func TestAuthenticate_InvalidJSON(t *testing.T) {
 
 func TestAuthenticate_NewUser(t *testing.T) {
 	db := setupTestDB(t)
-	handler := &handlers.AuthHandler{Db: db}
+	handler := &handlers.AuthHandler{Db: db, AutoRegister: true}
 
 	reqBody := handlers.AuthRequest{Username: "newuser", Password: "password123"}
 	w, err := performAuthRequest(handler, reqBody)
@@ -87,6 +104,36 @@ func TestAuthenticate_NewUser(t *testing.T) {
 	assert.Equal(t, 1000, user.Coins)
 }
 
+func TestAuthenticate_NewUser_LegacyPassword(t *testing.T) {
+	db := setupTestDB(t)
+	handler := &handlers.AuthHandler{Db: db, AutoRegister: true}
+
+	// Автоматическая регистрация не применяет политику паролей /register
+	reqBody := handlers.AuthRequest{Username: "legacy", Password: "pass"}
+	w, err := performAuthRequest(handler, reqBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, err = performAuthRequest(handler, reqBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticate_UnknownUser_LoginOnly(t *testing.T) {
+	db := setupTestDB(t)
+	handler := &handlers.AuthHandler{Db: db}
+
+	reqBody := handlers.AuthRequest{Username: "newuser", Password: "password123"}
+	w, err := performAuthRequest(handler, reqBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Без AUTH_AUTO_REGISTER вход не создаёт аккаунт
+	var count int64
+	db.Model(&models.User{}).Where("username = ?", "newuser").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
 func TestAuthenticate_ExistingUser_ValidPassword(t *testing.T) {
 	db := setupTestDB(t)
 	handler := &handlers.AuthHandler{Db: db}
@@ -128,3 +175,158 @@ func TestAuthenticate_ExistingUser_InvalidPassword(t *testing.T) {
 	// Исправлен ключ ошибки "errors" → "error"
 	assert.Equal(t, "Неверный пароль", resp["error"])
 }
+
+func TestRegister_Success(t *testing.T) {
+	db := setupTestDB(t)
+	handler := &handlers.AuthHandler{Db: db}
+
+	reqBody := handlers.RegisterRequest{Username: "newuser", Password: "password123"}
+	w, err := performRegisterRequest(handler, reqBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp handlers.AuthResponse
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Token)
+
+	var user models.User
+	err = db.Where("username = ?", "newuser").First(&user).Error
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, user.Coins)
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	db := setupTestDB(t)
+	handler := &handlers.AuthHandler{Db: db}
+
+	reqBody := handlers.RegisterRequest{Username: "newuser", Password: "password123"}
+	_, err := performRegisterRequest(handler, reqBody)
+	assert.NoError(t, err)
+
+	w, err := performRegisterRequest(handler, reqBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var resp map[string]string
+	err = json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "Пользователь уже существует", resp["error"])
+}
+
+func TestRegister_PolicyViolations(t *testing.T) {
+	db := setupTestDB(t)
+	handler := &handlers.AuthHandler{Db: db}
+
+	cases := []handlers.RegisterRequest{
+		{Username: "ab", Password: "password123"},
+		{Username: "bad name", Password: "password123"},
+		{Username: "newuser", Password: "short1"},
+		{Username: "newuser", Password: "onlyletters"},
+		{Username: "newuser", Password: "1234567890"},
+	}
+	for _, reqBody := range cases {
+		w, err := performRegisterRequest(handler, reqBody)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "%+v", reqBody)
+	}
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+// setupAuthRouter собирает роутер с публичными и защищёнными эндпоинтами аутентификации.
+func setupAuthRouter(t *testing.T) *gin.Engine {
+	db := setupTestDB(t)
+	handler := &handlers.AuthHandler{Db: db}
+
+	router := gin.New()
+	router.POST("/api/register", handler.Register)
+	router.POST("/api/auth", handler.Authenticate)
+	router.POST("/api/auth/refresh", handler.Refresh)
+	api := router.Group("/api")
+	api.Use(middlewares.JWTAuthMiddleware(db))
+	api.POST("/auth/logout", handler.Logout)
+	api.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func doJSON(router *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func registerTokens(t *testing.T, router *gin.Engine) handlers.AuthResponse {
+	w := doJSON(router, http.MethodPost, "/api/register", "", handlers.RegisterRequest{Username: "newuser", Password: "password123"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp handlers.AuthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Positive(t, resp.ExpiresIn)
+	return resp
+}
+
+func TestRefresh_RotatesTokens(t *testing.T) {
+	router := setupAuthRouter(t)
+	tokens := registerTokens(t, router)
+
+	w := doJSON(router, http.MethodPost, "/api/auth/refresh", "", handlers.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rotated handlers.AuthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+	w = doJSON(router, http.MethodGet, "/api/ping", rotated.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	router := setupAuthRouter(t)
+	tokens := registerTokens(t, router)
+
+	w := doJSON(router, http.MethodPost, "/api/auth/refresh", "", handlers.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rotated handlers.AuthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+
+	// Повторное использование старого токена отзывает всю цепочку
+	w = doJSON(router, http.MethodPost, "/api/auth/refresh", "", handlers.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doJSON(router, http.MethodPost, "/api/auth/refresh", "", handlers.RefreshRequest{RefreshToken: rotated.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRefresh_UnknownToken(t *testing.T) {
+	router := setupAuthRouter(t)
+
+	w := doJSON(router, http.MethodPost, "/api/auth/refresh", "", handlers.RefreshRequest{RefreshToken: "unknown"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogout_RevokesTokens(t *testing.T) {
+	router := setupAuthRouter(t)
+	tokens := registerTokens(t, router)
+
+	w := doJSON(router, http.MethodPost, "/api/auth/logout", tokens.Token, handlers.LogoutRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Access-токен попал в список отозванных
+	w = doJSON(router, http.MethodGet, "/api/ping", tokens.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Refresh-токен больше нельзя обменять
+	w = doJSON(router, http.MethodPost, "/api/auth/refresh", "", handlers.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupCartRouter собирает эндпоинты корзины, создаёт товары и возвращает токен пользователя с балансом 1000.
+func setupCartRouter(t *testing.T) (*gorm.DB, *gin.Engine, string) {
+	db, router, _ := setupRouter(t)
+
+	stock := 1
+	for _, merch := range []models.Merch{
+		{Name: "pen", Price: 10},
+		{Name: "cup", Price: 20},
+		{Name: "hoody", Price: 300},
+		{Name: "pink-hoody", Price: 500, Stock: &stock},
+	} {
+		assert.NoError(t, db.Create(&merch).Error)
+	}
+
+	return db, router, accessToken(t, db, "buyer", models.RoleUser)
+}
+
+func addToCart(t *testing.T, router *gin.Engine, token, item string, quantity int) handlers.CartResponse {
+	w := doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: item, Quantity: quantity})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var cart handlers.CartResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cart))
+	return cart
+}
+
+func TestCart_AddAndRemove(t *testing.T) {
+	_, router, token := setupCartRouter(t)
+
+	addToCart(t, router, token, "pen", 3)
+	cart := addToCart(t, router, token, "pen", 2)
+	assert.Len(t, cart.Items, 1)
+	assert.Equal(t, 5, cart.Items[0].Quantity)
+	assert.Equal(t, 50, cart.Total)
+
+	cart = addToCart(t, router, token, "cup", 0)
+	assert.Equal(t, 1, cart.Items[1].Quantity)
+	assert.Equal(t, 70, cart.Total)
+
+	w := doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "unknown", Quantity: 1})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "pen", Quantity: services.MaxCartQuantity})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodDelete, "/api/cart/items/pen?quantity=2", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodDelete, "/api/cart/items/cup", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodDelete, "/api/cart/items/cup", token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodGet, "/api/cart", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cart))
+	assert.Len(t, cart.Items, 1)
+	assert.Equal(t, 3, cart.Items[0].Quantity)
+}
+
+func TestCart_Checkout(t *testing.T) {
+	db, router, token := setupCartRouter(t)
+	addToCart(t, router, token, "pen", 5)
+	addToCart(t, router, token, "cup", 1)
+
+	w := doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp handlers.CheckoutResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 70, resp.Total)
+	assert.Len(t, resp.Items, 2)
+
+	var user models.User
+	assert.NoError(t, db.Where("username = ?", "buyer").First(&user).Error)
+	assert.Equal(t, 930, user.Coins)
+
+	var purchases int64
+	db.Model(&models.Purchase{}).Where("user_id = ?", user.ID).Count(&purchases)
+	assert.Equal(t, int64(6), purchases)
+
+	// Корзина очищается, повторное оформление невозможно
+	w = doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestCart_CheckoutFailsAsWhole(t *testing.T) {
+	db, router, token := setupCartRouter(t)
+	addToCart(t, router, token, "pen", 2)
+	addToCart(t, router, token, "pink-hoody", 2)
+	addToCart(t, router, token, "cup", 1)
+
+	var cup models.Merch
+	assert.NoError(t, db.Where("name = ?", "cup").First(&cup).Error)
+	assert.NoError(t, services.DeleteMerch(db, "admin", cup.ID))
+
+	w := doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var resp handlers.CheckoutErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Lines, 2)
+	assert.Equal(t, "pink-hoody", resp.Lines[0].Item)
+	assert.Equal(t, 1, *resp.Lines[0].Available)
+	assert.Equal(t, "cup", resp.Lines[1].Item)
+	assert.Nil(t, resp.Lines[1].Available)
+
+	// Ничего не куплено: баланс, остатки и корзина не изменились
+	var user models.User
+	assert.NoError(t, db.Where("username = ?", "buyer").First(&user).Error)
+	assert.Equal(t, 1000, user.Coins)
+	var hoody models.Merch
+	assert.NoError(t, db.Where("name = ?", "pink-hoody").First(&hoody).Error)
+	assert.Equal(t, 1, *hoody.Stock)
+	var purchases int64
+	db.Model(&models.Purchase{}).Count(&purchases)
+	assert.Equal(t, int64(0), purchases)
+	var cartItems int64
+	db.Model(&models.CartItem{}).Where("user_id = ?", user.ID).Count(&cartItems)
+	assert.Equal(t, int64(3), cartItems)
+}
+
+func TestCart_CheckoutInsufficientFunds(t *testing.T) {
+	db, router, token := setupCartRouter(t)
+	addToCart(t, router, token, "hoody", 3)
+	addToCart(t, router, token, "pink-hoody", 1)
+
+	w := doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var hoody models.Merch
+	assert.NoError(t, db.Where("name = ?", "pink-hoody").First(&hoody).Error)
+	assert.Equal(t, 1, *hoody.Stock)
+}
+
+func TestCart_Variants(t *testing.T) {
+	db, router, token := setupCartRouter(t)
+	var hoody models.Merch
+	assert.NoError(t, db.Where("name = ?", "hoody").First(&hoody).Error)
+	one, price := 1, 350
+	medium, err := services.CreateVariant(db, "admin", hoody.ID, "M", nil, &one, nil)
+	assert.NoError(t, err)
+	_, err = services.CreateVariant(db, "admin", hoody.ID, "XL", nil, nil, &price)
+	assert.NoError(t, err)
+
+	w := doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "hoody"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "hoody", Variant: "S"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "pen", Variant: "M"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	for _, variant := range []string{"M", "XL", "M"} {
+		w = doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "hoody", Variant: variant})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	cart := addToCart(t, router, token, "pen", 1)
+	assert.Equal(t, []handlers.CartLine{
+		{Item: "hoody", Variant: "M", Quantity: 2, Price: 300, Subtotal: 600},
+		{Item: "hoody", Variant: "XL", Quantity: 1, Price: 350, Subtotal: 350, Available: true},
+		{Item: "pen", Quantity: 1, Price: 10, Subtotal: 10, Available: true},
+	}, cart.Items)
+
+	// Остаток проверяется у варианта, а не у товара
+	w = doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var conflict handlers.CheckoutErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &conflict))
+	if assert.Len(t, conflict.Lines, 1) {
+		assert.Equal(t, "M", conflict.Lines[0].Variant)
+		assert.Equal(t, 1, *conflict.Lines[0].Available)
+	}
+
+	w = doJSON(router, http.MethodDelete, "/api/cart/items/hoody?variant=M&quantity=1", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp handlers.CheckoutResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 660, resp.Total)
+	assert.Equal(t, []handlers.CartLine{
+		{Item: "hoody", Variant: "M", Quantity: 1, Price: 300, Subtotal: 300, Available: true},
+		{Item: "hoody", Variant: "XL", Quantity: 1, Price: 350, Subtotal: 350, Available: true},
+		{Item: "pen", Quantity: 1, Price: 10, Subtotal: 10, Available: true},
+	}, resp.Items)
+
+	assertBalances(t, db, userByName(t, db, "buyer"), 1000-660, 0)
+	assert.NoError(t, db.First(medium, medium.ID).Error)
+	assert.Equal(t, 0, *medium.Stock)
+	var purchases int64
+	db.Model(&models.Purchase{}).Where("variant_id IS NOT NULL").Count(&purchases)
+	assert.Equal(t, int64(2), purchases)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestCart_VariantsAddedWhileInCart(t *testing.T) {
+	db, router, token := setupCartRouter(t)
+	addToCart(t, router, token, "cup", 1)
+
+	var cup models.Merch
+	assert.NoError(t, db.Where("name = ?", "cup").First(&cup).Error)
+	_, err := services.CreateVariant(db, "admin", cup.ID, "white", nil, nil, nil)
+	assert.NoError(t, err)
+
+	// У товара появились варианты, пока он лежал в корзине: нужно выбрать вариант
+	w := doJSON(router, http.MethodPost, "/api/cart/checkout", token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var resp handlers.CheckoutErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Lines, 1) {
+		assert.Equal(t, "Выберите вариант товара", resp.Lines[0].Error)
+	}
+}
@@ -0,0 +1,297 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createMerchViaAPI(t *testing.T, router *gin.Engine, token, name string, price int) handlers.MerchResponse {
+	w := doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: name, Price: price})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var merch handlers.MerchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &merch))
+	return merch
+}
+
+func TestCatalog_CreateValidation(t *testing.T) {
+	_, router, token := setupRouter(t)
+	createMerchViaAPI(t, router, token, "cup", 20)
+
+	w := doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: "   ", Price: 20})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: "pen", Price: -5})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: "cup", Price: 30})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCatalog_UpdateRecordsChange(t *testing.T) {
+	db, router, token := setupRouter(t)
+	merch := createMerchViaAPI(t, router, token, "cup", 20)
+
+	price := 25
+	w := doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/merch/%d", merch.ID), token, handlers.UpdateMerchRequest{Price: &price})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Merch
+	assert.NoError(t, db.First(&stored, merch.ID).Error)
+	assert.Equal(t, 25, stored.Price)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/api/admin/merch/%d/changes", merch.ID), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var changes []handlers.CatalogChangeEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+	assert.Len(t, changes, 2)
+	assert.Equal(t, models.CatalogActionCreate, changes[0].Action)
+	assert.Equal(t, models.CatalogActionUpdate, changes[1].Action)
+	assert.Equal(t, "admin", changes[1].ChangedBy)
+	assert.JSONEq(t, `{"name":"cup","price":20,"deleted":false}`, string(changes[1].OldValue))
+	assert.JSONEq(t, `{"name":"cup","price":25,"deleted":false}`, string(changes[1].NewValue))
+}
+
+func TestCatalog_DeleteAndRestore(t *testing.T) {
+	db, router, token := setupRouter(t)
+	merch := createMerchViaAPI(t, router, token, "cup", 20)
+	path := fmt.Sprintf("/api/admin/merch/%d", merch.ID)
+
+	w := doJSON(router, http.MethodDelete, path, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Удалённый товар не виден обычным запросам, но его название остаётся занятым
+	var count int64
+	db.Model(&models.Merch{}).Where("name = ?", "cup").Count(&count)
+	assert.Equal(t, int64(0), count)
+	w = doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: "cup", Price: 30})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(router, http.MethodDelete, path, token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodPost, path+"/restore", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.Merch{}).Where("name = ?", "cup").Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	w = doJSON(router, http.MethodPost, path+"/restore", token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCatalog_RestockAndLowStock(t *testing.T) {
+	_, router, token := setupRouter(t)
+	createMerchViaAPI(t, router, token, "cup", 20)
+
+	stock := 2
+	w := doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: "pink-hoody", Price: 500, Stock: &stock})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var hoody handlers.MerchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hoody))
+
+	// В отчёт попадают только товары с ограниченным запасом
+	w = doJSON(router, http.MethodGet, "/api/admin/merch/low-stock?threshold=3", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var low []handlers.LowStockItem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &low))
+	assert.Len(t, low, 1)
+	assert.Equal(t, "pink-hoody", low[0].Name)
+
+	path := fmt.Sprintf("/api/admin/merch/%d/restock", hoody.ID)
+	w = doJSON(router, http.MethodPost, path, token, handlers.RestockRequest{Quantity: 10})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restocked handlers.MerchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &restocked))
+	assert.Equal(t, 12, *restocked.Stock)
+
+	w = doJSON(router, http.MethodGet, "/api/admin/merch/low-stock?threshold=3", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &low))
+	assert.Empty(t, low)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/api/admin/merch/%d/changes", hoody.ID), token, nil)
+	var changes []handlers.CatalogChangeEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+	assert.Len(t, changes, 2)
+	assert.Equal(t, models.CatalogActionRestock, changes[1].Action)
+	assert.JSONEq(t, `{"name":"pink-hoody","price":500,"stock":12,"deleted":false}`, string(changes[1].NewValue))
+
+	w = doJSON(router, http.MethodPost, path, token, handlers.RestockRequest{Quantity: -1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Запас товара без ограничения пополнить нельзя
+	var all []handlers.MerchResponse
+	w = doJSON(router, http.MethodGet, "/api/admin/merch", token, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/restock", all[0].ID), token, handlers.RestockRequest{Quantity: 1})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCatalog_LowStockVariants(t *testing.T) {
+	_, router, token := setupRouter(t)
+	two, price := 2, 350
+	w := doJSON(router, http.MethodPost, "/api/admin/merch", token, handlers.CreateMerchRequest{Name: "cup", Price: 20, Stock: &two})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	hoody := createMerchViaAPI(t, router, token, "hoody", 300)
+	one, ten := 1, 10
+	large := createVariant(t, router, token, hoody.ID, handlers.CreateVariantRequest{Name: "L", Stock: &one, Price: &price})
+	createVariant(t, router, token, hoody.ID, handlers.CreateVariantRequest{Name: "M", Stock: &ten})
+	createVariant(t, router, token, hoody.ID, handlers.CreateVariantRequest{Name: "XL"})
+
+	// Вариант, который почти закончился, попадает в отчёт вместе с названием товара
+	w = doJSON(router, http.MethodGet, "/api/admin/merch/low-stock?threshold=3", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var low []handlers.LowStockItem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &low))
+	if assert.Len(t, low, 2) {
+		assert.Equal(t, "hoody", low[0].Name)
+		assert.Equal(t, hoody.ID, low[0].ID)
+		assert.Equal(t, "L", low[0].Variant)
+		assert.Equal(t, large.ID, *low[0].VariantID)
+		assert.Equal(t, 1, *low[0].Stock)
+		assert.Equal(t, 350, low[0].Price)
+		assert.Equal(t, "cup", low[1].Name)
+		assert.Nil(t, low[1].VariantID)
+		assert.Equal(t, 2, *low[1].Stock)
+	}
+
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/variants/%d/restock", hoody.ID, large.ID), token, handlers.RestockRequest{Quantity: 5})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodGet, "/api/admin/merch/low-stock?threshold=3", token, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &low))
+	if assert.Len(t, low, 1) {
+		assert.Equal(t, "cup", low[0].Name)
+	}
+
+	// Варианты удалённого товара в отчёт не попадают
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/merch/%d", hoody.ID), token, nil).Code)
+	w = doJSON(router, http.MethodGet, "/api/admin/merch/low-stock?threshold=100", token, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &low))
+	assert.Len(t, low, 1)
+}
+
+func TestCatalog_RestockVariant(t *testing.T) {
+	_, router, token := setupRouter(t)
+	hoody := createMerchViaAPI(t, router, token, "hoody", 300)
+	one := 1
+	large := createVariant(t, router, token, hoody.ID, handlers.CreateVariantRequest{Name: "L", Stock: &one})
+	medium := createVariant(t, router, token, hoody.ID, handlers.CreateVariantRequest{Name: "M"})
+
+	// Остаток товара с вариантами пополняется по вариантам
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/restock", hoody.ID), token, handlers.RestockRequest{Quantity: 5})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "по вариантам")
+
+	path := fmt.Sprintf("/api/admin/merch/%d/variants/%d/restock", hoody.ID, large.ID)
+	w = doJSON(router, http.MethodPost, path, token, handlers.RestockRequest{Quantity: 4})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var restocked handlers.VariantResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &restocked))
+	assert.Equal(t, 5, *restocked.Stock)
+
+	assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodPost, path, token, handlers.RestockRequest{Quantity: -1}).Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/variants/%d/restock", hoody.ID, medium.ID), token, handlers.RestockRequest{Quantity: 1})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/variants/%d/restock", hoody.ID+1, large.ID), token, handlers.RestockRequest{Quantity: 1})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/api/admin/merch/%d/changes", hoody.ID), token, nil)
+	var changes []handlers.CatalogChangeEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+	if assert.Len(t, changes, 4) {
+		assert.Equal(t, models.CatalogActionVariantRestock, changes[3].Action)
+		assert.JSONEq(t, fmt.Sprintf(`{"id":%d,"name":"L","stock":5,"deleted":false}`, large.ID), string(changes[3].NewValue))
+	}
+}
+
+// setupCatalogListing создаёт пользователя с балансом 100 и набор товаров.
+func setupCatalogListing(t *testing.T) (*handlers.CatalogHandler, func(query string) handlers.MerchListResponse) {
+	db := setupTestDB(t)
+	assert.NoError(t, db.Create(&models.User{Username: "testuser", Coins: 100}).Error)
+	for _, merch := range []models.Merch{
+		{Name: "t-shirt", Price: 80},
+		{Name: "cup", Price: 20},
+		{Name: "book", Price: 50},
+		{Name: "pen", Price: 10},
+		{Name: "hoody", Price: 300},
+		{Name: "pink-hoody", Price: 500},
+		{Name: "100%_cotton", Price: 40},
+	} {
+		assert.NoError(t, db.Create(&merch).Error)
+	}
+
+	handler := handlers.NewCatalogHandler(db)
+	list := func(query string) handlers.MerchListResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/merch?"+query, nil)
+		c.Set("username", "testuser")
+		handler.ListMerch(c)
+		assert.Equal(t, http.StatusOK, w.Code, query)
+
+		var resp handlers.MerchListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	return handler, list
+}
+
+func merchNames(resp handlers.MerchListResponse) []string {
+	names := make([]string, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func TestListMerch_Pagination(t *testing.T) {
+	_, list := setupCatalogListing(t)
+
+	resp := list("pageSize=3&page=2")
+	assert.Equal(t, int64(7), resp.Total)
+	assert.Equal(t, []string{"hoody", "pen", "pink-hoody"}, merchNames(resp))
+}
+
+func TestListMerch_FiltersAndSorting(t *testing.T) {
+	_, list := setupCatalogListing(t)
+
+	resp := list("minPrice=20&maxPrice=80&sort=price&order=desc")
+	assert.Equal(t, []string{"t-shirt", "book", "100%_cotton", "cup"}, merchNames(resp))
+
+	resp = list("q=HOODY")
+	assert.Equal(t, []string{"hoody", "pink-hoody"}, merchNames(resp))
+
+	// Спецсимволы LIKE ищутся буквально
+	resp = list("q=%25_")
+	assert.Equal(t, []string{"100%_cotton"}, merchNames(resp))
+}
+
+func TestListMerch_Affordable(t *testing.T) {
+	_, list := setupCatalogListing(t)
+
+	resp := list("affordable=true&sort=price")
+	assert.Equal(t, []string{"pen", "cup", "100%_cotton", "book", "t-shirt"}, merchNames(resp))
+	assert.Equal(t, int64(5), resp.Total)
+}
+
+func TestListMerch_InvalidParams(t *testing.T) {
+	handler, _ := setupCatalogListing(t)
+
+	for _, query := range []string{"page=0", "pageSize=1000", "minPrice=abc", "sort=id", "order=up", "affordable=maybe"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/merch?"+query, nil)
+		c.Set("username", "testuser")
+		handler.ListMerch(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
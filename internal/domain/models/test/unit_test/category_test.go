@@ -0,0 +1,168 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createCategory(t *testing.T, router *gin.Engine, token, name string, parentID *uint) handlers.CategoryResponse {
+	w := doJSON(router, http.MethodPost, "/api/admin/categories", token, handlers.CreateCategoryRequest{Name: name, ParentID: parentID})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var category handlers.CategoryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &category))
+	return category
+}
+
+func setMerchCategory(t *testing.T, router *gin.Engine, token string, merchID uint, categoryID *uint) handlers.MerchResponse {
+	w := doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/merch/%d/category", merchID), token,
+		handlers.SetMerchCategoryRequest{CategoryID: categoryID})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var merch handlers.MerchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &merch))
+	return merch
+}
+
+func TestCategories_FilterByCategoryAndTags(t *testing.T) {
+	db, router, adminToken := setupRouter(t)
+	clothes := createCategory(t, router, adminToken, "Одежда", nil)
+	hoodies := createCategory(t, router, adminToken, "Худи", &clothes.ID)
+	hoodie := createMerchViaAPI(t, router, adminToken, "hoodie", 300)
+	tshirt := createMerchViaAPI(t, router, adminToken, "t-shirt", 80)
+	createMerchViaAPI(t, router, adminToken, "cup", 20)
+
+	merch := setMerchCategory(t, router, adminToken, hoodie.ID, &hoodies.ID)
+	assert.Equal(t, "Одежда / Худи", merch.Category)
+	setMerchCategory(t, router, adminToken, tshirt.ID, &clothes.ID)
+
+	w := doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/merch/%d/tags", hoodie.ID), adminToken,
+		handlers.SetMerchTagsRequest{Tags: []string{" Winter ", "sale", "winter"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &merch))
+	assert.Equal(t, []string{"sale", "winter"}, merch.Tags)
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/merch/%d/tags", tshirt.ID), adminToken,
+		handlers.SetMerchTagsRequest{Tags: []string{"sale"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/merch/%d/tags", tshirt.ID), adminToken,
+		handlers.SetMerchTagsRequest{Tags: []string{""}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	token := accessToken(t, db, "testuser", models.RoleUser)
+	listNames := func(query string) []string {
+		w := doJSON(router, http.MethodGet, "/api/merch"+query, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var list handlers.MerchListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		names := []string{}
+		for _, item := range list.Items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	// Фильтр по категории включает товары вложенных категорий
+	assert.Equal(t, []string{"hoodie", "t-shirt"}, listNames(fmt.Sprintf("?category=%d", clothes.ID)))
+	assert.Equal(t, []string{"hoodie"}, listNames(fmt.Sprintf("?category=%d", hoodies.ID)))
+	assert.Equal(t, []string{"hoodie", "t-shirt"}, listNames("?tag=SALE"))
+	assert.Equal(t, []string{"hoodie"}, listNames("?tag=sale&tag=winter"))
+	assert.Equal(t, []string{"t-shirt"}, listNames(fmt.Sprintf("?category=%d&maxPrice=100&tag=sale", clothes.ID)))
+	assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodGet, "/api/merch?category=abc", token, nil).Code)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/api/admin/merch/%d/changes", hoodie.ID), adminToken, nil)
+	var changes []handlers.CatalogChangeEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+	actions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		actions = append(actions, change.Action)
+	}
+	assert.Equal(t, []string{models.CatalogActionCreate, models.CatalogActionCategorize, models.CatalogActionTag}, actions)
+}
+
+func TestCategories_TreeManagement(t *testing.T) {
+	_, router, adminToken := setupRouter(t)
+	clothes := createCategory(t, router, adminToken, "Одежда", nil)
+	hoodies := createCategory(t, router, adminToken, "Худи", &clothes.ID)
+	createCategory(t, router, adminToken, "Посуда", nil)
+
+	w := doJSON(router, http.MethodPost, "/api/admin/categories", adminToken, handlers.CreateCategoryRequest{Name: "Худи", ParentID: &clothes.ID})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/admin/categories", adminToken, handlers.CreateCategoryRequest{Name: "Одежда"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Категорию нельзя вложить в её же подкатегорию
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/categories/%d", clothes.ID), adminToken,
+		handlers.UpdateCategoryRequest{ParentID: &hoodies.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	hoodie := createMerchViaAPI(t, router, adminToken, "hoodie", 300)
+	setMerchCategory(t, router, adminToken, hoodie.ID, &hoodies.ID)
+	assert.Equal(t, http.StatusConflict, doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/categories/%d", clothes.ID), adminToken, nil).Code)
+	assert.Equal(t, http.StatusConflict, doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/categories/%d", hoodies.ID), adminToken, nil).Code)
+
+	// Перенос на верхний уровень меняет полное название у товаров категории
+	root := uint(0)
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/categories/%d", hoodies.ID), adminToken,
+		handlers.UpdateCategoryRequest{ParentID: &root})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodGet, "/api/categories", adminToken, nil)
+	var tree []handlers.CategoryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tree))
+	names := []string{}
+	for _, category := range tree {
+		names = append(names, category.Path)
+		assert.Empty(t, category.Children)
+	}
+	assert.Equal(t, []string{"Одежда", "Посуда", "Худи"}, names)
+
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/categories/%d", clothes.ID), adminToken, nil).Code)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/categories/%d", clothes.ID), adminToken, nil).Code)
+
+	setMerchCategory(t, router, adminToken, hoodie.ID, nil)
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodDelete, fmt.Sprintf("/api/admin/categories/%d", hoodies.ID), adminToken, nil).Code)
+}
+
+func TestCategories_InfoGroupedByCategory(t *testing.T) {
+	db, router, adminToken := setupRouter(t)
+	clothes := createCategory(t, router, adminToken, "Одежда", nil)
+	hoodies := createCategory(t, router, adminToken, "Худи", &clothes.ID)
+	hoodie := createMerchViaAPI(t, router, adminToken, "hoodie", 300)
+	tshirt := createMerchViaAPI(t, router, adminToken, "t-shirt", 80)
+	createMerchViaAPI(t, router, adminToken, "cup", 20)
+	setMerchCategory(t, router, adminToken, hoodie.ID, &hoodies.ID)
+	setMerchCategory(t, router, adminToken, tshirt.ID, &clothes.ID)
+
+	token := accessToken(t, db, "testuser", models.RoleUser)
+	for _, item := range []string{"hoodie", "t-shirt", "t-shirt", "cup"} {
+		assert.Equal(t, http.StatusOK, doJSON(router, http.MethodGet, "/api/buy/"+item, token, nil).Code)
+	}
+
+	info := getInfo(t, router, token)
+	assert.Empty(t, info.InventoryByCategory)
+	assert.Equal(t, []handlers.InventoryItem{
+		{Type: "cup", Quantity: 1},
+		{Type: "hoodie", Quantity: 1, CategoryID: &hoodies.ID, Category: "Одежда / Худи"},
+		{Type: "t-shirt", Quantity: 2, CategoryID: &clothes.ID, Category: "Одежда"},
+	}, info.Inventory)
+
+	w := doJSON(router, http.MethodGet, "/api/info?groupBy=category", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	groups := make([]string, 0, len(info.InventoryByCategory))
+	quantities := make([]int, 0, len(info.InventoryByCategory))
+	for _, group := range info.InventoryByCategory {
+		groups = append(groups, group.Category)
+		quantities = append(quantities, group.Quantity)
+	}
+	assert.Equal(t, []string{"Одежда", "Одежда / Худи", ""}, groups)
+	assert.Equal(t, []int{2, 1, 1}, quantities)
+
+	assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodGet, "/api/info?groupBy=price", token, nil).Code)
+}
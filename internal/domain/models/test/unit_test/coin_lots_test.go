@@ -0,0 +1,262 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// lotAmounts возвращает остатки партий пользователя в порядке списания.
+func lotAmounts(t *testing.T, db *gorm.DB, user models.User) []int {
+	var remaining []int
+	err := db.Model(&models.CoinLot{}).
+		Where("user_id = ? AND remaining > 0", user.ID).
+		Order("expires_at IS NULL, expires_at, id").
+		Pluck("remaining", &remaining).Error
+	assert.NoError(t, err)
+	return remaining
+}
+
+func TestCoinLots_SpentOldestFirstAndExpire(t *testing.T) {
+	t.Setenv("COIN_EXPIRY_POLICY", services.CoinExpiryPeriod)
+	t.Setenv("COIN_EXPIRY_PERIOD", "720h")
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+	bob := createLedgerUser(t, db, "bob")
+
+	t.Setenv("COIN_EXPIRY_PERIOD", "1440h")
+	carol := createLedgerUser(t, db, "carol")
+	transfer(t, db, carol, alice, 200)
+	assert.Equal(t, []int{1000, 200}, lotAmounts(t, db, alice))
+
+	// Сначала тратятся монеты, которые сгорят раньше; получатель получает их вместе со сроками
+	transfer(t, db, alice, bob, 1100)
+	assert.Equal(t, []int{100}, lotAmounts(t, db, alice))
+	assert.Equal(t, []int{1000, 1000, 100}, lotAmounts(t, db, bob))
+
+	now := time.Now()
+	expired, err := services.ExpireCoinLots(db, now.Add(45*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2000, expired)
+	assertBalances(t, db, alice, 100, 0)
+	assertBalances(t, db, bob, 100, 0)
+	assertBalances(t, db, carol, 800, 0)
+
+	// Повторный запуск в тот же день ничего не списывает
+	expired, err = services.ExpireCoinLots(db, now.Add(45*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	expired, err = services.ExpireCoinLots(db, now.Add(90*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, expired)
+	assertBalances(t, db, carol, 0, 0)
+
+	var entries int64
+	assert.NoError(t, db.Model(&models.LedgerEntry{}).Where("kind = ?", models.LedgerKindExpiry).Count(&entries).Error)
+	assert.Equal(t, int64(4), entries)
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestCoinLots_DebtRepaymentCreatesNoLot(t *testing.T) {
+	t.Setenv("COIN_EXPIRY_POLICY", services.CoinExpiryEndOfYear)
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+	bob := createLedgerUser(t, db, "bob")
+	assert.NoError(t, db.Model(&models.User{}).Where("id = ?", bob.ID).Update("debt", 300).Error)
+
+	transfer(t, db, alice, bob, 500)
+	assertBalances(t, db, bob, 1200, 0)
+	assert.Equal(t, []int{1000, 200}, lotAmounts(t, db, bob))
+
+	var lot models.CoinLot
+	assert.NoError(t, db.Where("user_id = ?", bob.ID).First(&lot).Error)
+	if assert.NotNil(t, lot.ExpiresAt) {
+		assert.Equal(t, time.Date(time.Now().UTC().Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC), lot.ExpiresAt.UTC())
+	}
+}
+
+func TestCoinLots_OpenAndNoExpiryPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	legacy := models.User{Username: "legacy", Coins: 700}
+	assert.NoError(t, db.Create(&legacy).Error)
+
+	assert.NoError(t, services.OpenCoinLots(db))
+	assert.NoError(t, services.OpenCoinLots(db))
+	assert.Equal(t, []int{700}, lotAmounts(t, db, legacy))
+
+	// По умолчанию монеты бессрочные
+	expired, err := services.ExpireCoinLots(db, time.Now().AddDate(10, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+	assertBalances(t, db, legacy, 700, 0)
+}
+
+func TestCoinLots_ExpiryCappedByBalance(t *testing.T) {
+	t.Setenv("COIN_EXPIRY_POLICY", services.CoinExpiryPeriod)
+	t.Setenv("COIN_EXPIRY_PERIOD", "720h")
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+
+	// Партии разошлись с балансом: в них на 500 монет больше, чем у пользователя
+	earlier := time.Now().Add(24 * time.Hour)
+	assert.NoError(t, db.Create(&models.CoinLot{UserID: alice.ID, Amount: 500, Remaining: 500, ExpiresAt: &earlier}).Error)
+
+	// Списывается не больше баланса, и ровно эта сумма снимается с партий в порядке сгорания
+	expired, err := services.ExpireCoinLots(db, time.Now().Add(45*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, expired)
+	assertBalances(t, db, alice, 0, 0)
+	assert.Equal(t, []int{500}, lotAmounts(t, db, alice))
+
+	var writtenOff int
+	assert.NoError(t, db.Model(&models.CoinLot{}).Where("user_id = ?", alice.ID).
+		Select("SUM(amount - remaining)").Scan(&writtenOff).Error)
+	var posted int
+	assert.NoError(t, db.Model(&models.LedgerPosting{}).Where("account = ?", models.LedgerAccountExpired).
+		Select("SUM(amount)").Scan(&posted).Error)
+	assert.Equal(t, 1000, writtenOff)
+	assert.Equal(t, 1000, posted)
+}
+
+func TestGetInfo_ExpiringCoins(t *testing.T) {
+	t.Setenv("COIN_EXPIRY_POLICY", services.CoinExpiryPeriod)
+	t.Setenv("COIN_EXPIRY_PERIOD", "240h")
+	db := setupTestDB(t)
+	user := createLedgerUser(t, db, "testuser")
+	t.Setenv("COIN_EXPIRY_PERIOD", "2400h")
+	other := createLedgerUser(t, db, "other")
+	transfer(t, db, other, user, 50)
+	handler := handlers.NewInfoHandler(db)
+
+	w := performGetInfo(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.ExpiringCoins, 1) {
+		assert.Equal(t, 1000, resp.ExpiringCoins[0].Amount)
+		assert.WithinDuration(t, time.Now().Add(240*time.Hour), resp.ExpiringCoins[0].ExpiresAt, time.Minute)
+	}
+
+	t.Setenv("COIN_EXPIRY_NOTICE", "24h")
+	w = performGetInfo(handler)
+	var later handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &later))
+	assert.Empty(t, later.ExpiringCoins)
+}
+
+func TestCoinLots_HeldTransfersKeepExpiry(t *testing.T) {
+	t.Setenv("FRAUD_HOLD", "true")
+	t.Setenv("FRAUD_NEW_ACCOUNT_SENDERS", "1")
+	t.Setenv("COIN_EXPIRY_POLICY", services.CoinExpiryPeriod)
+	t.Setenv("COIN_EXPIRY_PERIOD", "240h")
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+	t.Setenv("COIN_EXPIRY_PERIOD", "2400h")
+	bob := createLedgerUser(t, db, "bob")
+	t.Setenv("COIN_EXPIRY_PERIOD", "1440h")
+	carol := createLedgerUser(t, db, "carol")
+
+	review := func(transaction *models.Transaction, approve bool) {
+		var flag models.FraudFlag
+		assert.NoError(t, db.Where("transaction_id = ?", transaction.ID).First(&flag).Error)
+		var err error
+		if approve {
+			_, err = services.ApproveFraudFlag(db, "admin", flag.ID, "")
+		} else {
+			_, err = services.RejectFraudFlag(db, "admin", flag.ID, "")
+		}
+		assert.NoError(t, err)
+	}
+
+	// Отклонённый перевод возвращает отправителю монеты с прежним сроком, а одобренный
+	// передаёт получателю монеты со сроком отправителя
+	rejected := transfer(t, db, alice, carol, 1000)
+	assert.Equal(t, models.TransactionHeld, rejected.Status)
+	review(rejected, false)
+	assertBalances(t, db, alice, 1000, 0)
+	review(transfer(t, db, bob, carol, 300), true)
+	assertBalances(t, db, carol, 1300, 0)
+	pending := transfer(t, db, bob, carol, 200)
+
+	now := time.Now()
+	expired, err := services.ExpireCoinLots(db, now.Add(20*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, expired)
+	assertBalances(t, db, alice, 0, 0)
+
+	expired, err = services.ExpireCoinLots(db, now.Add(70*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, expired)
+	assert.Equal(t, []int{300}, lotAmounts(t, db, carol))
+
+	// Удерживаемые монеты сгорают в срок, и одобрение перевода их уже не зачисляет
+	expired, err = services.ExpireCoinLots(db, now.Add(120*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 500+200+300, expired)
+	review(pending, true)
+	assertBalances(t, db, bob, 0, 0)
+	assertBalances(t, db, carol, 0, 0)
+
+	var held int
+	assert.NoError(t, db.Model(&models.LedgerPosting{}).Select("COALESCE(SUM(amount), 0)").
+		Where("account = ?", models.LedgerAccountHeld).Scan(&held).Error)
+	assert.Equal(t, 0, held)
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestCoinLots_EscrowKeepsExpiry(t *testing.T) {
+	t.Setenv("COIN_EXPIRY_POLICY", services.CoinExpiryPeriod)
+	t.Setenv("COIN_EXPIRY_PERIOD", "240h")
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+	t.Setenv("COIN_EXPIRY_PERIOD", "1440h")
+	bob := createLedgerUser(t, db, "bob")
+
+	escrow := func(amount int) *models.Transaction {
+		var transaction *models.Transaction
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			transaction, err = services.CreateEscrow(tx, alice.ID, bob.ID, amount, "", 0)
+			return err
+		}))
+		return transaction
+	}
+
+	// Возвращённые и подтверждённые монеты сохраняют срок действия отправителя
+	released := escrow(400)
+	_, err := services.ReleaseEscrow(db, bob.ID, released.ID)
+	assert.NoError(t, err)
+	confirmed := escrow(300)
+	_, err = services.ConfirmEscrow(db, alice.ID, confirmed.ID)
+	assert.NoError(t, err)
+	escrow(200)
+
+	now := time.Now()
+	count, err := services.ReleaseExpiredEscrows(db, now.Add(services.EscrowTimeout()+time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assertBalances(t, db, alice, 700, 0)
+	assertBalances(t, db, bob, 1300, 0)
+
+	expired, err := services.ExpireCoinLots(db, now.Add(20*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 700+300, expired)
+	assertBalances(t, db, alice, 0, 0)
+	assert.Equal(t, []int{1000}, lotAmounts(t, db, bob))
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createCoinRequest(t *testing.T, router *gin.Engine, token, toUser string, amount int) handlers.CoinRequestResponse {
+	w := doJSON(router, http.MethodPost, "/api/coin-requests", token,
+		handlers.CreateCoinRequestRequest{ToUser: toUser, Amount: amount, Note: "  за пиццу  "})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp handlers.CoinRequestResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func listCoinRequests(t *testing.T, router *gin.Engine, token, query string) []handlers.CoinRequestResponse {
+	w := doJSON(router, http.MethodGet, "/api/coin-requests"+query, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp []handlers.CoinRequestResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestCoinRequests_AcceptTransfersOnce(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	requesterToken := accessToken(t, db, "requester", models.RoleUser)
+	payerToken := accessToken(t, db, "payer", models.RoleUser)
+
+	request := createCoinRequest(t, router, requesterToken, "payer", 300)
+	assert.Equal(t, "requester", request.FromUser)
+	assert.Equal(t, "payer", request.ToUser)
+	assert.Equal(t, "за пиццу", request.Note)
+	assert.Equal(t, models.CoinRequestPending, request.Status)
+
+	incoming := listCoinRequests(t, router, payerToken, "?box=incoming")
+	assert.Len(t, incoming, 1)
+	assert.Empty(t, listCoinRequests(t, router, payerToken, "?box=outgoing"))
+	assert.Len(t, listCoinRequests(t, router, requesterToken, "?box=outgoing&status=pending"), 1)
+
+	// Запрос может оплатить только тот, у кого просят монеты
+	path := fmt.Sprintf("/api/coin-requests/%d/accept", request.ID)
+	w := doJSON(router, http.MethodPost, path, requesterToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodPost, path, payerToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var accepted handlers.CoinRequestResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	assert.Equal(t, models.CoinRequestAccepted, accepted.Status)
+	assert.NotNil(t, accepted.TransactionID)
+
+	w = doJSON(router, http.MethodPost, path, payerToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var requester, payer models.User
+	assert.NoError(t, db.Where("username = ?", "requester").First(&requester).Error)
+	assert.NoError(t, db.Where("username = ?", "payer").First(&payer).Error)
+	assert.Equal(t, services.InitialCoins+300, requester.Coins)
+	assert.Equal(t, services.InitialCoins-300, payer.Coins)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestCoinRequests_DeclineCancelAndFunds(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	requesterToken := accessToken(t, db, "requester", models.RoleUser)
+	payerToken := accessToken(t, db, "payer", models.RoleUser)
+
+	w := doJSON(router, http.MethodPost, "/api/coin-requests", requesterToken,
+		handlers.CreateCoinRequestRequest{ToUser: "requester", Amount: 10})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/coin-requests", requesterToken,
+		handlers.CreateCoinRequestRequest{ToUser: "nobody", Amount: 10})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Отказ в оплате при нехватке монет оставляет запрос открытым
+	tooMuch := createCoinRequest(t, router, requesterToken, "payer", services.InitialCoins+1)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/coin-requests/%d/accept", tooMuch.ID), payerToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/coin-requests/%d/decline", tooMuch.ID), payerToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	cancelled := createCoinRequest(t, router, requesterToken, "payer", 50)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/coin-requests/%d/cancel", cancelled.ID), payerToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/coin-requests/%d/cancel", cancelled.ID), requesterToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodPost, fmt.Sprintf("/api/coin-requests/%d/accept", cancelled.ID), payerToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.Len(t, listCoinRequests(t, router, payerToken, "?status=declined"), 1)
+	assert.Len(t, listCoinRequests(t, router, payerToken, "?status=cancelled"), 1)
+
+	var payer models.User
+	assert.NoError(t, db.Where("username = ?", "payer").First(&payer).Error)
+	assert.Equal(t, services.InitialCoins, payer.Coins)
+}
+
+func TestCoinRequests_Expiry(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	requesterToken := accessToken(t, db, "requester", models.RoleUser)
+	payerToken := accessToken(t, db, "payer", models.RoleUser)
+
+	request := createCoinRequest(t, router, requesterToken, "payer", 100)
+	assert.WithinDuration(t, time.Now().Add(services.CoinRequestTTL()), request.ExpiresAt, time.Minute)
+	assert.NoError(t, db.Model(&models.CoinRequest{}).Where("id = ?", request.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/coin-requests/%d/accept", request.ID), payerToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "истёк")
+
+	expired := listCoinRequests(t, router, payerToken, "")
+	assert.Len(t, expired, 1)
+	assert.Equal(t, models.CoinRequestExpired, expired[0].Status)
+
+	var payer models.User
+	assert.NoError(t, db.Where("username = ?", "payer").First(&payer).Error)
+	assert.Equal(t, services.InitialCoins, payer.Coins)
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createEscrow(t *testing.T, router *gin.Engine, token string, req handlers.CreateEscrowRequest) handlers.EscrowResponse {
+	w := doJSON(router, http.MethodPost, "/api/escrow", token, req)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var escrow handlers.EscrowResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &escrow))
+	return escrow
+}
+
+func getInfo(t *testing.T, router *gin.Engine, token string) handlers.InfoResponse {
+	w := doJSON(router, http.MethodGet, "/api/info", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var info handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	return info
+}
+
+func assertReconciled(t *testing.T, db *gorm.DB) {
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestEscrow_ConfirmCreditsRecipient(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	alice := accessToken(t, db, "alice", models.RoleUser)
+	bob := accessToken(t, db, "bob", models.RoleUser)
+
+	escrow := createEscrow(t, router, alice, handlers.CreateEscrowRequest{ToUser: "bob", Amount: 300, Message: "за доставку"})
+	assert.Equal(t, models.TransactionPending, escrow.Status)
+	assert.Equal(t, "alice", escrow.FromUser)
+	assert.WithinDuration(t, time.Now().Add(services.EscrowTimeout()), escrow.ExpiresAt, time.Minute)
+	assertBalances(t, db, userByName(t, db, "alice"), 700, 0)
+	assertBalances(t, db, userByName(t, db, "bob"), 1000, 0)
+
+	info := getInfo(t, router, alice)
+	assert.Equal(t, 700, info.Available)
+	assert.Equal(t, 300, info.Held)
+	assert.Equal(t, 300, getInfo(t, router, bob).PendingIncoming)
+
+	// Подтвердить перевод может только отправитель
+	path := fmt.Sprintf("/api/escrow/%d/confirm", escrow.ID)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodPost, path, bob, nil).Code)
+
+	w := doJSON(router, http.MethodPost, path, alice, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var confirmed handlers.EscrowResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	assert.Equal(t, models.TransactionSettled, confirmed.Status)
+	assertBalances(t, db, userByName(t, db, "bob"), 1300, 0)
+	assert.Equal(t, 0, getInfo(t, router, alice).Held)
+
+	assert.Equal(t, http.StatusConflict, doJSON(router, http.MethodPost, path, alice, nil).Code)
+	assertReconciled(t, db)
+}
+
+func TestEscrow_ReleaseByRecipient(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	alice := accessToken(t, db, "alice", models.RoleUser)
+	bob := accessToken(t, db, "bob", models.RoleUser)
+
+	escrow := createEscrow(t, router, alice, handlers.CreateEscrowRequest{ToUser: "bob", Amount: 200})
+
+	w := doJSON(router, http.MethodGet, "/api/escrow?status=pending", bob, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var incoming []handlers.EscrowResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &incoming))
+	if assert.Len(t, incoming, 1) {
+		assert.Equal(t, escrow.ID, incoming[0].ID)
+	}
+
+	path := fmt.Sprintf("/api/escrow/%d/release", escrow.ID)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodPost, path, alice, nil).Code)
+	w = doJSON(router, http.MethodPost, path, bob, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assertBalances(t, db, userByName(t, db, "alice"), 1000, 0)
+	assertBalances(t, db, userByName(t, db, "bob"), 1000, 0)
+
+	confirm := fmt.Sprintf("/api/escrow/%d/confirm", escrow.ID)
+	assert.Equal(t, http.StatusConflict, doJSON(router, http.MethodPost, confirm, alice, nil).Code)
+	assertReconciled(t, db)
+}
+
+func TestEscrow_ExpiredReleasedToSender(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	alice := accessToken(t, db, "alice", models.RoleUser)
+	accessToken(t, db, "bob", models.RoleUser)
+
+	w := doJSON(router, http.MethodPost, "/api/escrow", alice, handlers.CreateEscrowRequest{ToUser: "bob", Amount: 100, Timeout: "9999h"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	escrow := createEscrow(t, router, alice, handlers.CreateEscrowRequest{ToUser: "bob", Amount: 100, Timeout: "1h"})
+	createEscrow(t, router, alice, handlers.CreateEscrowRequest{ToUser: "bob", Amount: 50})
+
+	released, err := services.ReleaseExpiredEscrows(db, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, released)
+
+	released, err = services.ReleaseExpiredEscrows(db, time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, released)
+	assertBalances(t, db, userByName(t, db, "alice"), 950, 0)
+
+	var stored models.Transaction
+	assert.NoError(t, db.First(&stored, escrow.ID).Error)
+	assert.Equal(t, models.TransactionReleased, stored.Status)
+
+	info := getInfo(t, router, alice)
+	assert.Equal(t, 50, info.Held)
+	assertReconciled(t, db)
+}
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func listFraudFlags(t *testing.T, router *gin.Engine, token, query string) []handlers.FraudFlagResponse {
+	w := doJSON(router, http.MethodGet, "/api/admin/fraud-flags"+query, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var flags []handlers.FraudFlagResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &flags))
+	return flags
+}
+
+func userByName(t *testing.T, db *gorm.DB, username string) models.User {
+	var user models.User
+	assert.NoError(t, db.Where("username = ?", username).First(&user).Error)
+	return user
+}
+
+func TestFraud_NewAccountFunnelHeldUntilApproved(t *testing.T) {
+	t.Setenv("FRAUD_HOLD", "true")
+	db, router, adminToken := setupRouter(t)
+	accessToken(t, db, "collector", models.RoleUser)
+
+	for i := 1; i <= 2; i++ {
+		code, _ := sendCoin(router, accessToken(t, db, fmt.Sprintf("farm%d", i), models.RoleUser), "collector", 100)
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Empty(t, listFraudFlags(t, router, adminToken, ""))
+
+	// Третий новый аккаунт, переводящий монеты тому же получателю, задерживается
+	w := doJSON(router, http.MethodPost, "/api/sendCoin", accessToken(t, db, "farm3", models.RoleUser),
+		handlers.SendCoinRequest{ToUser: "collector", Amount: 100})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var held handlers.TransferHeldResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &held))
+	assert.Equal(t, models.TransactionHeld, held.Status)
+	assertBalances(t, db, userByName(t, db, "farm3"), 900, 0)
+	assertBalances(t, db, userByName(t, db, "collector"), 1200, 0)
+
+	flags := listFraudFlags(t, router, adminToken, "")
+	if assert.Len(t, flags, 1) {
+		assert.Equal(t, held.TransactionID, flags[0].TransactionID)
+		assert.Equal(t, []string{services.FraudRuleNewAccountFunnel}, flags[0].Rules)
+		assert.Equal(t, "farm3", flags[0].FromUser)
+		assert.Equal(t, models.TransactionHeld, flags[0].TransactionStatus)
+	}
+
+	path := fmt.Sprintf("/api/admin/fraud-flags/%d/approve", flags[0].ID)
+	w = doJSON(router, http.MethodPost, path, adminToken, handlers.ReviewFraudFlagRequest{Comment: "сбор на подарок"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var approved handlers.FraudFlagResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approved))
+	assert.Equal(t, models.FraudFlagApproved, approved.Status)
+	assert.Equal(t, models.TransactionSettled, approved.TransactionStatus)
+	assert.Equal(t, "admin", approved.ReviewedBy)
+	assertBalances(t, db, userByName(t, db, "collector"), 1300, 0)
+
+	w = doJSON(router, http.MethodPost, path, adminToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, listFraudFlags(t, router, adminToken, ""))
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestFraud_CircularTransferRejected(t *testing.T) {
+	t.Setenv("FRAUD_HOLD", "true")
+	db, router, adminToken := setupRouter(t)
+	alice := accessToken(t, db, "alice", models.RoleUser)
+	bob := accessToken(t, db, "bob", models.RoleUser)
+	carol := accessToken(t, db, "carol", models.RoleUser)
+
+	// Прямой возврат монет кругом не считается
+	code, _ := sendCoin(router, alice, "bob", 100)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = sendCoin(router, bob, "alice", 50)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = sendCoin(router, bob, "carol", 100)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = sendCoin(router, carol, "alice", 100)
+	assert.Equal(t, http.StatusAccepted, code)
+
+	flags := listFraudFlags(t, router, adminToken, "")
+	if !assert.Len(t, flags, 1) {
+		return
+	}
+	assert.Equal(t, []string{services.FraudRuleCircular}, flags[0].Rules)
+
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/fraud-flags/%d/reject", flags[0].ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assertBalances(t, db, userByName(t, db, "carol"), 1100, 0)
+	assertBalances(t, db, userByName(t, db, "alice"), 950, 0)
+	assert.Len(t, listFraudFlags(t, router, adminToken, "?status=rejected"), 1)
+
+	// Отклонённый перевод получателю не зачислялся, поэтому отменить его нельзя
+	_, err := services.ReverseTransfer(db, "admin", flags[0].TransactionID, services.ReversalPolicyPartial, "")
+	assert.ErrorIs(t, err, services.ErrTransferNotSettled)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestFraud_FlagOnlyWithoutHold(t *testing.T) {
+	db, router, adminToken := setupRouter(t)
+	alice := accessToken(t, db, "alice", models.RoleUser)
+	bob := accessToken(t, db, "bob", models.RoleUser)
+	carol := accessToken(t, db, "carol", models.RoleUser)
+
+	sendCoin(router, alice, "bob", 100)
+	sendCoin(router, bob, "carol", 100)
+	code, _ := sendCoin(router, carol, "alice", 100)
+	assert.Equal(t, http.StatusOK, code)
+	assertBalances(t, db, userByName(t, db, "alice"), 1000, 0)
+
+	flags := listFraudFlags(t, router, adminToken, "")
+	if assert.Len(t, flags, 1) {
+		assert.Equal(t, models.TransactionSettled, flags[0].TransactionStatus)
+	}
+}
+
+func TestFraud_RejectedFunnelNotCounted(t *testing.T) {
+	t.Setenv("FRAUD_HOLD", "true")
+	t.Setenv("FRAUD_NEW_ACCOUNT_SENDERS", "2")
+	db, router, adminToken := setupRouter(t)
+	accessToken(t, db, "collector", models.RoleUser)
+	farm1 := accessToken(t, db, "farm1", models.RoleUser)
+
+	code, _ := sendCoin(router, farm1, "collector", 100)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = sendCoin(router, accessToken(t, db, "farm2", models.RoleUser), "collector", 100)
+	assert.Equal(t, http.StatusAccepted, code)
+	flags := listFraudFlags(t, router, adminToken, "")
+	if !assert.Len(t, flags, 1) {
+		return
+	}
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/fraud-flags/%d/reject", flags[0].ID), adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Отклонённый перевод не считается при проверке следующих переводов тому же получателю
+	code, _ = sendCoin(router, farm1, "collector", 100)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, listFraudFlags(t, router, adminToken, ""))
+	assertBalances(t, db, userByName(t, db, "collector"), 1200, 0)
+}
@@ -0,0 +1,160 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupHistory создаёт пользователя alice с переводами bob и carol и возвращает функцию запроса истории.
+// Переводы (от старых к новым): alice→bob 100, bob→alice 50, alice→carol 200, carol→alice 10, alice→bob 300.
+func setupHistory(t *testing.T) (*handlers.HistoryHandler, func(query string) handlers.HistoryResponse) {
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+	bob := createLedgerUser(t, db, "bob")
+	carol := createLedgerUser(t, db, "carol")
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, tr := range []struct {
+		from, to models.User
+		amount   int
+	}{
+		{alice, bob, 100},
+		{bob, alice, 50},
+		{alice, carol, 200},
+		{carol, alice, 10},
+		{alice, bob, 300},
+	} {
+		transaction := transfer(t, db, tr.from, tr.to, tr.amount)
+		assert.NoError(t, db.Model(transaction).Update("created_at", base.AddDate(0, 0, i)).Error)
+	}
+
+	handler := handlers.NewHistoryHandler(db)
+	list := func(query string) handlers.HistoryResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/history?"+query, nil)
+		c.Set("username", "alice")
+		handler.GetHistory(c)
+		assert.Equal(t, http.StatusOK, w.Code, query)
+
+		var resp handlers.HistoryResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	return handler, list
+}
+
+func historyAmounts(resp handlers.HistoryResponse) []int {
+	amounts := make([]int, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		amounts = append(amounts, item.Amount)
+	}
+	return amounts
+}
+
+func TestHistory_CursorPagination(t *testing.T) {
+	_, list := setupHistory(t)
+
+	var amounts []int
+	cursor := ""
+	pages := 0
+	for {
+		resp := list("limit=2&cursor=" + cursor)
+		amounts = append(amounts, historyAmounts(resp)...)
+		pages++
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []int{300, 10, 200, 50, 100}, amounts)
+
+	first := list("limit=1").Items[0]
+	assert.NotZero(t, first.ID)
+	assert.Equal(t, "bob", first.ToUser)
+	assert.Equal(t, "sent", first.Direction)
+	assert.Equal(t, time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC), first.CreatedAt.UTC())
+}
+
+func TestHistory_Filters(t *testing.T) {
+	_, list := setupHistory(t)
+
+	assert.Equal(t, []int{10, 50}, historyAmounts(list("direction=received")))
+	assert.Equal(t, []int{300, 50, 100}, historyAmounts(list("counterparty=bob")))
+	assert.Equal(t, []int{300}, historyAmounts(list("counterparty=bob&direction=sent&minAmount=150")))
+	assert.Equal(t, []int{50, 100}, historyAmounts(list("maxAmount=100&counterparty=bob")))
+	assert.Equal(t, []int{10, 200, 50}, historyAmounts(list("from=2025-03-02&to=2025-03-04")))
+	assert.Equal(t, []int{200, 50}, historyAmounts(list("from=2025-03-02T00:00:00Z&to=2025-03-03T12:00:00Z")))
+}
+
+func TestHistory_InvalidParams(t *testing.T) {
+	handler, _ := setupHistory(t)
+
+	for _, query := range []string{"limit=0", "limit=500", "cursor=###", "from=yesterday", "direction=both", "minAmount=abc"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/history?"+query, nil)
+		c.Set("username", "alice")
+		handler.GetHistory(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestHistory_MessageSearch(t *testing.T) {
+	db := setupTestDB(t)
+	alice := createLedgerUser(t, db, "alice")
+	bob := createLedgerUser(t, db, "bob")
+	for _, tr := range []struct {
+		from, to models.User
+		amount   int
+		message  string
+	}{
+		{alice, bob, 100, "Долг за пиццу"},
+		{bob, alice, 50, ""},
+		{alice, bob, 30, "Pizza 100%"},
+		{bob, alice, 20, "Спасибо за помощь"},
+	} {
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			_, err := services.Transfer(tx, tr.from.ID, tr.to.ID, tr.amount, tr.message)
+			return err
+		}))
+	}
+
+	handler := handlers.NewHistoryHandler(db)
+	list := func(query string) handlers.HistoryResponse {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/history?"+query, nil)
+		c.Set("username", "alice")
+		handler.GetHistory(c)
+		assert.Equal(t, http.StatusOK, w.Code, query)
+
+		var resp handlers.HistoryResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := list("")
+	assert.Equal(t, "Спасибо за помощь", resp.Items[0].Message)
+	assert.Equal(t, "", resp.Items[2].Message)
+
+	assert.Equal(t, []int{20, 100}, historyAmounts(list("q="+url.QueryEscape("за "))))
+	assert.Equal(t, []int{30}, historyAmounts(list("q=PIZZA")))
+	// Спецсимволы шаблона LIKE ищутся буквально
+	assert.Equal(t, []int{30}, historyAmounts(list("q="+url.QueryEscape("%"))))
+	assert.Equal(t, []int{20, 30, 100}, historyAmounts(list("hasMessage=true")))
+	assert.Equal(t, []int{50}, historyAmounts(list("hasMessage=false")))
+	assert.Equal(t, []int{30, 100}, historyAmounts(list("hasMessage=true&direction=sent")))
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupIdempotencyRouter возвращает роутер с middleware идемпотентности и счётчик вызовов обработчика.
+func setupIdempotencyRouter(t *testing.T, ttl time.Duration) (*gin.Engine, *int) {
+	db := setupTestDB(t)
+
+	calls := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "testuser")
+		c.Next()
+	})
+	router.POST("/sendCoin", middlewares.IdempotencyMiddleware(db, ttl), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+	return router, &calls
+}
+
+func performIdempotentRequest(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/sendCoin", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middlewares.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t, time.Hour)
+
+	first := performIdempotentRequest(router, "key-1", `{"toUser":"receiver","amount":10}`)
+	second := performIdempotentRequest(router, "key-1", `{"toUser":"receiver","amount":10}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotency_DifferentBodyConflict(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t, time.Hour)
+
+	performIdempotentRequest(router, "key-1", `{"toUser":"receiver","amount":10}`)
+	w := performIdempotentRequest(router, "key-1", `{"toUser":"receiver","amount":20}`)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t, time.Hour)
+
+	performIdempotentRequest(router, "", `{"toUser":"receiver","amount":10}`)
+	performIdempotentRequest(router, "", `{"toUser":"receiver","amount":10}`)
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_ExpiredKey(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t, -time.Second)
+
+	performIdempotentRequest(router, "key-1", `{"toUser":"receiver","amount":10}`)
+	w := performIdempotentRequest(router, "key-1", `{"toUser":"receiver","amount":10}`)
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+}
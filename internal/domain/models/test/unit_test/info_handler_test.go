@@ -2,6 +2,7 @@ package test
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,12 +13,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func setupTestValues(t *testing.T) (*gorm.DB, *handlers.InfoHandler, *httptest.ResponseRecorder, *gin.Context) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&models.User{}, &models.Purchase{}, &models.Merch{}, &models.Transaction{})
+	err = db.AutoMigrate(models.All()...)
 	assert.NoError(t, err)
 
 	handler := &handlers.InfoHandler{Db: db}
@@ -131,3 +133,89 @@ func TestGetInfo_HappyPath(t *testing.T) {
 		assert.Equal(t, expectedReceived[rec.FromUser], rec.Amount)
 	}
 }
+
+// countQueries подсчитывает SQL-запросы на чтение, выполненные через db.
+func countQueries(t testing.TB, db *gorm.DB) *int {
+	count := 0
+	inc := func(*gorm.DB) { count++ }
+	assert.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count_queries", inc))
+	assert.NoError(t, db.Callback().Row().After("gorm:row").Register("test:count_rows", inc))
+	return &count
+}
+
+// addInfoHistory создаёт пользователю n переводов в обе стороны и n покупок.
+func addInfoHistory(t testing.TB, db *gorm.DB, user, other models.User, merch models.Merch, n int) {
+	for i := 0; i < n; i++ {
+		assert.NoError(t, db.Create(&models.Transaction{FromUserID: user.ID, ToUserID: other.ID, Amount: 1}).Error)
+		assert.NoError(t, db.Create(&models.Transaction{FromUserID: other.ID, ToUserID: user.ID, Amount: 1}).Error)
+		assert.NoError(t, db.Create(&models.Purchase{UserID: user.ID, MerchID: merch.ID, Price: merch.Price}).Error)
+	}
+}
+
+func setupInfoLoad(t testing.TB, db *gorm.DB) (models.User, models.User, models.Merch) {
+	user := models.User{Username: "testuser"}
+	other := models.User{Username: "other"}
+	merch := models.Merch{Name: "cup", Price: 20}
+	assert.NoError(t, db.Create(&user).Error)
+	assert.NoError(t, db.Create(&other).Error)
+	assert.NoError(t, db.Create(&merch).Error)
+	return user, other, merch
+}
+
+func performGetInfo(handler *handlers.InfoHandler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "testuser")
+	c.Request = httptest.NewRequest(http.MethodGet, "/info", nil)
+	handler.GetInfo(c)
+	return w
+}
+
+func TestGetInfo_QueryCountDoesNotGrow(t *testing.T) {
+	db, handler, _, _ := setupTestValues(t)
+	user, other, merch := setupInfoLoad(t, db)
+	queries := countQueries(t, db)
+
+	addInfoHistory(t, db, user, other, merch, 5)
+	*queries = 0
+	assert.Equal(t, http.StatusOK, performGetInfo(handler).Code)
+	small := *queries
+
+	addInfoHistory(t, db, user, other, merch, 200)
+	*queries = 0
+	w := performGetInfo(handler)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, small, *queries)
+
+	var resp handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []handlers.InventoryItem{{Type: "cup", Quantity: 205}}, resp.Inventory)
+	assert.Len(t, resp.CoinHistory.Sent, 205)
+	assert.Len(t, resp.CoinHistory.Received, 205)
+	assert.Equal(t, "other", resp.CoinHistory.Sent[0].ToUser)
+	assert.Equal(t, "other", resp.CoinHistory.Received[0].FromUser)
+}
+
+// BenchmarkGetInfo сообщает число запросов к БД на один вызов /info (queries/op)
+// для разного объёма истории: оно не должно зависеть от количества переводов и покупок.
+func BenchmarkGetInfo(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("history=%d", n), func(b *testing.B) {
+			db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			assert.NoError(b, err)
+			assert.NoError(b, db.AutoMigrate(models.All()...))
+			user, other, merch := setupInfoLoad(b, db)
+			addInfoHistory(b, db, user, other, merch, n)
+
+			handler := handlers.NewInfoHandler(db)
+			queries := countQueries(b, db)
+			*queries = 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				performGetInfo(handler)
+			}
+			b.ReportMetric(float64(*queries)/float64(b.N), "queries/op")
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createLedgerUser(t *testing.T, db *gorm.DB, username string) models.User {
+	user := models.User{Username: username}
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return services.CreateUser(tx, &user)
+	}))
+	return user
+}
+
+func TestPostEntry_Unbalanced(t *testing.T) {
+	db := setupTestDB(t)
+
+	err := services.PostEntry(db, &models.LedgerEntry{
+		Kind: models.LedgerKindGrant,
+		Postings: []models.LedgerPosting{
+			services.SystemPosting(models.LedgerAccountIssuance, -100),
+			services.UserPosting(1, 50),
+		},
+	})
+	assert.ErrorIs(t, err, services.ErrUnbalancedEntry)
+}
+
+func TestLedger_AllMovementsRecorded(t *testing.T) {
+	db := setupTestDB(t)
+
+	sender := createLedgerUser(t, db, "sender")
+	receiver := createLedgerUser(t, db, "receiver")
+	merch := models.Merch{Name: "cup", Price: 20}
+	assert.NoError(t, db.Create(&merch).Error)
+
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		_, err := services.Transfer(tx, sender.ID, receiver.ID, 300, "")
+		return err
+	}))
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		_, err := services.BuyMerch(tx, receiver.ID, &merch, nil)
+		return err
+	}))
+
+	senderBalance, err := services.LedgerBalance(db, sender.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 700, senderBalance)
+
+	receiverBalance, err := services.LedgerBalance(db, receiver.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1280, receiverBalance)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestReconcile_ReportsMismatch(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createLedgerUser(t, db, "testuser")
+	createLedgerUser(t, db, "other")
+
+	// Меняем кэшированный баланс в обход журнала
+	assert.NoError(t, db.Model(&user).Update("coins", 1500).Error)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Equal(t, []services.BalanceMismatch{
+		{UserID: user.ID, Username: "testuser", Coins: 1500, LedgerBalance: 1000},
+	}, mismatches)
+}
+
+func TestOpenLedgerBalances(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Пользователь, созданный до появления журнала
+	legacy := models.User{Username: "legacy", Coins: 420}
+	assert.NoError(t, db.Create(&legacy).Error)
+	createLedgerUser(t, db, "fresh")
+
+	assert.NoError(t, services.OpenLedgerBalances(db))
+	// Повторный запуск не должен дублировать остатки
+	assert.NoError(t, services.OpenLedgerBalances(db))
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
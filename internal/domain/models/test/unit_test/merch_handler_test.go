@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/api/middlewares"
 	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -137,3 +139,87 @@ func TestBuyItem_Success(t *testing.T) {
 	err = db.Where("user_id = ? AND merch_id = ?", user.ID, merch.ID).First(&purchase).Error
 	assert.NoError(t, err)
 }
+
+func TestBuyItem_OutOfStock(t *testing.T) {
+	db := setupTestDB(t)
+	stock := 1
+	merch := models.Merch{Name: "pink-hoody", Price: 20, Stock: &stock}
+	assert.NoError(t, db.Create(&merch).Error)
+	user := models.User{Username: "testuser", Coins: 100}
+	assert.NoError(t, db.Create(&user).Error)
+
+	handler := handlers.NewMerchHandler(db)
+	w := performBuyItemRequest(handler, "pink-hoody", "testuser")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Merch
+	assert.NoError(t, db.First(&stored, merch.ID).Error)
+	assert.Equal(t, 0, *stored.Stock)
+
+	// Последняя единица продана: повторная покупка отклоняется, монеты не списываются
+	w = performBuyItemRequest(handler, "pink-hoody", "testuser")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var resp map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Товар закончился", resp["error"])
+
+	var updatedUser models.User
+	assert.NoError(t, db.First(&updatedUser, user.ID).Error)
+	assert.Equal(t, 80, updatedUser.Coins)
+}
+
+func TestBuyItem_Gift(t *testing.T) {
+	db := setupTestDB(t)
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(middlewares.JWTAuthMiddleware(db))
+	api.POST("/buy/:item", handlers.NewMerchHandler(db).BuyItem)
+	api.GET("/info", handlers.NewInfoHandler(db).GetInfo)
+
+	buyerToken := accessToken(t, db, "buyer", models.RoleUser)
+	recipientToken := accessToken(t, db, "birthday", models.RoleUser)
+	merch := models.Merch{Name: "cup", Price: 20}
+	assert.NoError(t, db.Create(&merch).Error)
+
+	w := doJSON(router, http.MethodPost, "/api/buy/cup", buyerToken, handlers.BuyItemRequest{Recipient: "buyer"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/buy/cup", buyerToken, handlers.BuyItemRequest{Recipient: "nobody"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(router, http.MethodPost, "/api/buy/cup", buyerToken, handlers.BuyItemRequest{Recipient: "birthday", Note: "С днём\nрождения!"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var buyerInfo handlers.InfoResponse
+	w = doJSON(router, http.MethodGet, "/api/info", buyerToken, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &buyerInfo))
+	assert.Equal(t, services.InitialCoins-20, buyerInfo.Coins)
+	assert.Empty(t, buyerInfo.Inventory)
+	assert.Empty(t, buyerInfo.Gifts.Received)
+	if assert.Len(t, buyerInfo.Gifts.Given, 1) {
+		assert.Equal(t, "cup", buyerInfo.Gifts.Given[0].Item)
+		assert.Equal(t, "birthday", buyerInfo.Gifts.Given[0].ToUser)
+		assert.Empty(t, buyerInfo.Gifts.Given[0].FromUser)
+		assert.Equal(t, "С днём рождения!", buyerInfo.Gifts.Given[0].Note)
+	}
+
+	var recipientInfo handlers.InfoResponse
+	w = doJSON(router, http.MethodGet, "/api/info", recipientToken, nil)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &recipientInfo))
+	assert.Equal(t, services.InitialCoins, recipientInfo.Coins)
+	assert.Equal(t, []handlers.InventoryItem{{Type: "cup", Quantity: 1}}, recipientInfo.Inventory)
+	assert.Empty(t, recipientInfo.Gifts.Given)
+	if assert.Len(t, recipientInfo.Gifts.Received, 1) {
+		assert.Equal(t, "buyer", recipientInfo.Gifts.Received[0].FromUser)
+		assert.Empty(t, recipientInfo.Gifts.Received[0].ToUser)
+	}
+
+	// Подарок оплачен другим пользователем, поэтому получатель не может вернуть его за монеты
+	var gift models.Purchase
+	assert.NoError(t, db.Where("gift_from_id IS NOT NULL").First(&gift).Error)
+	_, err := services.RequestReturn(db, gift.UserID, gift.ID, "")
+	assert.ErrorIs(t, err, services.ErrGiftNotReturnable)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
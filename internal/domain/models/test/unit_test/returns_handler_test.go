@@ -0,0 +1,155 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type returnsFixture struct {
+	db         *gorm.DB
+	router     *gin.Engine
+	userToken  string
+	adminToken string
+	user       models.User
+	merch      models.Merch
+	purchase   *models.Purchase
+}
+
+// setupReturnsRouter собирает эндпоинты возвратов и оформляет пользователю покупку товара с ограниченным запасом.
+func setupReturnsRouter(t *testing.T) *returnsFixture {
+	db, router, adminToken := setupRouter(t)
+
+	f := &returnsFixture{
+		db:         db,
+		router:     router,
+		userToken:  accessToken(t, db, "buyer", models.RoleUser),
+		adminToken: adminToken,
+	}
+	assert.NoError(t, db.Where("username = ?", "buyer").First(&f.user).Error)
+
+	stock := 5
+	f.merch = models.Merch{Name: "hoody", Price: 300, Stock: &stock}
+	assert.NoError(t, db.Create(&f.merch).Error)
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		f.purchase, err = services.BuyMerch(tx, f.user.ID, &f.merch, nil)
+		return err
+	}))
+	return f
+}
+
+func (f *returnsFixture) fileReturn(t *testing.T) handlers.ReturnResponse {
+	w := doJSON(f.router, http.MethodPost, "/api/returns", f.userToken, handlers.CreateReturnRequest{PurchaseID: f.purchase.ID, Reason: "Брак"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp handlers.ReturnResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestReturns_ApproveRefundsOnce(t *testing.T) {
+	f := setupReturnsRouter(t)
+
+	request := f.fileReturn(t)
+	assert.Equal(t, models.ReturnStatusPending, request.Status)
+	assert.Equal(t, "hoody", request.Item)
+
+	// Пока заявка не рассмотрена, вторую подать нельзя
+	w := doJSON(f.router, http.MethodPost, "/api/returns", f.userToken, handlers.CreateReturnRequest{PurchaseID: f.purchase.ID, Reason: "Брак"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(f.router, http.MethodGet, "/api/admin/returns", f.adminToken, nil)
+	var pending []handlers.ReturnResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	assert.Len(t, pending, 1)
+	assert.Equal(t, "buyer", pending[0].Username)
+
+	path := fmt.Sprintf("/api/admin/returns/%d/approve", request.ID)
+	w = doJSON(f.router, http.MethodPost, path, f.adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var approved handlers.ReturnResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approved))
+	assert.Equal(t, models.ReturnStatusApproved, approved.Status)
+	assert.Equal(t, 300, approved.Amount)
+
+	w = doJSON(f.router, http.MethodPost, path, f.adminToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(f.router, http.MethodPost, "/api/returns", f.userToken, handlers.CreateReturnRequest{PurchaseID: f.purchase.ID, Reason: "Ещё раз"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var user models.User
+	assert.NoError(t, f.db.First(&user, f.user.ID).Error)
+	assert.Equal(t, services.InitialCoins, user.Coins)
+	var merch models.Merch
+	assert.NoError(t, f.db.First(&merch, f.merch.ID).Error)
+	assert.Equal(t, 5, *merch.Stock)
+	var purchase models.Purchase
+	assert.NoError(t, f.db.First(&purchase, f.purchase.ID).Error)
+	assert.NotNil(t, purchase.ReturnedAt)
+
+	// Возврат виден в истории, а товар пропал из инвентаря
+	w = doJSON(f.router, http.MethodGet, "/api/info", f.userToken, nil)
+	var info handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Empty(t, info.Inventory)
+	assert.Equal(t, []handlers.RefundEntry{{PurchaseID: f.purchase.ID, Item: "hoody", Amount: 300}}, info.CoinHistory.Refunds)
+
+	mismatches, err := services.Reconcile(f.db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestReturns_RejectAllowsNewRequest(t *testing.T) {
+	f := setupReturnsRouter(t)
+
+	request := f.fileReturn(t)
+	w := doJSON(f.router, http.MethodPost, fmt.Sprintf("/api/admin/returns/%d/reject", request.ID), f.adminToken, handlers.ReviewReturnRequest{Comment: "Следы носки"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(f.router, http.MethodGet, "/api/returns", f.userToken, nil)
+	var own []handlers.ReturnResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &own))
+	assert.Len(t, own, 1)
+	assert.Equal(t, models.ReturnStatusRejected, own[0].Status)
+	assert.Equal(t, "Следы носки", own[0].Comment)
+
+	var user models.User
+	assert.NoError(t, f.db.First(&user, f.user.ID).Error)
+	assert.Equal(t, services.InitialCoins-300, user.Coins)
+
+	f.fileReturn(t)
+}
+
+func TestReturns_Validation(t *testing.T) {
+	f := setupReturnsRouter(t)
+
+	// Чужую покупку вернуть нельзя
+	otherToken := accessToken(t, f.db, "other", models.RoleUser)
+	w := doJSON(f.router, http.MethodPost, "/api/returns", otherToken, handlers.CreateReturnRequest{PurchaseID: f.purchase.ID, Reason: "Брак"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(f.router, http.MethodPost, "/api/returns", f.userToken, handlers.CreateReturnRequest{PurchaseID: f.purchase.ID, Reason: "  "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Срок возврата истёк
+	old := time.Now().Add(-services.ReturnWindow() - time.Hour)
+	assert.NoError(t, f.db.Model(&models.Purchase{}).Where("id = ?", f.purchase.ID).Update("created_at", old).Error)
+	w = doJSON(f.router, http.MethodPost, "/api/returns", f.userToken, handlers.CreateReturnRequest{PurchaseID: f.purchase.ID, Reason: "Брак"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(f.router, http.MethodGet, "/api/purchases", f.userToken, nil)
+	var purchases []handlers.PurchaseEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &purchases))
+	assert.Len(t, purchases, 1)
+	assert.Equal(t, 300, purchases[0].Price)
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func transfer(t *testing.T, db *gorm.DB, from, to models.User, amount int) *models.Transaction {
+	var transaction *models.Transaction
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = services.Transfer(tx, from.ID, to.ID, amount, "")
+		return err
+	}))
+	return transaction
+}
+
+func reverse(router *gin.Engine, token string, id uint, policy string) (int, handlers.ReversalResponse) {
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/transactions/%d/reverse", id), token,
+		handlers.ReverseTransferRequest{Policy: policy, Reason: "ошибся получателем"})
+	var resp handlers.ReversalResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+// withoutTime обнуляет время создания записей истории, чтобы их можно было сравнивать целиком.
+func withoutTime(entries []handlers.CoinHistoryEntry) []handlers.CoinHistoryEntry {
+	for i := range entries {
+		entries[i].CreatedAt = time.Time{}
+	}
+	return entries
+}
+
+func assertBalances(t *testing.T, db *gorm.DB, user models.User, coins, debt int) {
+	var stored models.User
+	assert.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, coins, stored.Coins, user.Username)
+	assert.Equal(t, debt, stored.Debt, user.Username)
+}
+
+func TestReverseTransfer_Full(t *testing.T) {
+	db, router, token := setupRouter(t)
+	sender := createLedgerUser(t, db, "sender")
+	receiver := createLedgerUser(t, db, "receiver")
+	original := transfer(t, db, sender, receiver, 500)
+
+	w := doJSON(router, http.MethodGet, "/api/admin/transactions?username=sender", token, nil)
+	var transactions []handlers.TransactionEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transactions))
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, original.ID, transactions[0].ID)
+
+	code, resp := reverse(router, token, original.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, services.ReversalPolicyPartial, resp.Policy)
+	assert.Equal(t, 500, resp.Amount)
+	assertBalances(t, db, sender, 1000, 0)
+	assertBalances(t, db, receiver, 1000, 0)
+
+	// Перевод отменяется только один раз, а компенсирующую транзакцию отменить нельзя
+	code, _ = reverse(router, token, original.ID, "")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = reverse(router, token, resp.ReversalID, "")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = reverse(router, token, 9999, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = reverse(router, token, original.ID, "all")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Отмена видна обеим сторонам
+	senderToken, err := services.IssueTokens(db, &sender)
+	assert.NoError(t, err)
+	w = doJSON(router, http.MethodGet, "/api/info", senderToken.AccessToken, nil)
+	var info handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, []handlers.CoinHistoryEntry{{ID: original.ID, ToUser: "receiver", Amount: 500, Reversed: true}}, withoutTime(info.CoinHistory.Sent))
+	assert.Equal(t, []handlers.CoinHistoryEntry{{ID: resp.ReversalID, FromUser: "receiver", Amount: 500, Reversal: true}}, withoutTime(info.CoinHistory.Received))
+
+	receiverToken, err := services.IssueTokens(db, &receiver)
+	assert.NoError(t, err)
+	w = doJSON(router, http.MethodGet, "/api/info", receiverToken.AccessToken, nil)
+	var receiverInfo handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &receiverInfo))
+	assert.Equal(t, []handlers.CoinHistoryEntry{{ID: resp.ReversalID, ToUser: "sender", Amount: 500, Reversal: true}}, withoutTime(receiverInfo.CoinHistory.Sent))
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestReverseTransfer_Partial(t *testing.T) {
+	db, router, token := setupRouter(t)
+	sender := createLedgerUser(t, db, "sender")
+	receiver := createLedgerUser(t, db, "receiver")
+	other := createLedgerUser(t, db, "other")
+	original := transfer(t, db, sender, receiver, 500)
+	transfer(t, db, receiver, other, 1300)
+
+	code, resp := reverse(router, token, original.ID, services.ReversalPolicyPartial)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 200, resp.Amount)
+	assert.Equal(t, 0, resp.Debt)
+	assertBalances(t, db, sender, 700, 0)
+	assertBalances(t, db, receiver, 0, 0)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestReverseTransfer_NegativeCreatesDebt(t *testing.T) {
+	db, router, token := setupRouter(t)
+	sender := createLedgerUser(t, db, "sender")
+	receiver := createLedgerUser(t, db, "receiver")
+	other := createLedgerUser(t, db, "other")
+	original := transfer(t, db, sender, receiver, 500)
+	transfer(t, db, receiver, other, 1300)
+
+	code, resp := reverse(router, token, original.ID, services.ReversalPolicyNegative)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 500, resp.Amount)
+	assert.Equal(t, 300, resp.Debt)
+	assertBalances(t, db, sender, 1000, 0)
+	assertBalances(t, db, receiver, 0, 300)
+
+	balance, err := services.LedgerBalance(db, receiver.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, -300, balance)
+
+	// Следующие зачисления сначала погашают долг
+	transfer(t, db, other, receiver, 400)
+	assertBalances(t, db, receiver, 100, 0)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -0,0 +1,187 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	after := time.Date(2025, 3, 14, 10, 7, 30, 0, time.UTC) // пятница
+	for _, tc := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 18 * * 1-5", time.Date(2025, 3, 14, 18, 30, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2025, 3, 16, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Заданы и день месяца, и день недели: подходит любой из них
+		{"0 0 20 * 1", time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)},
+	} {
+		schedule, err := services.ParseSchedule(tc.expr)
+		if assert.NoError(t, err, tc.expr) {
+			assert.Equal(t, tc.next, schedule.Next(after), tc.expr)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := services.ParseSchedule(expr)
+		assert.ErrorIs(t, err, services.ErrInvalidSchedule, expr)
+	}
+
+	never, err := services.ParseSchedule("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, never.Next(after).IsZero())
+}
+
+func createScheduledTransfer(t *testing.T, router *gin.Engine, token string, req handlers.CreateScheduledTransferRequest) handlers.ScheduledTransferResponse {
+	w := doJSON(router, http.MethodPost, "/api/scheduled-transfers", token, req)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var resp handlers.ScheduledTransferResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func storedScheduledTransfer(t *testing.T, db *gorm.DB, id uint) models.ScheduledTransfer {
+	var transfer models.ScheduledTransfer
+	assert.NoError(t, db.First(&transfer, id).Error)
+	return transfer
+}
+
+func TestScheduledTransfers_OneOffRunsOnce(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	token := accessToken(t, db, "manager", models.RoleUser)
+	accessToken(t, db, "report", models.RoleUser)
+
+	w := doJSON(router, http.MethodPost, "/api/scheduled-transfers", token,
+		handlers.CreateScheduledTransferRequest{ToUser: "report", Amount: 50})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPost, "/api/scheduled-transfers", token,
+		handlers.CreateScheduledTransferRequest{ToUser: "report", Amount: 50, Schedule: "0 25 * * *"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	runAt := time.Now().Add(time.Hour)
+	created := createScheduledTransfer(t, router, token,
+		handlers.CreateScheduledTransferRequest{ToUser: "report", Amount: 50, Message: "Бонус", RunAt: &runAt})
+	assert.Equal(t, models.ScheduledTransferActive, created.Status)
+
+	// До наступления времени перевод не выполняется
+	executed, err := services.RunDueScheduledTransfers(db, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, executed)
+
+	executed, err = services.RunDueScheduledTransfers(db, runAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, executed)
+	executed, err = services.RunDueScheduledTransfers(db, runAt.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, executed)
+
+	stored := storedScheduledTransfer(t, db, created.ID)
+	assert.Equal(t, models.ScheduledTransferCompleted, stored.Status)
+	if assert.NotNil(t, stored.LastTransactionID) {
+		var transaction models.Transaction
+		assert.NoError(t, db.First(&transaction, *stored.LastTransactionID).Error)
+		assert.Equal(t, 50, transaction.Amount)
+		assert.Equal(t, "Бонус", transaction.Message)
+	}
+
+	var report models.User
+	assert.NoError(t, db.Where("username = ?", "report").First(&report).Error)
+	assert.Equal(t, services.InitialCoins+50, report.Coins)
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestScheduledTransfers_RecurringRetriesAndSkips(t *testing.T) {
+	t.Setenv("SCHEDULED_TRANSFER_MAX_ATTEMPTS", "2")
+	t.Setenv("SCHEDULED_TRANSFER_RETRY_DELAY", "10m")
+	db, router, _ := setupRouter(t)
+	token := accessToken(t, db, "manager", models.RoleUser)
+	accessToken(t, db, "report", models.RoleUser)
+
+	created := createScheduledTransfer(t, router, token,
+		handlers.CreateScheduledTransferRequest{ToUser: "report", Amount: services.InitialCoins + 1, Schedule: "@daily"})
+	first := created.NextRunAt
+	assert.Equal(t, 0, first.Hour())
+
+	// Не хватает монет: попытка повторяется через RETRY_DELAY
+	executed, err := services.RunDueScheduledTransfers(db, first)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, executed)
+	stored := storedScheduledTransfer(t, db, created.ID)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, services.ErrInsufficientFunds.Error(), stored.LastError)
+	assert.WithinDuration(t, first.Add(10*time.Minute), stored.NextRunAt, time.Second)
+
+	// После последней попытки запуск пропускается до следующего дня
+	_, err = services.RunDueScheduledTransfers(db, stored.NextRunAt)
+	assert.NoError(t, err)
+	stored = storedScheduledTransfer(t, db, created.ID)
+	assert.Equal(t, models.ScheduledTransferActive, stored.Status)
+	assert.Equal(t, 0, stored.Attempts)
+	assert.WithinDuration(t, first.AddDate(0, 0, 1), stored.NextRunAt, time.Second)
+
+	// Денег стало достаточно: перевод выполняется и назначается на следующий день
+	assert.NoError(t, db.Model(&models.ScheduledTransfer{}).Where("id = ?", created.ID).Update("amount", 100).Error)
+	executed, err = services.RunDueScheduledTransfers(db, stored.NextRunAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, executed)
+	stored = storedScheduledTransfer(t, db, created.ID)
+	assert.Empty(t, stored.LastError)
+	assert.WithinDuration(t, first.AddDate(0, 0, 2), stored.NextRunAt, time.Second)
+}
+
+func TestScheduledTransfers_PauseResumeCancel(t *testing.T) {
+	db, router, _ := setupRouter(t)
+	token := accessToken(t, db, "manager", models.RoleUser)
+	otherToken := accessToken(t, db, "report", models.RoleUser)
+
+	created := createScheduledTransfer(t, router, token,
+		handlers.CreateScheduledTransferRequest{ToUser: "report", Amount: 10, Schedule: "0 9 * * 1"})
+	path := func(action string) string {
+		return fmt.Sprintf("/api/scheduled-transfers/%d/%s", created.ID, action)
+	}
+
+	w := doJSON(router, http.MethodPost, path("pause"), otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doJSON(router, http.MethodPost, path("pause"), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodPost, path("pause"), token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Приостановленный перевод не выполняется
+	executed, err := services.RunDueScheduledTransfers(db, created.NextRunAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, executed)
+
+	w = doJSON(router, http.MethodPost, path("resume"), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodPost, path("cancel"), token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodPost, path("resume"), token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(router, http.MethodGet, "/api/scheduled-transfers?status=cancelled", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []handlers.ScheduledTransferResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "report", list[0].ToUser)
+		assert.Equal(t, "0 9 * * 1", list[0].Schedule)
+	}
+}
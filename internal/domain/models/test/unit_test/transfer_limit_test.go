@@ -0,0 +1,132 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// sendCoin отправляет монеты и возвращает код ответа и лимит, если перевод отклонён из-за него.
+func sendCoin(router *gin.Engine, token, toUser string, amount int) (int, handlers.TransferLimitErrorResponse) {
+	w := doJSON(router, http.MethodPost, "/api/sendCoin", token, handlers.SendCoinRequest{ToUser: toUser, Amount: amount})
+	var resp handlers.TransferLimitErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestTransferLimits_Defaults(t *testing.T) {
+	t.Setenv("TRANSFER_MAX_AMOUNT", "300")
+	t.Setenv("TRANSFER_DAILY_LIMIT", "500")
+	t.Setenv("TRANSFER_DAILY_RECIPIENTS", "2")
+	db, router, _ := setupRouter(t)
+	token := accessToken(t, db, "alice", models.RoleUser)
+	for _, name := range []string{"bob", "carol", "dave"} {
+		accessToken(t, db, name, models.RoleUser)
+	}
+
+	code, resp := sendCoin(router, token, "bob", 301)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, handlers.TransferLimitErrorResponse{
+		Error:  "Превышена максимальная сумма одного перевода",
+		Limit:  services.TransferLimitAmount,
+		Max:    300,
+		Amount: 301,
+	}, resp)
+
+	code, _ = sendCoin(router, token, "bob", 100)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = sendCoin(router, token, "carol", 100)
+	assert.Equal(t, http.StatusOK, code)
+	code, resp = sendCoin(router, token, "dave", 100)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, services.TransferLimitDailyRecipients, resp.Limit)
+	assert.Equal(t, 2, resp.Used)
+
+	// Переводы уже знакомому получателю лимит получателей не затрагивают
+	code, _ = sendCoin(router, token, "bob", 300)
+	assert.Equal(t, http.StatusOK, code)
+	code, resp = sendCoin(router, token, "carol", 1)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, services.TransferLimitDaily, resp.Limit)
+	assert.Equal(t, 500, resp.Used)
+
+	var alice models.User
+	assert.NoError(t, db.Where("username = ?", "alice").First(&alice).Error)
+	assertBalances(t, db, alice, 500, 0)
+}
+
+func TestTransferLimits_AdminOverride(t *testing.T) {
+	t.Setenv("TRANSFER_DAILY_LIMIT", "100")
+	db, router, adminToken := setupRouter(t)
+	token := accessToken(t, db, "alice", models.RoleUser)
+	accessToken(t, db, "bob", models.RoleUser)
+
+	negative := -1
+	w := doJSON(router, http.MethodPut, "/api/admin/users/alice/transfer-limits", adminToken,
+		handlers.SetTransferLimitsRequest{WeeklyLimit: &negative})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(router, http.MethodPut, "/api/admin/users/nobody/transfer-limits", adminToken, handlers.SetTransferLimitsRequest{})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	unlimited, weekly := 0, 600
+	w = doJSON(router, http.MethodPut, "/api/admin/users/alice/transfer-limits", adminToken,
+		handlers.SetTransferLimitsRequest{DailyLimit: &unlimited, WeeklyLimit: &weekly})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var limits handlers.TransferLimitsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &limits))
+	assert.Equal(t, services.TransferLimits{DailyLimit: 100}, limits.Defaults)
+	assert.Equal(t, services.TransferLimits{WeeklyLimit: 600}, limits.Effective)
+	assert.Equal(t, "admin", limits.Override.UpdatedBy)
+
+	// Перевод трёхдневной давности учитывается только недельным лимитом
+	code, _ := sendCoin(router, token, "bob", 500)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, db.Model(&models.Transaction{}).Where("amount = ?", 500).
+		Update("created_at", time.Now().AddDate(0, 0, -3)).Error)
+	code, resp := sendCoin(router, token, "bob", 150)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, services.TransferLimitWeekly, resp.Limit)
+	assert.Equal(t, 500, resp.Used)
+
+	w = doJSON(router, http.MethodGet, "/api/admin/users/alice/transfer-limits", adminToken, nil)
+	var current handlers.TransferLimitsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &current))
+	assert.Equal(t, services.TransferUsage{Weekly: 500}, current.Usage)
+
+	// После сброса снова действует дневной лимит по умолчанию
+	w = doJSON(router, http.MethodDelete, "/api/admin/users/alice/transfer-limits", adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(router, http.MethodDelete, "/api/admin/users/alice/transfer-limits", adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	code, resp = sendCoin(router, token, "bob", 150)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, services.TransferLimitDaily, resp.Limit)
+	code, _ = sendCoin(router, token, "bob", 100)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestTransferLimits_IgnoreReturnedTransfers(t *testing.T) {
+	t.Setenv("TRANSFER_DAILY_LIMIT", "300")
+	t.Setenv("TRANSFER_DAILY_RECIPIENTS", "1")
+	db, router, _ := setupRouter(t)
+	token := accessToken(t, db, "alice", models.RoleUser)
+	bob := accessToken(t, db, "bob", models.RoleUser)
+	accessToken(t, db, "carol", models.RoleUser)
+
+	// Перевод с удержанием, возвращённый получателем, не расходует ни сумму, ни число получателей
+	escrow := createEscrow(t, router, token, handlers.CreateEscrowRequest{ToUser: "bob", Amount: 300})
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/escrow/%d/release", escrow.ID), bob, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	code, _ := sendCoin(router, token, "carol", 300)
+	assert.Equal(t, http.StatusOK, code)
+	assertBalances(t, db, userByName(t, db, "alice"), 700, 0)
+}
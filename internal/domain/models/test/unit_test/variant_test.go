@@ -0,0 +1,168 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/defskela/merchmarket/internal/api/handlers"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/defskela/merchmarket/internal/domain/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createVariant(t *testing.T, router *gin.Engine, token string, merchID uint, req handlers.CreateVariantRequest) handlers.VariantResponse {
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/variants", merchID), token, req)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var variant handlers.VariantResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &variant))
+	return variant
+}
+
+func TestVariants_BuyByVariant(t *testing.T) {
+	db, router, adminToken := setupRouter(t)
+	hoodie := createMerchViaAPI(t, router, adminToken, "hoodie", 300)
+	createMerchViaAPI(t, router, adminToken, "cup", 20)
+
+	one, price := 1, 350
+	createVariant(t, router, adminToken, hoodie.ID, handlers.CreateVariantRequest{
+		Name: "M", Attributes: map[string]string{"size": "M"}, Stock: &one,
+	})
+	large := createVariant(t, router, adminToken, hoodie.ID, handlers.CreateVariantRequest{
+		Name: "XL", Attributes: map[string]string{"size": "XL"}, Price: &price,
+	})
+	assert.Equal(t, 350, large.Price)
+
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/api/admin/merch/%d/variants", hoodie.ID), adminToken,
+		handlers.CreateVariantRequest{Name: "M"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	token := accessToken(t, db, "testuser", models.RoleUser)
+
+	// Товар с вариантами нельзя купить без выбора варианта, а товар без вариантов покупается как раньше и без него
+	assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodGet, "/api/buy/hoodie", token, nil).Code)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodGet, "/api/buy/hoodie?variant=S", token, nil).Code)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodGet, "/api/buy/cup?variant=M", token, nil).Code)
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodGet, "/api/buy/cup", token, nil).Code)
+
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodGet, "/api/buy/hoodie?variant=M", token, nil).Code)
+	assert.Equal(t, http.StatusConflict, doJSON(router, http.MethodGet, "/api/buy/hoodie?variant=M", token, nil).Code)
+	w = doJSON(router, http.MethodPost, "/api/buy/hoodie", token, handlers.BuyItemRequest{Variant: "XL"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assertBalances(t, db, userByName(t, db, "testuser"), 1000-20-300-350, 0)
+
+	var purchase models.Purchase
+	assert.NoError(t, db.Where("variant_id = ?", large.ID).First(&purchase).Error)
+	assert.Equal(t, 350, purchase.Price)
+
+	w = doJSON(router, http.MethodGet, "/api/info", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var info handlers.InfoResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, []handlers.InventoryItem{
+		{Type: "cup", Quantity: 1},
+		{Type: "hoodie", Variant: "M", Quantity: 1},
+		{Type: "hoodie", Variant: "XL", Quantity: 1},
+	}, info.Inventory)
+
+	w = doJSON(router, http.MethodPost, "/api/cart/items", token, handlers.AddCartItemRequest{Item: "hoodie"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mismatches, err := services.Reconcile(db)
+	assert.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestVariants_CatalogListsVariantsAndStock(t *testing.T) {
+	db, router, adminToken := setupRouter(t)
+	hoodie := createMerchViaAPI(t, router, adminToken, "hoodie", 300)
+	createMerchViaAPI(t, router, adminToken, "cup", 20)
+
+	zero := 0
+	small := createVariant(t, router, adminToken, hoodie.ID, handlers.CreateVariantRequest{Name: "S", Stock: &zero})
+	token := accessToken(t, db, "testuser", models.RoleUser)
+
+	listMerch := func(query string) []handlers.MerchResponse {
+		w := doJSON(router, http.MethodGet, "/api/merch"+query, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list handlers.MerchListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		return list.Items
+	}
+
+	items := listMerch("")
+	if assert.Len(t, items, 2) && assert.Len(t, items[1].Variants, 1) {
+		assert.Equal(t, "S", items[1].Variants[0].Name)
+		assert.Equal(t, 300, items[1].Variants[0].Price)
+	}
+	// Единственный вариант закончился, поэтому товара нет в наличии
+	assert.Len(t, listMerch("?inStock=true"), 1)
+
+	two, price := 2, 280
+	path := fmt.Sprintf("/api/admin/merch/%d/variants/%d", hoodie.ID, small.ID)
+	w := doJSON(router, http.MethodPut, path, adminToken, handlers.UpdateVariantRequest{Stock: &two, Price: &price})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, listMerch("?inStock=true"), 2)
+
+	w = doJSON(router, http.MethodDelete, path, adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, listMerch("")[1].Variants)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodPut, path, adminToken, handlers.UpdateVariantRequest{Stock: &two}).Code)
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/api/admin/merch/%d/changes", hoodie.ID), adminToken, nil)
+	var changes []handlers.CatalogChangeEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &changes))
+	actions := make([]string, 0, len(changes))
+	for _, change := range changes {
+		actions = append(actions, change.Action)
+	}
+	assert.Equal(t, []string{
+		models.CatalogActionCreate,
+		models.CatalogActionVariantCreate,
+		models.CatalogActionVariantUpdate,
+		models.CatalogActionVariantDelete,
+	}, actions)
+}
+
+func TestVariants_CatalogFiltersByVariantPrice(t *testing.T) {
+	db, router, adminToken := setupRouter(t)
+	hoodie := createMerchViaAPI(t, router, adminToken, "hoodie", 300)
+	baseballCap := createMerchViaAPI(t, router, adminToken, "cap", 50)
+	createMerchViaAPI(t, router, adminToken, "cup", 20)
+
+	cheap, expensive := 90, 2000
+	small := createVariant(t, router, adminToken, hoodie.ID, handlers.CreateVariantRequest{Name: "S", Price: &cheap})
+	createVariant(t, router, adminToken, hoodie.ID, handlers.CreateVariantRequest{Name: "XL"})
+	createVariant(t, router, adminToken, baseballCap.ID, handlers.CreateVariantRequest{Name: "gold", Price: &expensive})
+	token := accessToken(t, db, "testuser", models.RoleUser)
+
+	listNames := func(query string) []string {
+		w := doJSON(router, http.MethodGet, "/api/merch"+query, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var list handlers.MerchListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		names := []string{}
+		for _, item := range list.Items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	// Цена товара с вариантами — наименьшая цена варианта, а не цена самого товара
+	assert.Equal(t, []string{"cup", "hoodie"}, listNames("?maxPrice=100"))
+	assert.Equal(t, []string{"cap"}, listNames("?minPrice=100"))
+	assert.Equal(t, []string{"cup", "hoodie", "cap"}, listNames("?sort=price"))
+	assert.Equal(t, []string{"cap", "hoodie", "cup"}, listNames("?sort=price&order=desc"))
+	assert.Equal(t, []string{"cup", "hoodie"}, listNames("?affordable=true"))
+
+	// Закончившийся вариант не учитывается
+	zero := 0
+	w := doJSON(router, http.MethodPut, fmt.Sprintf("/api/admin/merch/%d/variants/%d", hoodie.ID, small.ID), adminToken,
+		handlers.UpdateVariantRequest{Stock: &zero})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"cup"}, listNames("?maxPrice=100"))
+	assert.Equal(t, []string{"cup", "hoodie", "cap"}, listNames("?sort=price"))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/defskela/merchmarket/internal/api/handlers"
@@ -186,3 +187,28 @@ func TestSendCoin_Success(t *testing.T) {
 	err = db.Where("from_user_id = ? AND to_user_id = ? AND amount = ?", sender.ID, receiver.ID, 50).First(&transaction).Error
 	assert.NoError(t, err)
 }
+
+func TestSendCoin_Message(t *testing.T) {
+	db := setupTestDB(t)
+	handler := handlers.NewWalletHandler(db)
+
+	sender := models.User{Username: "sender", Coins: 100}
+	assert.NoError(t, db.Create(&sender).Error)
+	receiver := models.User{Username: "receiver", Coins: 100}
+	assert.NoError(t, db.Create(&receiver).Error)
+
+	// Слишком длинный комментарий отклоняется до перевода
+	reqBody := handlers.SendCoinRequest{ToUser: "receiver", Amount: 10, Message: strings.Repeat("я", 201)}
+	w, err := performSendCoinRequest(handler, reqBody, "sender")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	reqBody = handlers.SendCoinRequest{ToUser: "receiver", Amount: 10, Message: " За\tпомощь\nс релизом\x00\u202e \x1b[31m"}
+	w, err = performSendCoinRequest(handler, reqBody, "sender")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var transaction models.Transaction
+	assert.NoError(t, db.Where("from_user_id = ?", sender.ID).First(&transaction).Error)
+	assert.Equal(t, "За помощь с релизом [31m", transaction.Message)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrAllowancePolicyNotFound возвращается, если политика начисления не найдена.
+	ErrAllowancePolicyNotFound = errors.New("политика начисления не найдена")
+	// ErrUnknownAllowancePeriod возвращается для неизвестного периода начисления.
+	ErrUnknownAllowancePeriod = errors.New("неизвестный период начисления")
+	// ErrAllowanceTarget возвращается, если политика одновременно задана для пользователя и для роли.
+	ErrAllowanceTarget = errors.New("политика задаётся либо для пользователя, либо для роли")
+)
+
+// AllowancePolicyChanges описывает изменяемые поля политики начисления. Пустые поля не меняются.
+type AllowancePolicyChanges struct {
+	Name   *string
+	Amount *int
+	Period *string
+	Active *bool
+}
+
+// AllowanceGrantPlan — начисление пользователю по политике за период:
+// запланированное при предпросмотре или выполненное.
+type AllowanceGrantPlan struct {
+	PolicyID   uint   `json:"policyId"`
+	PolicyName string `json:"policyName"`
+	UserID     uint   `json:"userId"`
+	Username   string `json:"username"`
+	Period     string `json:"period"`
+	Amount     int    `json:"amount"`
+}
+
+// AllowancePeriodKey возвращает обозначение периода, в который попадает момент t (UTC):
+// 2025-03-14 для дня, 2025-W11 для недели ISO и 2025-03 для месяца.
+func AllowancePeriodKey(period string, t time.Time) (string, error) {
+	t = t.UTC()
+	switch period {
+	case models.AllowancePeriodDay:
+		return t.Format(time.DateOnly), nil
+	case models.AllowancePeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	case models.AllowancePeriodMonth:
+		return t.Format("2006-01"), nil
+	}
+	return "", ErrUnknownAllowancePeriod
+}
+
+// CreateAllowancePolicy создаёт политику начисления. Если заданы и userID, и role, возвращается ErrAllowanceTarget.
+func CreateAllowancePolicy(db *gorm.DB, policy *models.AllowancePolicy) error {
+	if _, err := AllowancePeriodKey(policy.Period, time.Now()); err != nil {
+		return err
+	}
+	if policy.UserID != nil && policy.Role != "" {
+		return ErrAllowanceTarget
+	}
+	return db.Create(policy).Error
+}
+
+// UpdateAllowancePolicy изменяет политику начисления. Уже выполненные начисления не пересчитываются.
+func UpdateAllowancePolicy(db *gorm.DB, id uint, changes AllowancePolicyChanges) (*models.AllowancePolicy, error) {
+	policy, err := findAllowancePolicy(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if changes.Name != nil {
+		updates["name"] = *changes.Name
+		policy.Name = *changes.Name
+	}
+	if changes.Amount != nil {
+		updates["amount"] = *changes.Amount
+		policy.Amount = *changes.Amount
+	}
+	if changes.Period != nil {
+		if _, err := AllowancePeriodKey(*changes.Period, time.Now()); err != nil {
+			return nil, err
+		}
+		updates["period"] = *changes.Period
+		policy.Period = *changes.Period
+	}
+	if changes.Active != nil {
+		updates["active"] = *changes.Active
+		policy.Active = *changes.Active
+	}
+	if len(updates) == 0 {
+		return policy, nil
+	}
+	if err := db.Model(&models.AllowancePolicy{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeleteAllowancePolicy удаляет политику начисления. История начислений по ней сохраняется.
+func DeleteAllowancePolicy(db *gorm.DB, id uint) error {
+	res := db.Delete(&models.AllowancePolicy{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAllowancePolicyNotFound
+	}
+	return nil
+}
+
+func findAllowancePolicy(db *gorm.DB, id uint) (*models.AllowancePolicy, error) {
+	var policy models.AllowancePolicy
+	if err := db.First(&policy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAllowancePolicyNotFound
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// PlanAllowances возвращает начисления, которые ещё не выполнены в текущем для now периоде
+// каждой активной политики. Используется для предпросмотра и самим GrantAllowances.
+func PlanAllowances(db *gorm.DB, now time.Time) ([]AllowanceGrantPlan, error) {
+	var policies []models.AllowancePolicy
+	if err := db.Where("active = ?", true).Order("id").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	plan := []AllowanceGrantPlan{}
+	for _, policy := range policies {
+		period, err := AllowancePeriodKey(policy.Period, now)
+		if err != nil {
+			return nil, err
+		}
+
+		query := db.Model(&models.User{}).
+			Select("users.id AS user_id, users.username").
+			Where("NOT EXISTS (SELECT 1 FROM allowance_grants WHERE allowance_grants.user_id = users.id "+
+				"AND allowance_grants.policy_id = ? AND allowance_grants.period = ?)", policy.ID, period)
+		switch {
+		case policy.UserID != nil:
+			query = query.Where("users.id = ?", *policy.UserID)
+		case policy.Role != "":
+			query = query.Where("users.role = ?", policy.Role)
+		}
+
+		var users []struct {
+			UserID   uint
+			Username string
+		}
+		if err := query.Order("users.id").Scan(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			plan = append(plan, AllowanceGrantPlan{
+				PolicyID:   policy.ID,
+				PolicyName: policy.Name,
+				UserID:     user.UserID,
+				Username:   user.Username,
+				Period:     period,
+				Amount:     policy.Amount,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// GrantAllowances начисляет монеты по всем активным политикам за текущий для now период
+// и возвращает выполненные начисления. Каждое начисление проводится через журнал в
+// отдельной транзакции; уникальный индекс AllowanceGrant гарантирует, что повторный
+// запуск в том же периоде (в том числе параллельный) не начислит монеты ещё раз.
+func GrantAllowances(db *gorm.DB, now time.Time) ([]AllowanceGrantPlan, error) {
+	plan, err := PlanAllowances(db, now)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := []AllowanceGrantPlan{}
+	for _, item := range plan {
+		ok, err := grantAllowance(db, item)
+		if err != nil {
+			return granted, err
+		}
+		if ok {
+			granted = append(granted, item)
+		}
+	}
+	return granted, nil
+}
+
+// grantAllowance выполняет одно начисление. Возвращает false, если за этот период
+// начисление уже было выполнено.
+func grantAllowance(db *gorm.DB, item AllowanceGrantPlan) (bool, error) {
+	granted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		grant := models.AllowanceGrant{
+			PolicyID: item.PolicyID,
+			UserID:   item.UserID,
+			Period:   item.Period,
+			Amount:   item.Amount,
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := Credit(tx, item.UserID, item.Amount); err != nil {
+			return err
+		}
+		entry := models.LedgerEntry{
+			Kind:        models.LedgerKindGrant,
+			Description: fmt.Sprintf("Начисление по политике «%s» за %s", item.PolicyName, item.Period),
+			Postings: []models.LedgerPosting{
+				SystemPosting(models.LedgerAccountIssuance, -item.Amount),
+				UserPosting(item.UserID, item.Amount),
+			},
+		}
+		if err := PostEntry(tx, &entry); err != nil {
+			return err
+		}
+		granted = true
+		return tx.Model(&grant).Update("ledger_entry_id", entry.ID).Error
+	})
+	return granted, err
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// MaxCartQuantity — максимальное количество единиц одного товара в корзине.
+const MaxCartQuantity = 99
+
+var (
+	// ErrCartEmpty возвращается при оформлении пустой корзины.
+	ErrCartEmpty = errors.New("корзина пуста")
+	// ErrCartItemNotFound возвращается, если товара нет в корзине.
+	ErrCartItemNotFound = errors.New("товара нет в корзине")
+	// ErrCartQuantityExceeded возвращается, если количество товара в корзине превышает MaxCartQuantity.
+	ErrCartQuantityExceeded = errors.New("превышено количество товара в корзине")
+)
+
+// CartLineError описывает причину, по которой позицию корзины нельзя оформить.
+type CartLineError struct {
+	MerchID  uint
+	Item     string
+	Variant  string
+	Quantity int
+	// Available — остаток товара (или варианта) на складе; nil, если товар удалён из каталога.
+	Available *int
+	Err       error
+}
+
+// CheckoutError возвращается, если хотя бы одну позицию корзины нельзя оформить.
+// Содержит объяснение для каждой такой позиции.
+type CheckoutError struct {
+	Lines []CartLineError
+}
+
+func (e *CheckoutError) Error() string {
+	items := make([]string, 0, len(e.Lines))
+	for _, line := range e.Lines {
+		items = append(items, fmt.Sprintf("%s: %v", line.Item, line.Err))
+	}
+	return "не удалось оформить корзину: " + strings.Join(items, "; ")
+}
+
+// GetCart возвращает позиции корзины пользователя. Товары и варианты, удалённые из
+// каталога, остаются в корзине, чтобы пользователь увидел, почему их нельзя оформить.
+func GetCart(db *gorm.DB, userID uint) ([]models.CartItem, error) {
+	var items []models.CartItem
+	err := db.Preload("Merch", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Variant", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&items).Error
+	return items, err
+}
+
+// AddToCart добавляет quantity единиц товара (для товара с вариантами — выбранного
+// варианта, см. SelectVariant) в корзину пользователя. Если позиция уже есть в корзине,
+// количество увеличивается.
+func AddToCart(db *gorm.DB, userID, merchID uint, variantID *uint, quantity int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Уникальный индекс не защищает позиции без варианта (NULL не равен NULL),
+		// поэтому изменения корзины одного пользователя выполняются по очереди
+		if _, err := LockUsers(tx, userID); err != nil {
+			return err
+		}
+
+		var item models.CartItem
+		err := cartItemQuery(tx, userID, merchID, variantID).First(&item).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if quantity > MaxCartQuantity {
+				return ErrCartQuantityExceeded
+			}
+			item = models.CartItem{UserID: userID, MerchID: merchID, VariantID: variantID, Quantity: quantity}
+			return tx.Create(&item).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if item.Quantity+quantity > MaxCartQuantity {
+			return ErrCartQuantityExceeded
+		}
+		return tx.Model(&item).Update("quantity", item.Quantity+quantity).Error
+	})
+}
+
+// RemoveFromCart уменьшает количество товара (или его варианта) в корзине на quantity единиц.
+// Если quantity не положительно или не меньше количества в корзине, позиция удаляется целиком.
+func RemoveFromCart(db *gorm.DB, userID, merchID uint, variantID *uint, quantity int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var item models.CartItem
+		err := cartItemQuery(tx, userID, merchID, variantID).First(&item).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCartItemNotFound
+			}
+			return err
+		}
+
+		if quantity <= 0 || quantity >= item.Quantity {
+			return tx.Unscoped().Delete(&item).Error
+		}
+		return tx.Model(&item).Update("quantity", item.Quantity-quantity).Error
+	})
+}
+
+// cartItemQuery отбирает позицию корзины пользователя с товаром merchID и вариантом variantID.
+func cartItemQuery(tx *gorm.DB, userID, merchID uint, variantID *uint) *gorm.DB {
+	query := tx.Where("user_id = ? AND merch_id = ?", userID, merchID)
+	if variantID == nil {
+		return query.Where("variant_id IS NULL")
+	}
+	return query.Where("variant_id = ?", *variantID)
+}
+
+// Checkout оформляет все позиции корзины пользователя в рамках переданной транзакции БД:
+// резервирует остатки товаров и выбранных вариантов, списывает общую стоимость
+// по ценам вариантов и создаёт записи о покупках.
+// Если какую-либо позицию оформить нельзя, возвращается *CheckoutError со всеми
+// проблемными позициями; вызывающая сторона должна откатить транзакцию.
+func Checkout(tx *gorm.DB, userID uint) ([]models.Purchase, error) {
+	// Блокировка пользователя не даёт параллельно оформить одну и ту же корзину дважды
+	if _, err := LockUsers(tx, userID); err != nil {
+		return nil, err
+	}
+
+	items, err := GetCart(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	// Варианты могли появиться у товара уже после того, как его положили в корзину
+	merchIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		merchIDs = append(merchIDs, item.MerchID)
+	}
+	variants, err := MerchVariants(tx, merchIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		total      int
+		lineErrors []CartLineError
+	)
+	for _, item := range items {
+		line := CartLineError{
+			MerchID:  item.MerchID,
+			Item:     item.Merch.Name,
+			Quantity: item.Quantity,
+		}
+		if item.Variant != nil {
+			line.Variant = item.Variant.Name
+		}
+		switch {
+		case item.Merch.DeletedAt.Valid:
+			line.Err = ErrMerchNotFound
+		case item.Variant != nil && item.Variant.DeletedAt.Valid:
+			line.Err = ErrVariantNotFound
+		case item.Variant == nil && len(variants[item.MerchID]) > 0:
+			line.Err = ErrVariantRequired
+		}
+		if line.Err != nil {
+			lineErrors = append(lineErrors, line)
+			continue
+		}
+
+		if err := reserveItem(tx, &item.Merch, item.Variant, item.Quantity); err != nil {
+			if !errors.Is(err, ErrOutOfStock) {
+				return nil, err
+			}
+			line.Available = item.Merch.Stock
+			if item.Variant != nil {
+				line.Available = item.Variant.Stock
+			}
+			line.Err = ErrOutOfStock
+			lineErrors = append(lineErrors, line)
+			continue
+		}
+		total += ItemPrice(&item.Merch, item.Variant) * item.Quantity
+	}
+	if len(lineErrors) > 0 {
+		return nil, &CheckoutError{Lines: lineErrors}
+	}
+
+	if err := Debit(tx, userID, total); err != nil {
+		return nil, err
+	}
+
+	purchases := make([]models.Purchase, 0, len(items))
+	for i := range items {
+		for n := 0; n < items[i].Quantity; n++ {
+			purchase, err := recordPurchase(tx, models.Purchase{UserID: userID}, userID, &items[i].Merch, items[i].Variant)
+			if err != nil {
+				return nil, err
+			}
+			purchases = append(purchases, *purchase)
+		}
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.CartItem{}).Error; err != nil {
+		return nil, err
+	}
+	return purchases, nil
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMerchNotFound возвращается, если товар не найден.
+	ErrMerchNotFound = errors.New("товар не найден")
+	// ErrMerchNameTaken возвращается, если товар с таким названием уже есть в каталоге
+	// (в том числе среди удалённых).
+	ErrMerchNameTaken = errors.New("товар с таким названием уже существует")
+	// ErrMerchNotDeleted возвращается при попытке восстановить товар, который не был удалён.
+	ErrMerchNotDeleted = errors.New("товар не удалён")
+	// ErrUnlimitedStock возвращается при пополнении товара, запас которого не ограничен.
+	ErrUnlimitedStock = errors.New("запас товара не ограничен")
+)
+
+// LowStockItem — строка отчёта о заканчивающихся товарах: товар без вариантов
+// или вариант товара (Variant не nil) с ограниченным запасом.
+type LowStockItem struct {
+	Merch   models.Merch
+	Variant *models.MerchVariant
+}
+
+// Stock возвращает остаток строки отчёта: остаток варианта, если он указан, иначе товара.
+func (i LowStockItem) Stock() int {
+	if i.Variant != nil {
+		return *i.Variant.Stock
+	}
+	return *i.Merch.Stock
+}
+
+// MerchChanges описывает изменяемые поля товара. Пустые поля не меняются.
+type MerchChanges struct {
+	Name  *string
+	Price *int
+	Stock *int
+}
+
+// merchSnapshot — состояние товара, сохраняемое в журнале изменений каталога.
+type merchSnapshot struct {
+	Name       string `json:"name"`
+	Price      int    `json:"price"`
+	Stock      *int   `json:"stock,omitempty"`      // не указывается для товаров без ограничения запаса
+	CategoryID *uint  `json:"categoryId,omitempty"` // не указывается для товаров без категории
+	Deleted    bool   `json:"deleted"`
+}
+
+// CreateMerch добавляет товар в каталог и фиксирует изменение в журнале.
+// Если stock равен nil, запас товара не ограничен.
+func CreateMerch(db *gorm.DB, actor, name string, price int, stock *int) (*models.Merch, error) {
+	merch := models.Merch{Name: name, Price: price, Stock: stock}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureMerchNameFree(tx, name, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(&merch).Error; err != nil {
+			return err
+		}
+		return recordCatalogChange(tx, actor, models.CatalogActionCreate, nil, &merch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merch, nil
+}
+
+// UpdateMerch изменяет товар и фиксирует старое и новое значения в журнале.
+func UpdateMerch(db *gorm.DB, actor string, id uint, changes MerchChanges) (*models.Merch, error) {
+	var merch models.Merch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findMerch(tx, id, false, &merch); err != nil {
+			return err
+		}
+		before := merch
+
+		updates := map[string]interface{}{}
+		if changes.Name != nil && *changes.Name != merch.Name {
+			if err := ensureMerchNameFree(tx, *changes.Name, merch.ID); err != nil {
+				return err
+			}
+			updates["name"] = *changes.Name
+			merch.Name = *changes.Name
+		}
+		if changes.Price != nil && *changes.Price != merch.Price {
+			updates["price"] = *changes.Price
+			merch.Price = *changes.Price
+		}
+		if changes.Stock != nil && (merch.Stock == nil || *changes.Stock != *merch.Stock) {
+			updates["stock"] = *changes.Stock
+			merch.Stock = changes.Stock
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.Merch{}).Where("id = ?", merch.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordCatalogChange(tx, actor, models.CatalogActionUpdate, &before, &merch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merch, nil
+}
+
+// DeleteMerch мягко удаляет товар из каталога: купить его больше нельзя,
+// но история покупок сохраняется.
+func DeleteMerch(db *gorm.DB, actor string, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var merch models.Merch
+		if err := findMerch(tx, id, false, &merch); err != nil {
+			return err
+		}
+		if err := tx.Delete(&merch).Error; err != nil {
+			return err
+		}
+		deleted := merch
+		deleted.DeletedAt = gorm.DeletedAt{Valid: true}
+		return recordCatalogChange(tx, actor, models.CatalogActionDelete, &merch, &deleted)
+	})
+}
+
+// RestoreMerch возвращает в каталог мягко удалённый товар.
+func RestoreMerch(db *gorm.DB, actor string, id uint) (*models.Merch, error) {
+	var merch models.Merch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findMerch(tx, id, true, &merch); err != nil {
+			return err
+		}
+		if !merch.DeletedAt.Valid {
+			return ErrMerchNotDeleted
+		}
+		before := merch
+
+		if err := tx.Unscoped().Model(&models.Merch{}).Where("id = ?", merch.ID).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		merch.DeletedAt = gorm.DeletedAt{}
+		return recordCatalogChange(tx, actor, models.CatalogActionRestore, &before, &merch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merch, nil
+}
+
+// RestockMerch увеличивает остаток товара на quantity единиц. Остаток товара
+// с вариантами учитывается по вариантам, их пополняет RestockVariant.
+func RestockMerch(db *gorm.DB, actor string, id uint, quantity int) (*models.Merch, error) {
+	var merch models.Merch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findMerch(tx, id, false, &merch); err != nil {
+			return err
+		}
+		var variants int64
+		if err := tx.Model(&models.MerchVariant{}).Where("merch_id = ?", merch.ID).Count(&variants).Error; err != nil {
+			return err
+		}
+		if variants > 0 {
+			return ErrStockByVariants
+		}
+		if merch.Stock == nil {
+			return ErrUnlimitedStock
+		}
+		before := merch
+
+		err := tx.Model(&models.Merch{}).
+			Where("id = ?", merch.ID).
+			Update("stock", gorm.Expr("stock + ?", quantity)).Error
+		if err != nil {
+			return err
+		}
+		// Перечитываем остаток: параллельные покупки могли его изменить
+		if err := tx.First(&merch, merch.ID).Error; err != nil {
+			return err
+		}
+		return recordCatalogChange(tx, actor, models.CatalogActionRestock, &before, &merch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merch, nil
+}
+
+// LowStockMerch возвращает товары и варианты с ограниченным запасом, остаток которых
+// не превышает threshold, по возрастанию остатка. У товара с вариантами остаток
+// учитывается по вариантам, поэтому сам товар в отчёт не попадает.
+func LowStockMerch(db *gorm.DB, threshold int) ([]LowStockItem, error) {
+	var merches []models.Merch
+	err := db.Where("stock IS NOT NULL AND stock <= ?", threshold).
+		Where("NOT EXISTS (SELECT 1 FROM merch_variants WHERE merch_variants.merch_id = merches.id AND merch_variants.deleted_at IS NULL)").
+		Find(&merches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []models.MerchVariant
+	err = db.Joins("JOIN merches ON merches.id = merch_variants.merch_id AND merches.deleted_at IS NULL").
+		Where("merch_variants.stock IS NOT NULL AND merch_variants.stock <= ?", threshold).
+		Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	merchIDs := make([]uint, 0, len(variants))
+	for _, variant := range variants {
+		merchIDs = append(merchIDs, variant.MerchID)
+	}
+	var owners []models.Merch
+	if len(merchIDs) > 0 {
+		if err := db.Where("id IN ?", merchIDs).Find(&owners).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[uint]models.Merch, len(owners))
+	for _, merch := range owners {
+		byID[merch.ID] = merch
+	}
+
+	items := make([]LowStockItem, 0, len(merches)+len(variants))
+	for _, merch := range merches {
+		items = append(items, LowStockItem{Merch: merch})
+	}
+	for i := range variants {
+		items = append(items, LowStockItem{Merch: byID[variants[i].MerchID], Variant: &variants[i]})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Stock() != items[j].Stock() {
+			return items[i].Stock() < items[j].Stock()
+		}
+		if items[i].Merch.Name != items[j].Merch.Name {
+			return items[i].Merch.Name < items[j].Merch.Name
+		}
+		return items[i].Variant != nil && (items[j].Variant == nil || items[i].Variant.Name < items[j].Variant.Name)
+	})
+	return items, nil
+}
+
+// findMerch ищет товар по ID; withDeleted включает в поиск мягко удалённые товары.
+func findMerch(tx *gorm.DB, id uint, withDeleted bool, merch *models.Merch) error {
+	query := tx
+	if withDeleted {
+		query = query.Unscoped()
+	}
+	if err := query.First(merch, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMerchNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ensureMerchNameFree проверяет, что название не занято другим товаром, включая удалённые:
+// уникальный индекс по названию распространяется и на них.
+func ensureMerchNameFree(tx *gorm.DB, name string, exceptID uint) error {
+	var count int64
+	err := tx.Unscoped().Model(&models.Merch{}).
+		Where("name = ? AND id <> ?", name, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrMerchNameTaken
+	}
+	return nil
+}
+
+func recordCatalogChange(tx *gorm.DB, actor, action string, before, after *models.Merch) error {
+	change := models.CatalogChange{
+		Action:    action,
+		ChangedBy: actor,
+	}
+	if before != nil {
+		change.MerchID = before.ID
+		change.OldValue = snapshotMerch(before)
+	}
+	if after != nil {
+		change.MerchID = after.ID
+		change.NewValue = snapshotMerch(after)
+	}
+	return tx.Create(&change).Error
+}
+
+func snapshotMerch(merch *models.Merch) string {
+	data, _ := json.Marshal(merchSnapshot{
+		Name:       merch.Name,
+		Price:      merch.Price,
+		Stock:      merch.Stock,
+		CategoryID: merch.CategoryID,
+		Deleted:    merch.DeletedAt.Valid,
+	})
+	return string(data)
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// Ограничения на метки товара.
+const (
+	MaxMerchTags = 20 // максимальное число меток у одного товара
+	MaxTagLength = 32 // максимальная длина метки в символах
+)
+
+// maxCategoryDepth — максимальная вложенность категорий.
+const maxCategoryDepth = 16
+
+// categoryPathSeparator разделяет названия категорий в полном названии.
+const categoryPathSeparator = " / "
+
+var (
+	// ErrCategoryNotFound возвращается, если категория не найдена.
+	ErrCategoryNotFound = errors.New("категория не найдена")
+	// ErrCategoryNameTaken возвращается, если у родительской категории уже есть вложенная категория с таким названием.
+	ErrCategoryNameTaken = errors.New("категория с таким названием уже существует")
+	// ErrCategoryCycle возвращается при попытке вложить категорию в неё саму или в её потомка.
+	ErrCategoryCycle = errors.New("категория не может быть вложена в себя")
+	// ErrCategoryTooDeep возвращается, если вложенность категорий превышает допустимую.
+	ErrCategoryTooDeep = errors.New("слишком глубокая вложенность категорий")
+	// ErrCategoryInUse возвращается при удалении категории, в которой есть товары или вложенные категории.
+	ErrCategoryInUse = errors.New("в категории есть товары или вложенные категории")
+	// ErrInvalidTag возвращается, если метка пуста, слишком длинная или меток слишком много.
+	ErrInvalidTag = errors.New("недопустимая метка")
+)
+
+// CategoryChanges описывает изменяемые поля категории. Пустые поля не меняются;
+// ParentID, равный 0, переносит категорию на верхний уровень.
+type CategoryChanges struct {
+	Name     *string
+	ParentID *uint
+}
+
+// CategoryTree — все категории каталога, загруженные одним запросом, с быстрым
+// доступом к родителям и потомкам.
+type CategoryTree struct {
+	byID     map[uint]*models.Category
+	children map[uint][]uint
+	roots    []uint
+}
+
+// tagsSnapshot — метки товара, сохраняемые в журнале изменений каталога.
+type tagsSnapshot struct {
+	Tags []string `json:"tags"`
+}
+
+// LoadCategoryTree загружает все категории каталога.
+func LoadCategoryTree(db *gorm.DB) (*CategoryTree, error) {
+	var categories []models.Category
+	if err := db.Order("name").Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	tree := &CategoryTree{byID: map[uint]*models.Category{}, children: map[uint][]uint{}}
+	for i := range categories {
+		category := &categories[i]
+		tree.byID[category.ID] = category
+		if category.ParentID == nil {
+			tree.roots = append(tree.roots, category.ID)
+		} else {
+			tree.children[*category.ParentID] = append(tree.children[*category.ParentID], category.ID)
+		}
+	}
+	return tree, nil
+}
+
+// Category возвращает категорию по ID или nil, если её нет.
+func (t *CategoryTree) Category(id uint) *models.Category {
+	return t.byID[id]
+}
+
+// Roots возвращает ID категорий верхнего уровня в порядке названий.
+func (t *CategoryTree) Roots() []uint {
+	return t.roots
+}
+
+// Children возвращает ID вложенных категорий в порядке названий.
+func (t *CategoryTree) Children(id uint) []uint {
+	return t.children[id]
+}
+
+// Subtree возвращает ID категории и всех её потомков.
+func (t *CategoryTree) Subtree(id uint) []uint {
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, t.children[ids[i]]...)
+	}
+	return ids
+}
+
+// Path возвращает полное название категории от верхнего уровня, например "Одежда / Худи".
+func (t *CategoryTree) Path(id uint) string {
+	var names []string
+	for category := t.byID[id]; category != nil && len(names) < maxCategoryDepth; {
+		names = append(names, category.Name)
+		if category.ParentID == nil {
+			break
+		}
+		category = t.byID[*category.ParentID]
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, categoryPathSeparator)
+}
+
+// depth возвращает уровень вложенности категории (1 у категорий верхнего уровня).
+func (t *CategoryTree) depth(id uint) int {
+	depth := 0
+	for category := t.byID[id]; category != nil && depth <= maxCategoryDepth; depth++ {
+		if category.ParentID == nil {
+			return depth + 1
+		}
+		category = t.byID[*category.ParentID]
+	}
+	return depth
+}
+
+// height возвращает число уровней в поддереве категории, включая её саму.
+func (t *CategoryTree) height(id uint) int {
+	height := 0
+	for _, child := range t.children[id] {
+		height = max(height, t.height(child))
+	}
+	return height + 1
+}
+
+// CreateCategory создаёт категорию; если parentID не nil, категория вкладывается в родительскую.
+func CreateCategory(db *gorm.DB, name string, parentID *uint) (*models.Category, error) {
+	category := models.Category{Name: name, ParentID: parentID}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tree, err := LoadCategoryTree(tx)
+		if err != nil {
+			return err
+		}
+		if parentID != nil {
+			if tree.Category(*parentID) == nil {
+				return ErrCategoryNotFound
+			}
+			if tree.depth(*parentID) >= maxCategoryDepth {
+				return ErrCategoryTooDeep
+			}
+		}
+		if err := ensureCategoryNameFree(tx, name, parentID, 0); err != nil {
+			return err
+		}
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// UpdateCategory переименовывает категорию и (или) переносит её в другую родительскую категорию.
+func UpdateCategory(db *gorm.DB, id uint, changes CategoryChanges) (*models.Category, error) {
+	var category models.Category
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tree, err := LoadCategoryTree(tx)
+		if err != nil {
+			return err
+		}
+		current := tree.Category(id)
+		if current == nil {
+			return ErrCategoryNotFound
+		}
+		category = *current
+
+		name, parentID := category.Name, category.ParentID
+		if changes.Name != nil {
+			name = *changes.Name
+		}
+		if changes.ParentID != nil {
+			parentID = changes.ParentID
+			if *parentID == 0 {
+				parentID = nil
+			}
+		}
+		if parentID != nil {
+			if tree.Category(*parentID) == nil {
+				return ErrCategoryNotFound
+			}
+			for _, descendant := range tree.Subtree(id) {
+				if descendant == *parentID {
+					return ErrCategoryCycle
+				}
+			}
+			if tree.depth(*parentID)+tree.height(id) > maxCategoryDepth {
+				return ErrCategoryTooDeep
+			}
+		}
+		if err := ensureCategoryNameFree(tx, name, parentID, id); err != nil {
+			return err
+		}
+
+		category.Name, category.ParentID = name, parentID
+		return tx.Model(&models.Category{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"name": name, "parent_id": parentID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// DeleteCategory удаляет пустую категорию. Категорию с товарами или вложенными
+// категориями удалить нельзя, сначала их нужно перенести.
+func DeleteCategory(db *gorm.DB, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCategoryNotFound
+			}
+			return err
+		}
+
+		var children, merches int64
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		// Удалённые товары тоже учитываются: их можно восстановить
+		if err := tx.Unscoped().Model(&models.Merch{}).Where("category_id = ?", id).Count(&merches).Error; err != nil {
+			return err
+		}
+		if children > 0 || merches > 0 {
+			return ErrCategoryInUse
+		}
+		return tx.Unscoped().Delete(&category).Error
+	})
+}
+
+// SetMerchCategory помещает товар в категорию (nil — убирает из категории)
+// и фиксирует изменение в журнале каталога.
+func SetMerchCategory(db *gorm.DB, actor string, merchID uint, categoryID *uint) (*models.Merch, error) {
+	var merch models.Merch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findMerch(tx, merchID, false, &merch); err != nil {
+			return err
+		}
+		if categoryID != nil {
+			if err := tx.First(&models.Category{}, *categoryID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrCategoryNotFound
+				}
+				return err
+			}
+		}
+		before := merch
+
+		if err := tx.Model(&models.Merch{}).Where("id = ?", merch.ID).Update("category_id", categoryID).Error; err != nil {
+			return err
+		}
+		merch.CategoryID = categoryID
+		return recordCatalogChange(tx, actor, models.CatalogActionCategorize, &before, &merch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merch, nil
+}
+
+// NormalizeTags приводит метки к нижнему регистру, обрезает пробелы, убирает повторы
+// и сортирует. Возвращает ErrInvalidTag, если метка пуста или длиннее MaxTagLength
+// либо меток больше MaxMerchTags.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > MaxMerchTags {
+		return nil, ErrInvalidTag
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// SetMerchTags заменяет метки товара и фиксирует старые и новые метки в журнале каталога.
+func SetMerchTags(db *gorm.DB, actor string, merchID uint, tags []string) ([]string, error) {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var merch models.Merch
+		if err := findMerch(tx, merchID, false, &merch); err != nil {
+			return err
+		}
+		current, err := MerchTags(tx, []uint{merchID})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("merch_id = ?", merchID).Delete(&models.MerchTag{}).Error; err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if err := tx.Create(&models.MerchTag{MerchID: merchID, Tag: tag}).Error; err != nil {
+				return err
+			}
+		}
+
+		before, _ := json.Marshal(tagsSnapshot{Tags: current[merchID]})
+		after, _ := json.Marshal(tagsSnapshot{Tags: tags})
+		return tx.Create(&models.CatalogChange{
+			MerchID:   merchID,
+			Action:    models.CatalogActionTag,
+			OldValue:  string(before),
+			NewValue:  string(after),
+			ChangedBy: actor,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// MerchTags возвращает метки перечисленных товаров в алфавитном порядке, сгруппированные по товару.
+func MerchTags(db *gorm.DB, merchIDs []uint) (map[uint][]string, error) {
+	byMerch := map[uint][]string{}
+	if len(merchIDs) == 0 {
+		return byMerch, nil
+	}
+	var tags []models.MerchTag
+	if err := db.Where("merch_id IN ?", merchIDs).Order("tag").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		byMerch[tag.MerchID] = append(byMerch[tag.MerchID], tag.Tag)
+	}
+	return byMerch, nil
+}
+
+// ensureCategoryNameFree проверяет, что у родительской категории нет другой вложенной
+// категории с тем же названием. Для категорий верхнего уровня уникальный индекс не
+// срабатывает (NULL не равен NULL), поэтому проверка выполняется явно.
+func ensureCategoryNameFree(tx *gorm.DB, name string, parentID *uint, exceptID uint) error {
+	query := tx.Model(&models.Category{}).Where("name = ? AND id <> ?", name, exceptID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryNameTaken
+	}
+	return nil
+}
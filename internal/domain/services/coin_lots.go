@@ -0,0 +1,368 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Политики срока действия начисляемых монет.
+const (
+	// CoinExpiryNone — монеты не сгорают.
+	CoinExpiryNone = "none"
+	// CoinExpiryPeriod — монеты сгорают через COIN_EXPIRY_PERIOD после начисления.
+	CoinExpiryPeriod = "period"
+	// CoinExpiryEndOfYear — монеты сгорают в конце года начисления (UTC).
+	CoinExpiryEndOfYear = "end_of_year"
+)
+
+// CoinExpiry возвращает срок действия монет, начисленных в момент now, по политике
+// COIN_EXPIRY_POLICY. Для политики none и неизвестной политики возвращается nil.
+func CoinExpiry(now time.Time) *time.Time {
+	var expiresAt time.Time
+	switch policy := config.GetString("COIN_EXPIRY_POLICY", CoinExpiryNone); policy {
+	case CoinExpiryNone:
+		return nil
+	case CoinExpiryPeriod:
+		expiresAt = now.Add(config.GetDuration("COIN_EXPIRY_PERIOD", 365*24*time.Hour))
+	case CoinExpiryEndOfYear:
+		expiresAt = time.Date(now.UTC().Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		log.Printf("Неизвестная политика срока действия монет %q, монеты не сгорают", policy)
+		return nil
+	}
+	return &expiresAt
+}
+
+// CoinExpiryNotice возвращает, за сколько до сгорания монеты показываются в /api/info.
+func CoinExpiryNotice() time.Duration {
+	return config.GetDuration("COIN_EXPIRY_NOTICE", 30*24*time.Hour)
+}
+
+// ExpiringCoins — монеты пользователя, которые сгорят в момент ExpiresAt.
+type ExpiringCoins struct {
+	Amount    int       `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// UpcomingExpirations возвращает монеты пользователя, которые сгорят в течение
+// CoinExpiryNotice после now, сгруппированные по сроку действия.
+func UpcomingExpirations(db *gorm.DB, userID uint, now time.Time) ([]ExpiringCoins, error) {
+	var lots []models.CoinLot
+	err := db.Where("user_id = ? AND remaining > 0 AND expires_at <= ?", userID, now.Add(CoinExpiryNotice())).
+		Order("expires_at").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := []ExpiringCoins{}
+	for _, lot := range lots {
+		if n := len(upcoming); n > 0 && upcoming[n-1].ExpiresAt.Equal(*lot.ExpiresAt) {
+			upcoming[n-1].Amount += lot.Remaining
+			continue
+		}
+		upcoming = append(upcoming, ExpiringCoins{Amount: lot.Remaining, ExpiresAt: *lot.ExpiresAt})
+	}
+	return upcoming, nil
+}
+
+// lotSlice — часть партии монет: сколько монет и до какого срока они действуют.
+type lotSlice struct {
+	Amount    int
+	ExpiresAt *time.Time
+}
+
+// spendLots списывает amount монет с партий пользователя, начиная с тех, что сгорят
+// раньше всех, и возвращает списанные части. Монеты, не покрытые партиями (остатки,
+// появившиеся до введения партий), считаются бессрочными и списываются последними.
+func spendLots(tx *gorm.DB, userID uint, amount int) ([]lotSlice, error) {
+	var lots []models.CoinLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining > 0", userID).
+		Order("expires_at IS NULL, expires_at, id").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var slices []lotSlice
+	rest := amount
+	for _, lot := range lots {
+		if rest == 0 {
+			break
+		}
+		take := min(lot.Remaining, rest)
+		err := tx.Model(&models.CoinLot{}).
+			Where("id = ?", lot.ID).
+			Update("remaining", gorm.Expr("remaining - ?", take)).Error
+		if err != nil {
+			return nil, err
+		}
+		slices = append(slices, lotSlice{Amount: take, ExpiresAt: lot.ExpiresAt})
+		rest -= take
+	}
+	if rest > 0 {
+		slices = append(slices, lotSlice{Amount: rest})
+	}
+	return slices, nil
+}
+
+// addLots создаёт пользователю партии монет из переданных частей.
+func addLots(tx *gorm.DB, userID uint, slices []lotSlice) error {
+	lots := make([]models.CoinLot, 0, len(slices))
+	for _, slice := range slices {
+		if slice.Amount > 0 {
+			lots = append(lots, models.CoinLot{
+				UserID:    userID,
+				Amount:    slice.Amount,
+				Remaining: slice.Amount,
+				ExpiresAt: slice.ExpiresAt,
+			})
+		}
+	}
+	if len(lots) == 0 {
+		return nil
+	}
+	return tx.Create(&lots).Error
+}
+
+// skipLots отбрасывает первые amount монет из частей (например, ушедшие на погашение долга).
+func skipLots(slices []lotSlice, amount int) []lotSlice {
+	rest := make([]lotSlice, 0, len(slices))
+	for _, slice := range slices {
+		skip := min(slice.Amount, amount)
+		amount -= skip
+		if slice.Amount > skip {
+			rest = append(rest, lotSlice{Amount: slice.Amount - skip, ExpiresAt: slice.ExpiresAt})
+		}
+	}
+	return rest
+}
+
+// holdLots сохраняет части партий, списанные с отправителя незавершённого перевода,
+// чтобы при завершении перевода монеты сохранили срок действия.
+func holdLots(tx *gorm.DB, transactionID uint, slices []lotSlice) error {
+	lots := make([]models.HeldCoinLot, 0, len(slices))
+	for _, slice := range slices {
+		if slice.Amount > 0 {
+			lots = append(lots, models.HeldCoinLot{
+				TransactionID: transactionID,
+				Amount:        slice.Amount,
+				Remaining:     slice.Amount,
+				ExpiresAt:     slice.ExpiresAt,
+			})
+		}
+	}
+	if len(lots) == 0 {
+		return nil
+	}
+	return tx.Create(&lots).Error
+}
+
+// releaseHeldLots забирает удерживаемые части партий перевода и возвращает их для
+// зачисления получателю или отправителю. Сгоревшие за время удержания монеты в них
+// не попадают. Для переводов, удержанных до появления HeldCoinLot, возвращается вся
+// сумма amount со сроком действия по текущей политике.
+func releaseHeldLots(tx *gorm.DB, transactionID uint, amount int) ([]lotSlice, error) {
+	var lots []models.HeldCoinLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("transaction_id = ?", transactionID).
+		Order("id").
+		Find(&lots).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(lots) == 0 {
+		return []lotSlice{{Amount: amount, ExpiresAt: CoinExpiry(time.Now())}}, nil
+	}
+
+	slices := make([]lotSlice, 0, len(lots))
+	for _, lot := range lots {
+		if lot.Remaining > 0 {
+			slices = append(slices, lotSlice{Amount: lot.Remaining, ExpiresAt: lot.ExpiresAt})
+		}
+	}
+	err = tx.Model(&models.HeldCoinLot{}).
+		Where("transaction_id = ? AND remaining > 0", transactionID).
+		Update("remaining", 0).Error
+	if err != nil {
+		return nil, err
+	}
+	return slices, nil
+}
+
+// ExpireCoinLots списывает остатки партий, срок действия которых истёк к моменту now,
+// и отражает сгорание в журнале записью expiry по каждому пользователю. Монеты
+// незавершённых переводов, удерживаемые на системном счёте, сгорают так же — записью
+// expiry по каждому переводу. Если партии пользователя превышают его баланс, сгорает
+// не больше баланса: ровно эта сумма снимается с партий в порядке сгорания, а
+// расхождение пишется в лог. Возвращает общее число сгоревших монет. Повторный
+// запуск ничего не списывает.
+func ExpireCoinLots(db *gorm.DB, now time.Time) (int, error) {
+	var userIDs []uint
+	err := db.Model(&models.CoinLot{}).
+		Where("remaining > 0 AND expires_at <= ?", now).
+		Distinct("user_id").
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		expired := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			users, err := LockUsers(tx, userID)
+			if err != nil {
+				return err
+			}
+
+			var lots []models.CoinLot
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND remaining > 0 AND expires_at <= ?", userID, now).
+				Order("expires_at").
+				Order("id").
+				Find(&lots).Error
+			if err != nil {
+				return err
+			}
+			for _, lot := range lots {
+				expired += lot.Remaining
+			}
+			// Баланс не может стать отрицательным, даже если партии разошлись с ним: списываем
+			// не больше баланса, а остальное оставляем в партиях и сообщаем о расхождении
+			if coins := users[userID].Coins; expired > coins {
+				log.Printf("Партии пользователя #%d превышают баланс: не списано %d сгоревших монет", userID, expired-coins)
+				expired = coins
+			}
+			if expired == 0 {
+				return nil
+			}
+
+			left := expired
+			for _, lot := range lots {
+				if left == 0 {
+					break
+				}
+				take := min(lot.Remaining, left)
+				updates := map[string]interface{}{"remaining": lot.Remaining - take}
+				if take == lot.Remaining {
+					updates["expired_at"] = now
+				}
+				if err := tx.Model(&models.CoinLot{}).Where("id = ?", lot.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				left -= take
+			}
+			err = tx.Model(&models.User{}).
+				Where("id = ?", userID).
+				Update("coins", gorm.Expr("coins - ?", expired)).Error
+			if err != nil {
+				return err
+			}
+			return PostEntry(tx, &models.LedgerEntry{
+				Kind:        models.LedgerKindExpiry,
+				Description: fmt.Sprintf("Сгорание монет на %s", now.UTC().Format(time.DateOnly)),
+				Postings: []models.LedgerPosting{
+					UserPosting(userID, -expired),
+					SystemPosting(models.LedgerAccountExpired, expired),
+				},
+			})
+		})
+		if err != nil {
+			return total, err
+		}
+		total += expired
+	}
+
+	held, err := expireHeldLots(db, now)
+	return total + held, err
+}
+
+// expireHeldLots списывает удерживаемые части партий незавершённых переводов, срок
+// действия которых истёк к моменту now, и возвращает число сгоревших монет.
+func expireHeldLots(db *gorm.DB, now time.Time) (int, error) {
+	var transactionIDs []uint
+	err := db.Model(&models.HeldCoinLot{}).
+		Where("remaining > 0 AND expires_at <= ?", now).
+		Distinct("transaction_id").
+		Pluck("transaction_id", &transactionIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, transactionID := range transactionIDs {
+		expired := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var lots []models.HeldCoinLot
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("transaction_id = ? AND remaining > 0 AND expires_at <= ?", transactionID, now).
+				Find(&lots).Error
+			if err != nil {
+				return err
+			}
+			ids := make([]uint, 0, len(lots))
+			for _, lot := range lots {
+				expired += lot.Remaining
+				ids = append(ids, lot.ID)
+			}
+			if expired == 0 {
+				return nil
+			}
+
+			err = tx.Model(&models.HeldCoinLot{}).
+				Where("id IN ?", ids).
+				Updates(map[string]interface{}{"remaining": 0, "expired_at": now}).Error
+			if err != nil {
+				return err
+			}
+			return PostEntry(tx, &models.LedgerEntry{
+				Kind:          models.LedgerKindExpiry,
+				TransactionID: &transactionID,
+				Description:   fmt.Sprintf("Сгорание удерживаемых монет перевода на %s", now.UTC().Format(time.DateOnly)),
+				Postings: []models.LedgerPosting{
+					SystemPosting(models.LedgerAccountHeld, -expired),
+					SystemPosting(models.LedgerAccountExpired, expired),
+				},
+			})
+		})
+		if err != nil {
+			return total, err
+		}
+		total += expired
+	}
+	return total, nil
+}
+
+// OpenCoinLots создаёт партии для монет, которые не покрыты партиями (например, остатки
+// пользователей, созданных до их появления). Срок действия назначается по текущей политике.
+func OpenCoinLots(db *gorm.DB) error {
+	var uncovered []struct {
+		ID      uint
+		Missing int
+	}
+	err := db.Model(&models.User{}).
+		Select("users.id, users.coins - COALESCE(SUM(coin_lots.remaining), 0) AS missing").
+		Joins("LEFT JOIN coin_lots ON coin_lots.user_id = users.id AND coin_lots.deleted_at IS NULL").
+		Group("users.id, users.coins").
+		Having("users.coins > COALESCE(SUM(coin_lots.remaining), 0)").
+		Scan(&uncovered).Error
+	if err != nil {
+		return err
+	}
+
+	expiresAt := CoinExpiry(time.Now())
+	for _, user := range uncovered {
+		if err := addLots(db, user.ID, []lotSlice{{Amount: user.Missing, ExpiresAt: expiresAt}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCoinRequestNotFound возвращается, если запрос монет не найден или недоступен пользователю.
+	ErrCoinRequestNotFound = errors.New("запрос монет не найден")
+	// ErrCoinRequestNotPending возвращается при попытке ответить на уже закрытый запрос.
+	ErrCoinRequestNotPending = errors.New("запрос монет уже закрыт")
+	// ErrCoinRequestExpired возвращается при попытке ответить на истёкший запрос.
+	ErrCoinRequestExpired = errors.New("срок запроса монет истёк")
+)
+
+// CoinRequestTTL возвращает срок, в течение которого на запрос монет можно ответить.
+func CoinRequestTTL() time.Duration {
+	return config.GetDuration("COIN_REQUEST_TTL", 7*24*time.Hour)
+}
+
+// CreateCoinRequest создаёт запрос монет от requesterID к payerID.
+func CreateCoinRequest(db *gorm.DB, requesterID, payerID uint, amount int, note string) (*models.CoinRequest, error) {
+	request := models.CoinRequest{
+		FromUserID: requesterID,
+		ToUserID:   payerID,
+		Amount:     amount,
+		Note:       note,
+		Status:     models.CoinRequestPending,
+		ExpiresAt:  time.Now().Add(CoinRequestTTL()),
+	}
+	if err := db.Create(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ExpireCoinRequests переводит просроченные незакрытые запросы в статус expired.
+func ExpireCoinRequests(db *gorm.DB) error {
+	return db.Model(&models.CoinRequest{}).
+		Where("status = ? AND expires_at < ?", models.CoinRequestPending, time.Now()).
+		Update("status", models.CoinRequestExpired).Error
+}
+
+// AcceptCoinRequest оплачивает запрос монет: переводит сумму запроса от payerID тому,
+// кто её запросил, тем же атомарным переводом, что и SendCoin. Комментарий запроса
+// становится комментарием перевода. Фиксация транзакции остаётся на вызывающей стороне.
+func AcceptCoinRequest(tx *gorm.DB, payerID, id uint) (*models.CoinRequest, *models.Transaction, error) {
+	request, err := closeCoinRequest(tx, id, "to_user_id", payerID, models.CoinRequestAccepted)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transaction, err := Transfer(tx, payerID, request.FromUserID, request.Amount, request.Note)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Model(request).Update("transaction_id", transaction.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	request.TransactionID = &transaction.ID
+	return request, transaction, nil
+}
+
+// DeclineCoinRequest отклоняет запрос монет от имени того, у кого их просят.
+func DeclineCoinRequest(db *gorm.DB, payerID, id uint) (*models.CoinRequest, error) {
+	return closeCoinRequest(db, id, "to_user_id", payerID, models.CoinRequestDeclined)
+}
+
+// CancelCoinRequest отменяет запрос монет от имени того, кто их просит.
+func CancelCoinRequest(db *gorm.DB, requesterID, id uint) (*models.CoinRequest, error) {
+	return closeCoinRequest(db, id, "from_user_id", requesterID, models.CoinRequestCancelled)
+}
+
+// closeCoinRequest переводит незакрытый запрос, в котором пользователь userID занимает
+// сторону column, в статус status. Условное обновление не даёт закрыть запрос дважды.
+func closeCoinRequest(tx *gorm.DB, id uint, column string, userID uint, status string) (*models.CoinRequest, error) {
+	var request models.CoinRequest
+	if err := tx.Where("id = ? AND "+column+" = ?", id, userID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCoinRequestNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	res := tx.Model(&models.CoinRequest{}).
+		Where("id = ? AND status = ? AND expires_at >= ?", request.ID, models.CoinRequestPending, now).
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if request.Status == models.CoinRequestExpired ||
+			(request.Status == models.CoinRequestPending && now.After(request.ExpiresAt)) {
+			return nil, ErrCoinRequestExpired
+		}
+		return nil, ErrCoinRequestNotPending
+	}
+	request.Status = status
+	request.RespondedAt = &now
+	return &request, nil
+}
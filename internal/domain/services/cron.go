@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSchedule возвращается для cron-выражения, которое не удалось разобрать.
+var ErrInvalidSchedule = errors.New("неверное расписание")
+
+// Сокращения для часто используемых расписаний.
+var scheduleMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// Schedule — разобранное cron-выражение из пяти полей: минута, час, день месяца,
+// месяц и день недели. Время вычисляется в UTC.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// Как в cron, если заданы и день месяца, и день недели, подходит любой из них
+	domAny, dowAny bool
+}
+
+// scheduleField описывает допустимый диапазон поля cron-выражения.
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+var scheduleFields = [5]scheduleField{
+	{"минута", 0, 59},
+	{"час", 0, 23},
+	{"день месяца", 1, 31},
+	{"месяц", 1, 12},
+	{"день недели", 0, 7},
+}
+
+// ParseSchedule разбирает cron-выражение вида "0 9 1 * *". Поддерживаются
+// значения, списки через запятую, диапазоны, шаги (*/15, 1-5/2) и сокращения
+// @hourly, @daily, @weekly, @monthly, @yearly.
+func ParseSchedule(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := scheduleMacros[expr]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(scheduleFields) {
+		return nil, fmt.Errorf("%w: ожидается 5 полей", ErrInvalidSchedule)
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		var err error
+		if bits[i], err = parseScheduleField(part, scheduleFields[i]); err != nil {
+			return nil, err
+		}
+	}
+	// Воскресенье можно задать как 0 или 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseScheduleField(value string, field scheduleField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("%w: неверный шаг в поле «%s»", ErrInvalidSchedule, field.name)
+			}
+			rng = item[:i]
+		}
+
+		from, to := field.min, field.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("%w: неверное значение в поле «%s»", ErrInvalidSchedule, field.name)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("%w: неверное значение в поле «%s»", ErrInvalidSchedule, field.name)
+				}
+			} else if step > 1 {
+				// "5/15" означает «начиная с 5 каждые 15»
+				to = field.max
+			}
+		}
+		if from < field.min || to > field.max || from > to {
+			return 0, fmt.Errorf("%w: поле «%s» должно быть от %d до %d", ErrInvalidSchedule, field.name, field.min, field.max)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next возвращает ближайший момент по расписанию строго после after.
+// Если такого момента нет в ближайшие пять лет (например, 30 февраля), возвращается нулевое время.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEscrowNotFound возвращается, если перевод с удержанием не найден или недоступен пользователю.
+	ErrEscrowNotFound = errors.New("перевод с удержанием не найден")
+	// ErrEscrowNotPending возвращается, если перевод с удержанием уже завершён.
+	ErrEscrowNotPending = errors.New("перевод с удержанием уже завершён")
+	// ErrInvalidEscrowTimeout возвращается, если срок подтверждения не положителен или больше ESCROW_MAX_TIMEOUT.
+	ErrInvalidEscrowTimeout = errors.New("недопустимый срок подтверждения")
+)
+
+// EscrowTimeout возвращает срок подтверждения перевода с удержанием по умолчанию.
+func EscrowTimeout() time.Duration {
+	return config.GetDuration("ESCROW_TIMEOUT", 72*time.Hour)
+}
+
+// EscrowMaxTimeout возвращает наибольший допустимый срок подтверждения.
+func EscrowMaxTimeout() time.Duration {
+	return config.GetDuration("ESCROW_MAX_TIMEOUT", 30*24*time.Hour)
+}
+
+// CreateEscrow создаёт перевод с удержанием в рамках переданной транзакции БД: монеты
+// списываются с отправителя, но зачисляются получателю только после подтверждения
+// отправителем. Если перевод не подтверждён за timeout (0 — EscrowTimeout), монеты
+// возвращаются отправителю. В обоих случаях монеты сохраняют исходный срок действия.
+// Лимиты и правила обнаружения мошенничества применяются так же, как к обычному переводу.
+func CreateEscrow(tx *gorm.DB, fromUserID, toUserID uint, amount int, message string, timeout time.Duration) (*models.Transaction, error) {
+	if timeout == 0 {
+		timeout = EscrowTimeout()
+	}
+	if timeout < 0 || timeout > EscrowMaxTimeout() {
+		return nil, ErrInvalidEscrowTimeout
+	}
+	expiresAt := time.Now().Add(timeout)
+	return transfer(tx, fromUserID, toUserID, amount, message, &expiresAt)
+}
+
+// ConfirmEscrow подтверждает перевод с удержанием: монеты зачисляются получателю.
+// Подтвердить перевод может только отправитель. Если перевод помечен правилами
+// обнаружения мошенничества и включён FRAUD_HOLD, он задерживается до проверки.
+func ConfirmEscrow(db *gorm.DB, senderID, id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findEscrow(tx, id, "from_user_id", senderID, &transaction); err != nil {
+			return err
+		}
+
+		if FraudHoldEnabled() {
+			var flagged int64
+			err := tx.Model(&models.FraudFlag{}).
+				Where("transaction_id = ? AND status = ?", id, models.FraudFlagOpen).
+				Count(&flagged).Error
+			if err != nil {
+				return err
+			}
+			if flagged > 0 {
+				transaction.Status = models.TransactionHeld
+				return moveTransferStatus(tx, id, models.TransactionPending, models.TransactionHeld)
+			}
+		}
+
+		transaction.Status = models.TransactionSettled
+		ok, err := completeTransfer(tx, id, models.TransactionPending, models.TransactionSettled)
+		if err == nil && !ok {
+			err = ErrEscrowNotPending
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ReleaseEscrow отказывается от перевода с удержанием: монеты возвращаются отправителю.
+// Отказаться от перевода может только получатель.
+func ReleaseEscrow(db *gorm.DB, recipientID, id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findEscrow(tx, id, "to_user_id", recipientID, &transaction); err != nil {
+			return err
+		}
+		transaction.Status = models.TransactionReleased
+		ok, err := completeTransfer(tx, id, models.TransactionPending, models.TransactionReleased)
+		if err == nil && !ok {
+			err = ErrEscrowNotPending
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ReleaseExpiredEscrows возвращает отправителям монеты переводов с удержанием,
+// не подтверждённых до истечения срока, и возвращает число таких переводов.
+// Каждый перевод завершается в отдельной транзакции.
+func ReleaseExpiredEscrows(db *gorm.DB, now time.Time) (int, error) {
+	var ids []uint
+	err := db.Model(&models.Transaction{}).
+		Where("status = ? AND expires_at <= ?", models.TransactionPending, now).
+		Order("id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, id := range ids {
+		var ok bool
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			ok, err = completeTransfer(tx, id, models.TransactionPending, models.TransactionReleased)
+			return err
+		})
+		if err != nil {
+			return released, err
+		}
+		if ok {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// findEscrow загружает перевод с удержанием, в котором пользователь userID указан в column.
+func findEscrow(tx *gorm.DB, id uint, column string, userID uint, transaction *models.Transaction) error {
+	err := tx.Where("id = ? AND "+column+" = ? AND expires_at IS NOT NULL", id, userID).First(transaction).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrEscrowNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if transaction.Status != models.TransactionPending {
+		return ErrEscrowNotPending
+	}
+	return nil
+}
+
+// moveTransferStatus условно меняет статус перевода с from на to. Если статус уже
+// изменён параллельным запросом, возвращается ErrEscrowNotPending.
+func moveTransferStatus(tx *gorm.DB, id uint, from, to string) error {
+	res := tx.Model(&models.Transaction{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrEscrowNotPending
+	}
+	return nil
+}
+
+// completeTransfer завершает перевод, монеты которого удерживаются на системном счёте:
+// при статусе settled они зачисляются получателю, при любом другом возвращаются
+// отправителю вместе с исходными сроками действия. Статус меняется условно с from на to; если перевод уже не в статусе
+// from, ничего не происходит и возвращается false.
+func completeTransfer(tx *gorm.DB, id uint, from, to string) (bool, error) {
+	var transaction models.Transaction
+	if err := tx.First(&transaction, id).Error; err != nil {
+		return false, err
+	}
+	if err := moveTransferStatus(tx, id, from, to); err != nil {
+		if errors.Is(err, ErrEscrowNotPending) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	userID, description := transaction.ToUserID, "зачисление удержанного перевода"
+	if to != models.TransactionSettled {
+		userID, description = transaction.FromUserID, "возврат удержанного перевода"
+	}
+	// Монеты зачисляются с исходными сроками действия; сгоревшие за время удержания
+	// уже списаны с системного счёта
+	slices, err := releaseHeldLots(tx, transaction.ID, transaction.Amount)
+	if err != nil {
+		return false, err
+	}
+	amount := 0
+	for _, slice := range slices {
+		amount += slice.Amount
+	}
+	if amount == 0 {
+		return true, nil
+	}
+	if err := credit(tx, userID, slices); err != nil {
+		return false, err
+	}
+	err = PostEntry(tx, &models.LedgerEntry{
+		Kind:          models.LedgerKindRelease,
+		TransactionID: &transaction.ID,
+		Description:   description,
+		Postings: []models.LedgerPosting{
+			SystemPosting(models.LedgerAccountHeld, -amount),
+			UserPosting(userID, amount),
+		},
+	})
+	return err == nil, err
+}
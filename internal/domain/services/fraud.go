@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// Правила обнаружения мошенничества.
+const (
+	// FraudRuleNewAccountFunnel — много новых аккаунтов переводят монеты одному получателю.
+	FraudRuleNewAccountFunnel = "new_account_funnel"
+	// FraudRuleCircular — монеты возвращаются отправителю через других пользователей.
+	FraudRuleCircular = "circular_transfer"
+)
+
+var (
+	// ErrFraudFlagNotFound возвращается, если помеченный перевод не найден в очереди проверки.
+	ErrFraudFlagNotFound = errors.New("помеченный перевод не найден")
+	// ErrFraudFlagReviewed возвращается при повторном рассмотрении помеченного перевода.
+	ErrFraudFlagReviewed = errors.New("помеченный перевод уже рассмотрен")
+)
+
+// FraudRule — правило обнаружения мошенничества. Check вызывается для каждого перевода
+// до его записи в БД и возвращает пояснение, если перевод подозрителен, или пустую строку.
+type FraudRule interface {
+	Name() string
+	Check(tx *gorm.DB, transaction *models.Transaction, now time.Time) (string, error)
+}
+
+// fraudRules — правила, которые применяются к каждому переводу.
+var fraudRules = []FraudRule{
+	newAccountFunnelRule{},
+	circularTransferRule{},
+}
+
+// fraudFinding — сработавшее правило и его пояснение.
+type fraudFinding struct {
+	Rule    string
+	Details string
+}
+
+// FraudWindow возвращает период, за который правила учитывают переводы.
+func FraudWindow() time.Duration {
+	return config.GetDuration("FRAUD_WINDOW", 24*time.Hour)
+}
+
+// FraudHoldEnabled сообщает, задерживаются ли помеченные переводы до проверки администратором.
+func FraudHoldEnabled() bool {
+	return config.GetBool("FRAUD_HOLD", false)
+}
+
+// evaluateFraudRules применяет к переводу все правила и возвращает сработавшие.
+func evaluateFraudRules(tx *gorm.DB, transaction *models.Transaction) ([]fraudFinding, error) {
+	now := time.Now()
+	var findings []fraudFinding
+	for _, rule := range fraudRules {
+		details, err := rule.Check(tx, transaction, now)
+		if err != nil {
+			return nil, err
+		}
+		if details != "" {
+			findings = append(findings, fraudFinding{Rule: rule.Name(), Details: details})
+		}
+	}
+	return findings, nil
+}
+
+// flagTransaction помещает перевод в очередь проверки.
+func flagTransaction(tx *gorm.DB, transactionID uint, findings []fraudFinding) error {
+	rules := make([]string, 0, len(findings))
+	details := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule)
+		details = append(details, finding.Details)
+	}
+	return tx.Create(&models.FraudFlag{
+		TransactionID: transactionID,
+		Rules:         strings.Join(rules, ","),
+		Details:       strings.Join(details, "; "),
+		Status:        models.FraudFlagOpen,
+	}).Error
+}
+
+// newAccountFunnelRule срабатывает, если за FRAUD_WINDOW получателю перевели монеты
+// не меньше FRAUD_NEW_ACCOUNT_SENDERS разных аккаунтов моложе FRAUD_NEW_ACCOUNT_AGE,
+// включая текущего отправителя. Так выглядит сбор стартовых монет с новых аккаунтов.
+// Отклонённые и возвращённые отправителям переводы не учитываются.
+type newAccountFunnelRule struct{}
+
+func (newAccountFunnelRule) Name() string {
+	return FraudRuleNewAccountFunnel
+}
+
+func (newAccountFunnelRule) Check(tx *gorm.DB, transaction *models.Transaction, now time.Time) (string, error) {
+	threshold := config.GetInt("FRAUD_NEW_ACCOUNT_SENDERS", 3)
+	if threshold <= 0 {
+		return "", nil
+	}
+	age := config.GetDuration("FRAUD_NEW_ACCOUNT_AGE", 72*time.Hour)
+	bornAfter := now.Add(-age)
+
+	var sender models.User
+	if err := tx.Select("created_at").First(&sender, transaction.FromUserID).Error; err != nil {
+		return "", err
+	}
+	if !sender.CreatedAt.After(bornAfter) {
+		return "", nil
+	}
+
+	var others int64
+	err := tx.Model(&models.Transaction{}).
+		Joins("JOIN users senders ON senders.id = transactions.from_user_id").
+		Where("transactions.to_user_id = ? AND transactions.from_user_id <> ?", transaction.ToUserID, transaction.FromUserID).
+		Where("transactions.created_at > ? AND transactions.reversal_of IS NULL", now.Add(-FraudWindow())).
+		Where("transactions.status NOT IN ?", voidedTransferStatuses).
+		Where("senders.created_at > ?", bornAfter).
+		Distinct("transactions.from_user_id").
+		Count(&others).Error
+	if err != nil {
+		return "", err
+	}
+	if senders := int(others) + 1; senders >= threshold {
+		return fmt.Sprintf("получателю переводят монеты %d новых аккаунтов за %s", senders, FraudWindow()), nil
+	}
+	return "", nil
+}
+
+// circularTransferRule срабатывает, если за FRAUD_WINDOW монеты от получателя уже дошли
+// до отправителя через других пользователей, то есть перевод замыкает круг. Длина круга
+// (число переводов в нём) ограничена FRAUD_CYCLE_DEPTH; прямой возврат долга (A→B, B→A)
+// кругом не считается.
+type circularTransferRule struct{}
+
+func (circularTransferRule) Name() string {
+	return FraudRuleCircular
+}
+
+func (circularTransferRule) Check(tx *gorm.DB, transaction *models.Transaction, now time.Time) (string, error) {
+	depth := config.GetInt("FRAUD_CYCLE_DEPTH", 4)
+	since := now.Add(-FraudWindow())
+
+	visited := map[uint]bool{transaction.ToUserID: true}
+	frontier := []uint{transaction.ToUserID}
+	// Текущий перевод — первое звено круга; ищем путь от получателя к отправителю
+	// длиной от двух переводов до depth-1
+	for steps := 1; steps < depth && len(frontier) > 0; steps++ {
+		var next []uint
+		err := tx.Model(&models.Transaction{}).
+			Where("from_user_id IN ? AND created_at > ? AND reversal_of IS NULL AND status NOT IN ?",
+				frontier, since, voidedTransferStatuses).
+			Distinct().
+			Pluck("to_user_id", &next).Error
+		if err != nil {
+			return "", err
+		}
+
+		frontier = frontier[:0]
+		for _, id := range next {
+			if id == transaction.FromUserID {
+				if steps > 1 {
+					return fmt.Sprintf("перевод замыкает круг из %d переводов за %s", steps+1, FraudWindow()), nil
+				}
+				continue
+			}
+			if !visited[id] {
+				visited[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return "", nil
+}
+
+// ApproveFraudFlag признаёт помеченный перевод законным. Задержанный перевод
+// зачисляется получателю.
+func ApproveFraudFlag(db *gorm.DB, actor string, id uint, comment string) (*models.FraudFlag, error) {
+	return reviewFraudFlag(db, actor, id, comment, models.FraudFlagApproved)
+}
+
+// RejectFraudFlag признаёт помеченный перевод мошенническим. Задержанный перевод
+// и неподтверждённый перевод с удержанием отклоняются, монеты возвращаются отправителю.
+// Уже зачисленный перевод можно отменить отдельно через ReverseTransfer.
+func RejectFraudFlag(db *gorm.DB, actor string, id uint, comment string) (*models.FraudFlag, error) {
+	return reviewFraudFlag(db, actor, id, comment, models.FraudFlagRejected)
+}
+
+func reviewFraudFlag(db *gorm.DB, actor string, id uint, comment, status string) (*models.FraudFlag, error) {
+	var flag models.FraudFlag
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&flag, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFraudFlagNotFound
+			}
+			return err
+		}
+
+		// Условное обновление не даёт рассмотреть перевод дважды при параллельных запросах
+		now := time.Now()
+		res := tx.Model(&models.FraudFlag{}).
+			Where("id = ? AND status = ?", id, models.FraudFlagOpen).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"reviewed_by": actor,
+				"reviewed_at": now,
+				"comment":     comment,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrFraudFlagReviewed
+		}
+		flag.Status, flag.ReviewedBy, flag.ReviewedAt, flag.Comment = status, actor, &now, comment
+
+		// Задержанный перевод зачисляется получателю или возвращается отправителю;
+		// для зачисленного перевода ничего не меняется
+		if status == models.FraudFlagApproved {
+			_, err := completeTransfer(tx, flag.TransactionID, models.TransactionHeld, models.TransactionSettled)
+			return err
+		}
+		// Неподтверждённый перевод с удержанием при отклонении тоже возвращается отправителю
+		for _, from := range []string{models.TransactionHeld, models.TransactionPending} {
+			if _, err := completeTransfer(tx, flag.TransactionID, from, models.TransactionRejected); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
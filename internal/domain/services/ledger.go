@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// ErrUnbalancedEntry возвращается при попытке записать запись журнала, сумма проводок которой не равна нулю.
+var ErrUnbalancedEntry = errors.New("сумма проводок записи журнала не равна нулю")
+
+// UserPosting возвращает проводку по счёту пользователя.
+func UserPosting(userID uint, amount int) models.LedgerPosting {
+	return models.LedgerPosting{Account: models.LedgerAccountUser, UserID: &userID, Amount: amount}
+}
+
+// SystemPosting возвращает проводку по системному счёту.
+func SystemPosting(account string, amount int) models.LedgerPosting {
+	return models.LedgerPosting{Account: account, Amount: amount}
+}
+
+// PostEntry проверяет, что запись сбалансирована, и сохраняет её вместе с проводками.
+// Кэшированный баланс пользователей (User.Coins) вызывающая сторона обновляет сама
+// в той же транзакции БД.
+func PostEntry(tx *gorm.DB, entry *models.LedgerEntry) error {
+	if len(entry.Postings) < 2 {
+		return ErrUnbalancedEntry
+	}
+	sum := 0
+	for _, posting := range entry.Postings {
+		sum += posting.Amount
+	}
+	if sum != 0 {
+		return ErrUnbalancedEntry
+	}
+	return tx.Create(entry).Error
+}
+
+// LedgerBalance возвращает баланс пользователя, посчитанный по проводкам журнала.
+func LedgerBalance(db *gorm.DB, userID uint) (int, error) {
+	var balance int
+	err := db.Model(&models.LedgerPosting{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("account = ? AND user_id = ?", models.LedgerAccountUser, userID).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// BalanceMismatch описывает пользователя, у которого кэшированный баланс расходится с журналом.
+type BalanceMismatch struct {
+	UserID        uint   `json:"userId"`
+	Username      string `json:"username"`
+	Coins         int    `json:"coins"`
+	Debt          int    `json:"debt,omitempty"`
+	LedgerBalance int    `json:"ledgerBalance"`
+}
+
+// Reconcile сверяет кэшированный баланс каждого пользователя с суммой его проводок
+// и возвращает всех пользователей, у которых они различаются.
+// Долг пользователя уменьшает его баланс: по журналу такой баланс отрицателен.
+func Reconcile(db *gorm.DB) ([]BalanceMismatch, error) {
+	var mismatches []BalanceMismatch
+	err := db.Table("users").
+		Select("users.id AS user_id, users.username, users.coins, users.debt, COALESCE(SUM(ledger_postings.amount), 0) AS ledger_balance").
+		Joins("LEFT JOIN ledger_postings ON ledger_postings.user_id = users.id AND ledger_postings.account = ? AND ledger_postings.deleted_at IS NULL", models.LedgerAccountUser).
+		Where("users.deleted_at IS NULL").
+		Group("users.id, users.username, users.coins, users.debt").
+		Having("users.coins - users.debt <> COALESCE(SUM(ledger_postings.amount), 0)").
+		Order("users.id").
+		Scan(&mismatches).Error
+	return mismatches, err
+}
+
+// OpenLedgerBalances переносит в журнал остатки пользователей, созданных до его появления:
+// для каждого пользователя без проводок записывается начисление на сумму текущего баланса.
+func OpenLedgerBalances(db *gorm.DB) error {
+	var users []models.User
+	err := db.Where("coins > 0 AND NOT EXISTS (?)",
+		db.Model(&models.LedgerPosting{}).Select("1").Where("ledger_postings.user_id = users.id"),
+	).Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return PostEntry(tx, &models.LedgerEntry{
+				Kind:        models.LedgerKindGrant,
+				Description: "Начальный остаток",
+				Postings: []models.LedgerPosting{
+					SystemPosting(models.LedgerAccountIssuance, -user.Coins),
+					UserPosting(user.ID, user.Coins),
+				},
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
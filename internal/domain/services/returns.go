@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPurchaseNotFound возвращается, если покупка не найдена или принадлежит другому пользователю.
+	ErrPurchaseNotFound = errors.New("покупка не найдена")
+	// ErrReturnWindowExpired возвращается, если срок возврата покупки истёк.
+	ErrReturnWindowExpired = errors.New("срок возврата истёк")
+	// ErrAlreadyReturned возвращается, если покупка уже возвращена или по ней есть незакрытая заявка.
+	ErrAlreadyReturned = errors.New("покупка уже возвращена или ожидает возврата")
+	// ErrReturnNotFound возвращается, если заявка на возврат не найдена.
+	ErrReturnNotFound = errors.New("заявка на возврат не найдена")
+	// ErrReturnNotPending возвращается при рассмотрении уже рассмотренной заявки.
+	ErrReturnNotPending = errors.New("заявка на возврат уже рассмотрена")
+	// ErrGiftNotReturnable возвращается при попытке вернуть подарок: монеты за него платил другой пользователь.
+	ErrGiftNotReturnable = errors.New("подарок нельзя вернуть")
+)
+
+// ReturnWindow возвращает срок, в течение которого покупку можно вернуть.
+func ReturnWindow() time.Duration {
+	return config.GetDuration("RETURN_WINDOW", 14*24*time.Hour)
+}
+
+// RequestReturn создаёт заявку на возврат покупки пользователя.
+// По одной покупке может быть не больше одной незакрытой или одобренной заявки.
+func RequestReturn(db *gorm.DB, userID, purchaseID uint, reason string) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Блокировка пользователя упорядочивает параллельные заявки по одной покупке
+		if _, err := LockUsers(tx, userID); err != nil {
+			return err
+		}
+
+		var purchase models.Purchase
+		err := tx.Where("id = ? AND user_id = ?", purchaseID, userID).First(&purchase).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPurchaseNotFound
+			}
+			return err
+		}
+		if purchase.ReturnedAt != nil {
+			return ErrAlreadyReturned
+		}
+		if purchase.GiftFromID != nil {
+			return ErrGiftNotReturnable
+		}
+		if time.Since(purchase.CreatedAt) > ReturnWindow() {
+			return ErrReturnWindowExpired
+		}
+
+		var open int64
+		err = tx.Model(&models.ReturnRequest{}).
+			Where("purchase_id = ? AND status IN ?", purchaseID, []string{models.ReturnStatusPending, models.ReturnStatusApproved}).
+			Count(&open).Error
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrAlreadyReturned
+		}
+
+		request = models.ReturnRequest{
+			PurchaseID: purchaseID,
+			UserID:     userID,
+			Reason:     reason,
+			Status:     models.ReturnStatusPending,
+		}
+		return tx.Create(&request).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ApproveReturn одобряет заявку на возврат: зачисляет пользователю цену покупки,
+// возвращает товар на склад и помечает покупку возвращённой. Всё выполняется одной транзакцией.
+func ApproveReturn(db *gorm.DB, actor string, id uint) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findPendingReturn(tx, id, &request); err != nil {
+			return err
+		}
+		if _, err := LockUsers(tx, request.UserID); err != nil {
+			return err
+		}
+
+		var purchase models.Purchase
+		if err := tx.Preload("Merch", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).First(&purchase, request.PurchaseID).Error; err != nil {
+			return err
+		}
+		// Условное обновление гарантирует, что покупка будет возвращена только один раз
+		now := time.Now()
+		res := tx.Model(&models.Purchase{}).
+			Where("id = ? AND returned_at IS NULL", purchase.ID).
+			Update("returned_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrAlreadyReturned
+		}
+
+		amount := purchase.Price
+		if amount == 0 {
+			// Покупки, сделанные до появления поля Price, возвращаются по текущей цене
+			amount = purchase.Merch.Price
+		}
+		if err := Credit(tx, request.UserID, amount); err != nil {
+			return err
+		}
+		// Товар возвращается на склад: у товара с вариантами — в остаток варианта
+		restock := tx.Unscoped().Model(&models.Merch{}).Where("id = ? AND stock IS NOT NULL", purchase.MerchID)
+		if purchase.VariantID != nil {
+			restock = tx.Unscoped().Model(&models.MerchVariant{}).Where("id = ? AND stock IS NOT NULL", *purchase.VariantID)
+		}
+		if err := restock.Update("stock", gorm.Expr("stock + 1")).Error; err != nil {
+			return err
+		}
+		err := PostEntry(tx, &models.LedgerEntry{
+			Kind:        models.LedgerKindRefund,
+			PurchaseID:  &purchase.ID,
+			Description: "возврат товара " + purchase.Merch.Name,
+			Postings: []models.LedgerPosting{
+				SystemPosting(models.LedgerAccountShop, -amount),
+				UserPosting(request.UserID, amount),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return closeReturn(tx, &request, models.ReturnStatusApproved, actor, "", amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// RejectReturn отклоняет заявку на возврат с комментарием администратора.
+// После отклонения пользователь может подать новую заявку в пределах срока возврата.
+func RejectReturn(db *gorm.DB, actor string, id uint, comment string) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findPendingReturn(tx, id, &request); err != nil {
+			return err
+		}
+		return closeReturn(tx, &request, models.ReturnStatusRejected, actor, comment, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func findPendingReturn(tx *gorm.DB, id uint, request *models.ReturnRequest) error {
+	if err := tx.First(request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReturnNotFound
+		}
+		return err
+	}
+	if request.Status != models.ReturnStatusPending {
+		return ErrReturnNotPending
+	}
+	return nil
+}
+
+// closeReturn переводит заявку из статуса pending в итоговый статус.
+// Условие на статус защищает от повторного рассмотрения параллельным запросом.
+func closeReturn(tx *gorm.DB, request *models.ReturnRequest, status, actor, comment string, amount int) error {
+	now := time.Now()
+	res := tx.Model(&models.ReturnRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.ReturnStatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": actor,
+			"reviewed_at": now,
+			"comment":     comment,
+			"amount":      amount,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrReturnNotPending
+	}
+	request.Status = status
+	request.ReviewedBy = actor
+	request.ReviewedAt = &now
+	request.Comment = comment
+	request.Amount = amount
+	return nil
+}
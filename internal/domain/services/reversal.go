@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// Политики отмены перевода на случай, когда получатель уже потратил часть монет.
+const (
+	// ReversalPolicyPartial возвращает отправителю столько монет, сколько осталось у получателя.
+	ReversalPolicyPartial = "partial"
+	// ReversalPolicyNegative возвращает всю сумму, а недостающее записывает получателю в долг.
+	ReversalPolicyNegative = "negative"
+)
+
+var (
+	// ErrTransactionNotFound возвращается, если перевод не найден.
+	ErrTransactionNotFound = errors.New("перевод не найден")
+	// ErrAlreadyReversed возвращается при повторной отмене перевода.
+	ErrAlreadyReversed = errors.New("перевод уже отменён")
+	// ErrReversalOfReversal возвращается при попытке отменить компенсирующую транзакцию.
+	ErrReversalOfReversal = errors.New("нельзя отменить отмену перевода")
+	// ErrNothingToReverse возвращается, если по политике partial у получателя не осталось монет для возврата.
+	ErrNothingToReverse = errors.New("у получателя не осталось монет для возврата")
+	// ErrTransferNotSettled возвращается при попытке отменить перевод, не зачисленный получателю.
+	ErrTransferNotSettled = errors.New("перевод не зачислен получателю")
+	// ErrUnknownReversalPolicy возвращается для неизвестной политики отмены.
+	ErrUnknownReversalPolicy = errors.New("неизвестная политика отмены перевода")
+)
+
+// DefaultReversalPolicy возвращает политику отмены, заданную в TRANSFER_REVERSAL_POLICY.
+func DefaultReversalPolicy() string {
+	return config.GetString("TRANSFER_REVERSAL_POLICY", ReversalPolicyPartial)
+}
+
+// Reversal — результат отмены перевода.
+type Reversal struct {
+	Original models.Transaction
+	// Compensation — компенсирующая транзакция от получателя к отправителю.
+	Compensation models.Transaction
+	// Debt — сумма, записанная получателю в долг (только для политики negative).
+	Debt int
+}
+
+// ReverseTransfer отменяет перевод: создаёт компенсирующую транзакцию от получателя
+// к отправителю, связанную с исходной. Если получатель уже потратил часть монет,
+// результат определяется политикой: partial возвращает остаток, negative — всю сумму
+// с записью недостающего в долг получателю. Каждый перевод можно отменить только один раз.
+func ReverseTransfer(db *gorm.DB, actor string, id uint, policy, reason string) (*Reversal, error) {
+	if policy != ReversalPolicyPartial && policy != ReversalPolicyNegative {
+		return nil, ErrUnknownReversalPolicy
+	}
+
+	var result Reversal
+	err := db.Transaction(func(tx *gorm.DB) error {
+		original := &result.Original
+		if err := tx.First(original, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+		if original.ReversalOf != nil {
+			return ErrReversalOfReversal
+		}
+		if original.Status != models.TransactionSettled {
+			return ErrTransferNotSettled
+		}
+
+		users, err := LockUsers(tx, original.FromUserID, original.ToUserID)
+		if err != nil {
+			return err
+		}
+		// Проверка выполняется после блокировки, поэтому параллельная отмена того же перевода
+		// увидит уже созданную компенсирующую транзакцию
+		var reversed int64
+		if err := tx.Model(&models.Transaction{}).Where("reversal_of = ?", original.ID).Count(&reversed).Error; err != nil {
+			return err
+		}
+		if reversed > 0 {
+			return ErrAlreadyReversed
+		}
+
+		available := users[original.ToUserID].Coins
+		taken := min(original.Amount, available)
+		amount := taken
+		if policy == ReversalPolicyNegative {
+			amount = original.Amount
+			result.Debt = original.Amount - taken
+		}
+		if amount == 0 {
+			return ErrNothingToReverse
+		}
+
+		// Возвращённые монеты сохраняют сроки действия; сумма, записанная в долг, зачисляется заново
+		var slices []lotSlice
+		if taken > 0 {
+			if slices, err = debit(tx, original.ToUserID, taken); err != nil {
+				return err
+			}
+		}
+		if result.Debt > 0 {
+			err := tx.Model(&models.User{}).
+				Where("id = ?", original.ToUserID).
+				Update("debt", gorm.Expr("debt + ?", result.Debt)).Error
+			if err != nil {
+				return err
+			}
+		}
+		if result.Debt > 0 {
+			slices = append(slices, lotSlice{Amount: result.Debt, ExpiresAt: CoinExpiry(time.Now())})
+		}
+		if err := credit(tx, original.FromUserID, slices); err != nil {
+			return err
+		}
+
+		result.Compensation = models.Transaction{
+			FromUserID: original.ToUserID,
+			ToUserID:   original.FromUserID,
+			Amount:     amount,
+			ReversalOf: &original.ID,
+			Status:     models.TransactionSettled,
+		}
+		if err := tx.Create(&result.Compensation).Error; err != nil {
+			return err
+		}
+
+		description := fmt.Sprintf("отмена перевода #%d (%s)", original.ID, actor)
+		if reason != "" {
+			description += ": " + reason
+		}
+		return PostEntry(tx, &models.LedgerEntry{
+			Kind:          models.LedgerKindReversal,
+			TransactionID: &result.Compensation.ID,
+			Description:   description,
+			Postings: []models.LedgerPosting{
+				UserPosting(original.ToUserID, -amount),
+				UserPosting(original.FromUserID, amount),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrScheduledTransferNotFound возвращается, если запланированный перевод не найден или принадлежит другому пользователю.
+	ErrScheduledTransferNotFound = errors.New("запланированный перевод не найден")
+	// ErrScheduledTransferStatus возвращается, если действие недоступно в текущем статусе перевода.
+	ErrScheduledTransferStatus = errors.New("действие недоступно в текущем статусе перевода")
+	// ErrScheduleNeverRuns возвращается для расписания, по которому перевод никогда не выполнится.
+	ErrScheduleNeverRuns = errors.New("по расписанию перевод никогда не выполнится")
+)
+
+// Сколько запланированных переводов обрабатывается за один проход обработчика.
+const scheduledTransfersBatch = 100
+
+// ScheduledTransferMaxAttempts возвращает число попыток выполнить перевод, после которого
+// разовый перевод считается неудавшимся, а повторяющийся пропускает текущий запуск.
+func ScheduledTransferMaxAttempts() int {
+	return config.GetInt("SCHEDULED_TRANSFER_MAX_ATTEMPTS", 3)
+}
+
+// ScheduledTransferRetryDelay возвращает паузу перед повторной попыткой;
+// с каждой неудачной попыткой пауза растёт.
+func ScheduledTransferRetryDelay() time.Duration {
+	return config.GetDuration("SCHEDULED_TRANSFER_RETRY_DELAY", 5*time.Minute)
+}
+
+// CreateScheduledTransfer планирует перевод от fromUserID к toUserID. Если schedule пуст,
+// перевод выполняется один раз в runAt; иначе он повторяется по cron-выражению schedule,
+// а первый запуск — в runAt, если оно задано, или в ближайший момент по расписанию.
+func CreateScheduledTransfer(db *gorm.DB, fromUserID, toUserID uint, amount int, message, schedule string, runAt *time.Time) (*models.ScheduledTransfer, error) {
+	now := time.Now()
+	var parsed *Schedule
+	if schedule != "" {
+		var err error
+		if parsed, err = ParseSchedule(schedule); err != nil {
+			return nil, err
+		}
+		if parsed.Next(now).IsZero() {
+			return nil, ErrScheduleNeverRuns
+		}
+	}
+
+	transfer := models.ScheduledTransfer{
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Amount:     amount,
+		Message:    message,
+		Schedule:   schedule,
+		Status:     models.ScheduledTransferActive,
+	}
+	switch {
+	case runAt != nil:
+		transfer.NextRunAt = *runAt
+	case parsed != nil:
+		transfer.NextRunAt = parsed.Next(now)
+	default:
+		transfer.NextRunAt = now
+	}
+
+	if err := db.Create(&transfer).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// PauseScheduledTransfer приостанавливает активный перевод пользователя.
+func PauseScheduledTransfer(db *gorm.DB, userID, id uint) (*models.ScheduledTransfer, error) {
+	return setScheduledTransferStatus(db, userID, id, models.ScheduledTransferPaused, models.ScheduledTransferActive)
+}
+
+// ResumeScheduledTransfer возобновляет приостановленный перевод. Запуски повторяющегося
+// перевода, пропущенные за время паузы, не выполняются.
+func ResumeScheduledTransfer(db *gorm.DB, userID, id uint) (*models.ScheduledTransfer, error) {
+	return setScheduledTransferStatus(db, userID, id, models.ScheduledTransferActive, models.ScheduledTransferPaused)
+}
+
+// CancelScheduledTransfer отменяет активный или приостановленный перевод.
+func CancelScheduledTransfer(db *gorm.DB, userID, id uint) (*models.ScheduledTransfer, error) {
+	return setScheduledTransferStatus(db, userID, id, models.ScheduledTransferCancelled,
+		models.ScheduledTransferActive, models.ScheduledTransferPaused)
+}
+
+// setScheduledTransferStatus переводит запланированный перевод пользователя userID
+// в статус status, если он находится в одном из статусов from.
+func setScheduledTransferStatus(db *gorm.DB, userID, id uint, status string, from ...string) (*models.ScheduledTransfer, error) {
+	var transfer models.ScheduledTransfer
+	if err := db.Where("id = ? AND from_user_id = ?", id, userID).First(&transfer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledTransferNotFound
+		}
+		return nil, err
+	}
+
+	nextRunAt := transfer.NextRunAt
+	if status == models.ScheduledTransferActive && transfer.Schedule != "" && nextRunAt.Before(time.Now()) {
+		parsed, err := ParseSchedule(transfer.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		nextRunAt = parsed.Next(time.Now())
+	}
+
+	// Условие на статус не даёт двум параллельным запросам изменить перевод дважды
+	res := db.Model(&models.ScheduledTransfer{}).
+		Where("id = ? AND status IN ?", transfer.ID, from).
+		Updates(map[string]interface{}{"status": status, "next_run_at": nextRunAt})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrScheduledTransferStatus
+	}
+	transfer.Status = status
+	transfer.NextRunAt = nextRunAt
+	return &transfer, nil
+}
+
+// RunDueScheduledTransfers выполняет активные переводы, время которых наступило к моменту now,
+// и возвращает число успешно выполненных. Ошибка отдельного перевода не прерывает обработку
+// остальных: она записывается в перевод, а попытка повторяется позже.
+func RunDueScheduledTransfers(db *gorm.DB, now time.Time) (int, error) {
+	var due []models.ScheduledTransfer
+	err := db.Where("status = ? AND next_run_at <= ?", models.ScheduledTransferActive, now).
+		Order("next_run_at").
+		Limit(scheduledTransfersBatch).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	executed := 0
+	for i := range due {
+		ok, err := runScheduledTransfer(db, &due[i], now)
+		if err != nil {
+			return executed, err
+		}
+		if ok {
+			executed++
+		}
+	}
+	return executed, nil
+}
+
+// errScheduledTransferTaken сообщает, что перевод уже обработан другим экземпляром обработчика.
+var errScheduledTransferTaken = errors.New("запланированный перевод уже обработан")
+
+// runScheduledTransfer выполняет один запланированный перевод тем же атомарным переводом,
+// что и SendCoin. Счётчик Runs захватывается условным обновлением в той же транзакции,
+// поэтому параллельные обработчики не выполнят перевод дважды.
+func runScheduledTransfer(db *gorm.DB, scheduled *models.ScheduledTransfer, now time.Time) (bool, error) {
+	var next *time.Time
+	if scheduled.Schedule != "" {
+		parsed, err := ParseSchedule(scheduled.Schedule)
+		if err != nil {
+			return false, failScheduledTransfer(db, scheduled, nil, now, err)
+		}
+		at := parsed.Next(now)
+		next = &at
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"runs":        gorm.Expr("runs + 1"),
+			"attempts":    0,
+			"last_run_at": now,
+			"last_error":  "",
+		}
+		// У разового перевода и у расписания без будущих запусков это последний запуск
+		if next == nil || next.IsZero() {
+			updates["status"] = models.ScheduledTransferCompleted
+		} else {
+			updates["next_run_at"] = *next
+		}
+		res := tx.Model(&models.ScheduledTransfer{}).
+			Where("id = ? AND runs = ? AND status = ?", scheduled.ID, scheduled.Runs, models.ScheduledTransferActive).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errScheduledTransferTaken
+		}
+
+		transaction, err := Transfer(tx, scheduled.FromUserID, scheduled.ToUserID, scheduled.Amount, scheduled.Message)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ScheduledTransfer{}).
+			Where("id = ?", scheduled.ID).
+			Update("last_transaction_id", transaction.ID).Error
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, errScheduledTransferTaken):
+		return false, nil
+	default:
+		return false, failScheduledTransfer(db, scheduled, next, now, err)
+	}
+}
+
+// failScheduledTransfer записывает неудачную попытку и назначает повтор. После
+// ScheduledTransferMaxAttempts попыток разовый перевод получает статус failed,
+// а повторяющийся пропускает текущий запуск и ждёт следующего по расписанию.
+func failScheduledTransfer(db *gorm.DB, scheduled *models.ScheduledTransfer, next *time.Time, now time.Time, cause error) error {
+	log.Printf("Запланированный перевод #%d не выполнен: %v", scheduled.ID, cause)
+
+	attempts := scheduled.Attempts + 1
+	updates := map[string]interface{}{
+		"runs":        gorm.Expr("runs + 1"),
+		"attempts":    attempts,
+		"last_run_at": now,
+		"last_error":  cause.Error(),
+	}
+	switch {
+	case attempts < ScheduledTransferMaxAttempts():
+		updates["next_run_at"] = now.Add(ScheduledTransferRetryDelay() * time.Duration(attempts))
+	case next != nil && !next.IsZero():
+		updates["next_run_at"] = *next
+		updates["attempts"] = 0
+	default:
+		updates["status"] = models.ScheduledTransferFailed
+	}
+	return db.Model(&models.ScheduledTransfer{}).
+		Where("id = ? AND runs = ?", scheduled.ID, scheduled.Runs).
+		Updates(updates).Error
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidToken возвращается для неверного, просроченного или отозванного access-токена.
+	ErrInvalidToken = errors.New("неверный или просроченный токен")
+	// ErrInvalidRefreshToken возвращается для неизвестного, просроченного или отозванного refresh-токена.
+	ErrInvalidRefreshToken = errors.New("неверный или просроченный refresh-токен")
+	// ErrRefreshTokenReused возвращается при повторном использовании refresh-токена.
+	// В этом случае отзывается вся цепочка токенов, к которой он относится.
+	ErrRefreshTokenReused = errors.New("refresh-токен уже был использован")
+)
+
+// TokenPair — выданные пользователю access- и refresh-токены.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// AccessClaims — данные, извлечённые из проверенного access-токена.
+type AccessClaims struct {
+	Username  string
+	Role      string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// jwtSecret читается при каждом обращении, чтобы учитывать переменные, загруженные из .env после старта.
+func jwtSecret() []byte {
+	return []byte(os.Getenv("JWT_SECRET_KEY"))
+}
+
+func accessTokenTTL() time.Duration {
+	return config.GetDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+func refreshTokenTTL() time.Duration {
+	return config.GetDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// IssueTokens выдаёт пользователю новую пару токенов и открывает новую цепочку refresh-токенов.
+func IssueTokens(db *gorm.DB, user *models.User) (*TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return issueTokens(db, user, familyID)
+}
+
+// RefreshTokens обменивает refresh-токен на новую пару токенов той же цепочки.
+// Использованный токен помечается как обменянный; его повторное предъявление
+// считается утечкой и отзывает всю цепочку.
+func RefreshTokens(db *gorm.DB, refreshToken string) (*TokenPair, error) {
+	var stored models.RefreshToken
+	err := db.Where("token_hash = ?", hashToken(refreshToken)).First(&stored).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil || now.After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Условное обновление гарантирует, что токен будет обменян только один раз,
+	// даже если два запроса с ним пришли одновременно
+	res := db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", stored.ID).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if err := RevokeTokenFamily(db, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	var user models.User
+	if err := db.First(&user, stored.UserID).Error; err != nil {
+		return nil, err
+	}
+	return issueTokens(db, &user, stored.FamilyID)
+}
+
+// RevokeTokenFamily отзывает все refresh-токены цепочки.
+func RevokeTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Logout отзывает access-токен по его jti и, если передан refresh-токен пользователя,
+// всю цепочку, к которой он относится.
+func Logout(db *gorm.DB, claims *AccessClaims, userID uint, refreshToken string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Попутно удаляем записи об уже истёкших токенах: проверять их больше не нужно
+		if err := tx.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.RevokedToken{JTI: claims.JTI, ExpiresAt: claims.ExpiresAt}).Error; err != nil {
+			return err
+		}
+
+		if refreshToken == "" {
+			return nil
+		}
+		var stored models.RefreshToken
+		err := tx.Where("token_hash = ? AND user_id = ?", hashToken(refreshToken), userID).First(&stored).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		return RevokeTokenFamily(tx, stored.FamilyID)
+	})
+}
+
+// ParseAccessToken проверяет подпись и срок действия access-токена,
+// а также то, что токен не был отозван.
+func ParseAccessToken(db *gorm.DB, tokenString string) (*AccessClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	username, _ := mapClaims["username"].(string)
+	role, _ := mapClaims["role"].(string)
+	jti, _ := mapClaims["jti"].(string)
+	exp, err := mapClaims.GetExpirationTime()
+	if username == "" || jti == "" || err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var revoked int64
+	if err := db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&revoked).Error; err != nil {
+		return nil, err
+	}
+	if revoked > 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return &AccessClaims{Username: username, Role: role, JTI: jti, ExpiresAt: exp.Time}, nil
+}
+
+// issueTokens создаёт access-токен и refresh-токен в указанной цепочке.
+func issueTokens(db *gorm.DB, user *models.User, familyID string) (*TokenPair, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ttl := accessTokenTTL()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": user.Username,
+		"role":     user.Role,
+		"jti":      jti,
+		"iat":      now.Unix(),
+		"exp":      now.Add(ttl).Unix(),
+	})
+	accessToken, err := token.SignedString(jwtSecret())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	stored := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(refreshTokenTTL()),
+	}
+	if err := db.Create(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: ttl}, nil
+}
+
+// randomToken возвращает криптографически стойкую случайную строку из n байт.
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
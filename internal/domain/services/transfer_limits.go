@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/config"
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// Лимиты исходящих переводов.
+const (
+	// TransferLimitAmount — максимальная сумма одного перевода.
+	TransferLimitAmount = "amount"
+	// TransferLimitDaily — сумма исходящих переводов за последние 24 часа.
+	TransferLimitDaily = "daily"
+	// TransferLimitWeekly — сумма исходящих переводов за последние 7 дней.
+	TransferLimitWeekly = "weekly"
+	// TransferLimitDailyRecipients — число разных получателей за последние 24 часа.
+	TransferLimitDailyRecipients = "daily_recipients"
+)
+
+var (
+	// ErrTransferLimitExceeded возвращается (в составе TransferLimitError), если перевод превышает лимит.
+	ErrTransferLimitExceeded = errors.New("превышен лимит переводов")
+	// ErrNegativeTransferLimit возвращается, если лимит задан отрицательным числом.
+	ErrNegativeTransferLimit = errors.New("лимит не может быть отрицательным")
+	// ErrTransferLimitOverrideNotFound возвращается, если у пользователя нет индивидуальных лимитов.
+	ErrTransferLimitOverrideNotFound = errors.New("индивидуальные лимиты не заданы")
+)
+
+// voidedTransferStatuses — статусы переводов, по которым монеты вернулись отправителю.
+// Такие переводы не учитываются ни лимитами, ни правилами обнаружения мошенничества.
+var voidedTransferStatuses = []string{models.TransactionRejected, models.TransactionReleased}
+
+// TransferLimits — лимиты исходящих переводов пользователя. 0 означает отсутствие ограничения.
+type TransferLimits struct {
+	MaxAmount       int `json:"maxAmount"`
+	DailyLimit      int `json:"dailyLimit"`
+	WeeklyLimit     int `json:"weeklyLimit"`
+	DailyRecipients int `json:"dailyRecipients"`
+}
+
+// TransferUsage — исходящие переводы пользователя, учитываемые лимитами.
+type TransferUsage struct {
+	Daily           int `json:"daily"`
+	Weekly          int `json:"weekly"`
+	DailyRecipients int `json:"dailyRecipients"`
+}
+
+// TransferLimitError возвращается, если перевод превышает лимит Limit.
+type TransferLimitError struct {
+	Limit  string
+	Max    int // значение лимита
+	Used   int // использовано до этого перевода
+	Amount int // сумма отклонённого перевода
+}
+
+func (e *TransferLimitError) Error() string {
+	return fmt.Sprintf("%v (%s): лимит %d, использовано %d", ErrTransferLimitExceeded, e.Limit, e.Max, e.Used)
+}
+
+func (e *TransferLimitError) Unwrap() error {
+	return ErrTransferLimitExceeded
+}
+
+// DefaultTransferLimits возвращает лимиты из конфигурации. По умолчанию ограничений нет.
+func DefaultTransferLimits() TransferLimits {
+	return TransferLimits{
+		MaxAmount:       config.GetInt("TRANSFER_MAX_AMOUNT", 0),
+		DailyLimit:      config.GetInt("TRANSFER_DAILY_LIMIT", 0),
+		WeeklyLimit:     config.GetInt("TRANSFER_WEEKLY_LIMIT", 0),
+		DailyRecipients: config.GetInt("TRANSFER_DAILY_RECIPIENTS", 0),
+	}
+}
+
+// Apply возвращает лимиты с учётом индивидуальных значений override.
+func (l TransferLimits) Apply(override *models.TransferLimitOverride) TransferLimits {
+	if override == nil {
+		return l
+	}
+	for _, field := range []struct {
+		value  *int
+		target *int
+	}{
+		{override.MaxAmount, &l.MaxAmount},
+		{override.DailyLimit, &l.DailyLimit},
+		{override.WeeklyLimit, &l.WeeklyLimit},
+		{override.DailyRecipients, &l.DailyRecipients},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	return l
+}
+
+// FindTransferLimitOverride возвращает индивидуальные лимиты пользователя или nil, если они не заданы.
+func FindTransferLimitOverride(db *gorm.DB, userID uint) (*models.TransferLimitOverride, error) {
+	// Limit(1).Find вместо First: отсутствие индивидуальных лимитов — обычный случай,
+	// и gorm не должен писать в журнал "record not found" при каждом переводе
+	var override models.TransferLimitOverride
+	res := db.Where("user_id = ?", userID).Limit(1).Find(&override)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &override, nil
+}
+
+// EffectiveTransferLimits возвращает лимиты, действующие для пользователя.
+func EffectiveTransferLimits(db *gorm.DB, userID uint) (TransferLimits, error) {
+	override, err := FindTransferLimitOverride(db, userID)
+	if err != nil {
+		return TransferLimits{}, err
+	}
+	return DefaultTransferLimits().Apply(override), nil
+}
+
+// SetTransferLimitOverride задаёт пользователю индивидуальные лимиты, полностью заменяя
+// прежние. Поля override, равные nil, возвращают соответствующий лимит к значению по умолчанию.
+func SetTransferLimitOverride(db *gorm.DB, actor string, userID uint, override models.TransferLimitOverride) (*models.TransferLimitOverride, error) {
+	for _, value := range []*int{override.MaxAmount, override.DailyLimit, override.WeeklyLimit, override.DailyRecipients} {
+		if value != nil && *value < 0 {
+			return nil, ErrNegativeTransferLimit
+		}
+	}
+
+	existing, err := FindTransferLimitOverride(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		override.Model = existing.Model
+	}
+	override.UserID = userID
+	override.UpdatedBy = actor
+	if err := db.Save(&override).Error; err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// DeleteTransferLimitOverride удаляет индивидуальные лимиты пользователя.
+func DeleteTransferLimitOverride(db *gorm.DB, userID uint) error {
+	res := db.Unscoped().Where("user_id = ?", userID).Delete(&models.TransferLimitOverride{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTransferLimitOverrideNotFound
+	}
+	return nil
+}
+
+// TransferUsageAt возвращает исходящие переводы пользователя за 24 часа и 7 дней до now.
+// Компенсирующие переводы, созданные при отмене, а также отклонённые и не подтверждённые
+// переводы, монеты по которым вернулись отправителю, не учитываются.
+func TransferUsageAt(db *gorm.DB, userID uint, now time.Time) (TransferUsage, error) {
+	dayAgo := now.Add(-24 * time.Hour)
+	var usage TransferUsage
+	err := db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN created_at > ? THEN amount ELSE 0 END), 0) AS daily, "+
+			"COALESCE(SUM(amount), 0) AS weekly, "+
+			"COUNT(DISTINCT CASE WHEN created_at > ? THEN to_user_id END) AS daily_recipients", dayAgo, dayAgo).
+		Where("from_user_id = ? AND reversal_of IS NULL AND created_at > ?", userID, now.AddDate(0, 0, -7)).
+		Where("status NOT IN ?", voidedTransferStatuses).
+		Scan(&usage).Error
+	return usage, err
+}
+
+// checkTransferLimits проверяет, что перевод amount монет от fromUserID к toUserID не превышает
+// лимитов отправителя. Вызывается внутри транзакции перевода после блокировки строки
+// отправителя, поэтому параллельные переводы одного пользователя учитываются корректно.
+func checkTransferLimits(tx *gorm.DB, fromUserID, toUserID uint, amount int) error {
+	limits, err := EffectiveTransferLimits(tx, fromUserID)
+	if err != nil {
+		return err
+	}
+	if limits.MaxAmount > 0 && amount > limits.MaxAmount {
+		return &TransferLimitError{Limit: TransferLimitAmount, Max: limits.MaxAmount, Amount: amount}
+	}
+	if limits.DailyLimit == 0 && limits.WeeklyLimit == 0 && limits.DailyRecipients == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	usage, err := TransferUsageAt(tx, fromUserID, now)
+	if err != nil {
+		return err
+	}
+	if limits.DailyLimit > 0 && usage.Daily+amount > limits.DailyLimit {
+		return &TransferLimitError{Limit: TransferLimitDaily, Max: limits.DailyLimit, Used: usage.Daily, Amount: amount}
+	}
+	if limits.WeeklyLimit > 0 && usage.Weekly+amount > limits.WeeklyLimit {
+		return &TransferLimitError{Limit: TransferLimitWeekly, Max: limits.WeeklyLimit, Used: usage.Weekly, Amount: amount}
+	}
+	if limits.DailyRecipients > 0 && usage.DailyRecipients >= limits.DailyRecipients {
+		// Переводы уже знакомому за эти сутки получателю лимит получателей не увеличивают
+		var known int64
+		err := tx.Model(&models.Transaction{}).
+			Where("from_user_id = ? AND to_user_id = ? AND reversal_of IS NULL AND created_at > ? AND status NOT IN ?",
+				fromUserID, toUserID, now.Add(-24*time.Hour), voidedTransferStatuses).
+			Count(&known).Error
+		if err != nil {
+			return err
+		}
+		if known == 0 {
+			return &TransferLimitError{
+				Limit:  TransferLimitDailyRecipients,
+				Max:    limits.DailyRecipients,
+				Used:   usage.DailyRecipients,
+				Amount: amount,
+			}
+		}
+	}
+	return nil
+}
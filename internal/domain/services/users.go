@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrEmptyAdminPassword возвращается, если администратора нужно создать, а пароль для него не задан.
+var ErrEmptyAdminPassword = errors.New("не задан пароль администратора")
+
+// InitialCoins — количество монет, начисляемых пользователю при регистрации.
+const InitialCoins = 1000
+
+// CreateUser создаёт пользователя с начальным балансом InitialCoins
+// и записывает начисление в журнал в рамках переданной транзакции БД.
+func CreateUser(tx *gorm.DB, user *models.User) error {
+	user.Coins = InitialCoins
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	if err := addLots(tx, user.ID, []lotSlice{{Amount: InitialCoins, ExpiresAt: CoinExpiry(time.Now())}}); err != nil {
+		return err
+	}
+
+	return PostEntry(tx, &models.LedgerEntry{
+		Kind:        models.LedgerKindGrant,
+		Description: "Начисление при регистрации",
+		Postings: []models.LedgerPosting{
+			SystemPosting(models.LedgerAccountIssuance, -InitialCoins),
+			UserPosting(user.ID, InitialCoins),
+		},
+	})
+}
+
+// EnsureAdmin гарантирует наличие администратора с указанным именем.
+// Если пользователь уже существует, ему назначается роль администратора, пароль не меняется.
+// Иначе создаётся новый пользователь с ролью администратора и указанным паролем.
+func EnsureAdmin(db *gorm.DB, username, password string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Where("username = ?", username).First(&user).Error
+		if err == nil {
+			if user.Role == models.RoleAdmin {
+				return nil
+			}
+			return tx.Model(&user).Update("role", models.RoleAdmin).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if password == "" {
+			return ErrEmptyAdminPassword
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		user = models.User{
+			Username: username,
+			Password: string(hash),
+			Role:     models.RoleAdmin,
+		}
+		return CreateUser(tx, &user)
+	})
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVariantNotFound возвращается, если у товара нет варианта с таким названием или ID.
+	ErrVariantNotFound = errors.New("вариант товара не найден")
+	// ErrVariantRequired возвращается при покупке товара с вариантами без выбора варианта.
+	ErrVariantRequired = errors.New("не выбран вариант товара")
+	// ErrVariantNameTaken возвращается, если у товара уже есть вариант с таким названием
+	// (в том числе среди удалённых).
+	ErrVariantNameTaken = errors.New("вариант с таким названием уже существует")
+	// ErrStockByVariants возвращается при пополнении товара с вариантами: его остаток
+	// учитывается по вариантам, и пополнять нужно вариант.
+	ErrStockByVariants = errors.New("остаток товара учитывается по вариантам")
+)
+
+// VariantChanges описывает изменяемые поля варианта. Пустые поля не меняются;
+// Price, равная 0, сбрасывает цену варианта на цену товара.
+type VariantChanges struct {
+	Name       *string
+	Attributes map[string]string
+	Stock      *int
+	Price      *int
+}
+
+// variantSnapshot — состояние варианта, сохраняемое в журнале изменений каталога.
+type variantSnapshot struct {
+	ID         uint              `json:"id"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Stock      *int              `json:"stock,omitempty"`
+	Price      *int              `json:"price,omitempty"`
+	Deleted    bool              `json:"deleted"`
+}
+
+// ItemPrice возвращает цену единицы товара: цену варианта, если она задана, иначе цену товара.
+func ItemPrice(merch *models.Merch, variant *models.MerchVariant) int {
+	if variant != nil && variant.Price != nil {
+		return *variant.Price
+	}
+	return merch.Price
+}
+
+// MerchVariants возвращает действующие варианты перечисленных товаров, сгруппированные по товару.
+func MerchVariants(db *gorm.DB, merchIDs []uint) (map[uint][]models.MerchVariant, error) {
+	byMerch := map[uint][]models.MerchVariant{}
+	if len(merchIDs) == 0 {
+		return byMerch, nil
+	}
+	var variants []models.MerchVariant
+	if err := db.Where("merch_id IN ?", merchIDs).Order("id").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		byMerch[variant.MerchID] = append(byMerch[variant.MerchID], variant)
+	}
+	return byMerch, nil
+}
+
+// SelectVariant выбирает вариант товара по названию. Для товара без вариантов название
+// должно быть пустым, и возвращается nil; для товара с вариантами пустое название
+// приводит к ErrVariantRequired.
+func SelectVariant(db *gorm.DB, merch *models.Merch, name string) (*models.MerchVariant, error) {
+	if name == "" {
+		var count int64
+		if err := db.Model(&models.MerchVariant{}).Where("merch_id = ?", merch.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrVariantRequired
+		}
+		return nil, nil
+	}
+
+	var variant models.MerchVariant
+	if err := db.Where("merch_id = ? AND name = ?", merch.ID, name).First(&variant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// CreateVariant добавляет товару вариант и фиксирует изменение в журнале каталога.
+// Если stock равен nil, запас варианта не ограничен; если price равна nil, вариант
+// продаётся по цене товара.
+func CreateVariant(db *gorm.DB, actor string, merchID uint, name string, attributes map[string]string, stock, price *int) (*models.MerchVariant, error) {
+	variant := models.MerchVariant{MerchID: merchID, Name: name, Attributes: attributes, Stock: stock, Price: price}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var merch models.Merch
+		if err := findMerch(tx, merchID, false, &merch); err != nil {
+			return err
+		}
+		if err := ensureVariantNameFree(tx, merchID, name, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		return recordVariantChange(tx, actor, models.CatalogActionVariantCreate, nil, &variant)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// UpdateVariant изменяет вариант товара и фиксирует старое и новое значения в журнале.
+func UpdateVariant(db *gorm.DB, actor string, merchID, id uint, changes VariantChanges) (*models.MerchVariant, error) {
+	var variant models.MerchVariant
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findVariant(tx, merchID, id, &variant); err != nil {
+			return err
+		}
+		before := variant
+
+		updates := map[string]interface{}{}
+		if changes.Name != nil && *changes.Name != variant.Name {
+			if err := ensureVariantNameFree(tx, merchID, *changes.Name, variant.ID); err != nil {
+				return err
+			}
+			updates["name"] = *changes.Name
+			variant.Name = *changes.Name
+		}
+		if changes.Attributes != nil {
+			data, err := json.Marshal(changes.Attributes)
+			if err != nil {
+				return err
+			}
+			updates["attributes"] = string(data)
+			variant.Attributes = changes.Attributes
+		}
+		if changes.Stock != nil {
+			updates["stock"] = *changes.Stock
+			variant.Stock = changes.Stock
+		}
+		if changes.Price != nil {
+			if *changes.Price == 0 {
+				updates["price"] = nil
+				variant.Price = nil
+			} else {
+				updates["price"] = *changes.Price
+				variant.Price = changes.Price
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.MerchVariant{}).Where("id = ?", variant.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordVariantChange(tx, actor, models.CatalogActionVariantUpdate, &before, &variant)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// DeleteVariant мягко удаляет вариант товара: купить его больше нельзя,
+// но покупки варианта остаются в инвентаре.
+func DeleteVariant(db *gorm.DB, actor string, merchID, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var variant models.MerchVariant
+		if err := findVariant(tx, merchID, id, &variant); err != nil {
+			return err
+		}
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		deleted := variant
+		deleted.DeletedAt = gorm.DeletedAt{Valid: true}
+		return recordVariantChange(tx, actor, models.CatalogActionVariantDelete, &variant, &deleted)
+	})
+}
+
+// RestockVariant увеличивает остаток варианта на quantity единиц и фиксирует пополнение в журнале.
+func RestockVariant(db *gorm.DB, actor string, merchID, id uint, quantity int) (*models.MerchVariant, error) {
+	var variant models.MerchVariant
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := findVariant(tx, merchID, id, &variant); err != nil {
+			return err
+		}
+		if variant.Stock == nil {
+			return ErrUnlimitedStock
+		}
+		before := variant
+
+		err := tx.Model(&models.MerchVariant{}).
+			Where("id = ?", variant.ID).
+			Update("stock", gorm.Expr("stock + ?", quantity)).Error
+		if err != nil {
+			return err
+		}
+		// Перечитываем остаток: параллельные покупки могли его изменить
+		if err := tx.First(&variant, variant.ID).Error; err != nil {
+			return err
+		}
+		return recordVariantChange(tx, actor, models.CatalogActionVariantRestock, &before, &variant)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// reserveVariantStock уменьшает остаток варианта на quantity единиц условным UPDATE.
+// Если остатка не хватает, возвращается ErrOutOfStock.
+func reserveVariantStock(tx *gorm.DB, variantID uint, quantity int) error {
+	res := tx.Model(&models.MerchVariant{}).
+		Where("id = ? AND (stock IS NULL OR stock >= ?)", variantID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOutOfStock
+	}
+	return nil
+}
+
+// findVariant ищет действующий вариант товара merchID по ID.
+func findVariant(tx *gorm.DB, merchID, id uint, variant *models.MerchVariant) error {
+	if err := tx.Where("merch_id = ?", merchID).First(variant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVariantNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ensureVariantNameFree проверяет, что название не занято другим вариантом того же товара,
+// включая удалённые: уникальный индекс распространяется и на них.
+func ensureVariantNameFree(tx *gorm.DB, merchID uint, name string, exceptID uint) error {
+	var count int64
+	err := tx.Unscoped().Model(&models.MerchVariant{}).
+		Where("merch_id = ? AND name = ? AND id <> ?", merchID, name, exceptID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrVariantNameTaken
+	}
+	return nil
+}
+
+func recordVariantChange(tx *gorm.DB, actor, action string, before, after *models.MerchVariant) error {
+	change := models.CatalogChange{
+		Action:    action,
+		ChangedBy: actor,
+	}
+	if before != nil {
+		change.MerchID = before.MerchID
+		change.OldValue = snapshotVariant(before)
+	}
+	if after != nil {
+		change.MerchID = after.MerchID
+		change.NewValue = snapshotVariant(after)
+	}
+	return tx.Create(&change).Error
+}
+
+func snapshotVariant(variant *models.MerchVariant) string {
+	data, _ := json.Marshal(variantSnapshot{
+		ID:         variant.ID,
+		Name:       variant.Name,
+		Attributes: variant.Attributes,
+		Stock:      variant.Stock,
+		Price:      variant.Price,
+		Deleted:    variant.DeletedAt.Valid,
+	})
+	return string(data)
+}
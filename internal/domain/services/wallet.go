@@ -0,0 +1,292 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/defskela/merchmarket/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInsufficientFunds возвращается, когда на балансе пользователя недостаточно монет.
+	ErrInsufficientFunds = errors.New("недостаточно средств")
+	// ErrOutOfStock возвращается, когда товар закончился на складе.
+	ErrOutOfStock = errors.New("товар закончился")
+	// ErrMessageTooLong возвращается, когда комментарий к переводу длиннее MaxMessageLength.
+	ErrMessageTooLong = errors.New("комментарий слишком длинный")
+)
+
+// MaxMessageLength — максимальная длина комментария к переводу в символах.
+const MaxMessageLength = 200
+
+// SanitizeMessage очищает комментарий к переводу: переводы строк и табуляции заменяются
+// пробелами, остальные управляющие и невидимые символы форматирования (например,
+// смена направления текста) удаляются, пробелы по краям обрезаются.
+func SanitizeMessage(message string) (string, error) {
+	message = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			return ' '
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == utf8.RuneError:
+			return -1
+		}
+		return r
+	}, message)
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > MaxMessageLength {
+		return "", ErrMessageTooLong
+	}
+	return message, nil
+}
+
+// LockUsers блокирует строки пользователей (SELECT ... FOR UPDATE) до конца транзакции.
+// Строки захватываются в порядке возрастания ID, поэтому встречные переводы
+// между одними и теми же пользователями не приводят к взаимной блокировке.
+func LockUsers(tx *gorm.DB, ids ...uint) (map[uint]*models.User, error) {
+	var users []models.User
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	locked := make(map[uint]*models.User, len(users))
+	for i := range users {
+		locked[users[i].ID] = &users[i]
+	}
+	for _, id := range ids {
+		if _, ok := locked[id]; !ok {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+	return locked, nil
+}
+
+// Debit атомарно списывает монеты с баланса пользователя.
+// Списание выполняется условным UPDATE, поэтому баланс не может уйти в минус
+// даже при параллельных запросах. Монеты списываются с партий, начиная с тех,
+// что сгорят раньше всех.
+func Debit(tx *gorm.DB, userID uint, amount int) error {
+	_, err := debit(tx, userID, amount)
+	return err
+}
+
+// debit работает как Debit и возвращает списанные части партий.
+func debit(tx *gorm.DB, userID uint, amount int) ([]lotSlice, error) {
+	res := tx.Model(&models.User{}).
+		Where("id = ? AND coins >= ?", userID, amount).
+		Update("coins", gorm.Expr("coins - ?", amount))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInsufficientFunds
+	}
+	return spendLots(tx, userID, amount)
+}
+
+// Credit атомарно зачисляет монеты на баланс пользователя.
+// Если у пользователя есть долг (User.Debt), зачисление сначала погашает его.
+// Зачисленные монеты сгорают по политике COIN_EXPIRY_POLICY.
+func Credit(tx *gorm.DB, userID uint, amount int) error {
+	return credit(tx, userID, []lotSlice{{Amount: amount, ExpiresAt: CoinExpiry(time.Now())}})
+}
+
+// credit зачисляет пользователю переданные части партий с сохранением их сроков действия.
+// Часть, ушедшая на погашение долга, партий не образует.
+func credit(tx *gorm.DB, userID uint, slices []lotSlice) error {
+	users, err := LockUsers(tx, userID)
+	if err != nil {
+		return err
+	}
+	amount := 0
+	for _, slice := range slices {
+		amount += slice.Amount
+	}
+
+	// Оба выражения вычисляются по значениям строки до обновления
+	res := tx.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"coins": gorm.Expr("coins + CASE WHEN debt >= ? THEN 0 ELSE ? - debt END", amount, amount),
+			"debt":  gorm.Expr("CASE WHEN debt >= ? THEN debt - ? ELSE 0 END", amount, amount),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return addLots(tx, userID, skipLots(slices, users[userID].Debt))
+}
+
+// Transfer переводит монеты между пользователями в рамках переданной транзакции БД
+// и записывает перевод в историю вместе с комментарием message (уже очищенным
+// SanitizeMessage). Перевод, превышающий лимиты отправителя, отклоняется с
+// TransferLimitError. Перевод, на котором сработали правила обнаружения
+// мошенничества, попадает в очередь проверки, а при включённом FRAUD_HOLD
+// задерживается: монеты списываются с отправителя, но зачисляются получателю
+// только после одобрения администратором. Фиксация транзакции остаётся на вызывающей стороне.
+func Transfer(tx *gorm.DB, fromUserID, toUserID uint, amount int, message string) (*models.Transaction, error) {
+	return transfer(tx, fromUserID, toUserID, amount, message, nil)
+}
+
+// transfer выполняет перевод. Если задан expiresAt, перевод создаётся с удержанием
+// (статус pending): монеты списываются с отправителя и ждут подтверждения до expiresAt.
+func transfer(tx *gorm.DB, fromUserID, toUserID uint, amount int, message string, expiresAt *time.Time) (*models.Transaction, error) {
+	if _, err := LockUsers(tx, fromUserID, toUserID); err != nil {
+		return nil, err
+	}
+	if err := checkTransferLimits(tx, fromUserID, toUserID, amount); err != nil {
+		return nil, err
+	}
+	// Монеты переходят получателю вместе со сроками действия
+	slices, err := debit(tx, fromUserID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := models.Transaction{
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Amount:     amount,
+		Message:    message,
+		Status:     models.TransactionSettled,
+		ExpiresAt:  expiresAt,
+	}
+	findings, err := evaluateFraudRules(tx, &transaction)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case expiresAt != nil:
+		transaction.Status = models.TransactionPending
+	case len(findings) > 0 && FraudHoldEnabled():
+		transaction.Status = models.TransactionHeld
+	default:
+		if err := credit(tx, toUserID, slices); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+	// Монеты незачисленного перевода сохраняют сроки действия до его завершения
+	if transaction.Status != models.TransactionSettled {
+		if err := holdLots(tx, transaction.ID, slices); err != nil {
+			return nil, err
+		}
+	}
+	if len(findings) > 0 {
+		if err := flagTransaction(tx, transaction.ID, findings); err != nil {
+			return nil, err
+		}
+	}
+
+	// Незачисленные монеты до решения хранятся на системном счёте
+	receiver := UserPosting(toUserID, amount)
+	if transaction.Status != models.TransactionSettled {
+		receiver = SystemPosting(models.LedgerAccountHeld, amount)
+	}
+	err = PostEntry(tx, &models.LedgerEntry{
+		Kind:          models.LedgerKindTransfer,
+		TransactionID: &transaction.ID,
+		Postings: []models.LedgerPosting{
+			UserPosting(fromUserID, -amount),
+			receiver,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ReserveStock атомарно уменьшает остаток товара на quantity единиц.
+// Для товаров без ограничения запаса (stock IS NULL) остаток не меняется.
+func ReserveStock(tx *gorm.DB, merchID uint, quantity int) error {
+	res := tx.Model(&models.Merch{}).
+		Where("id = ? AND (stock IS NULL OR stock >= ?)", merchID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOutOfStock
+	}
+	return nil
+}
+
+// BuyMerch списывает с пользователя стоимость товара, уменьшает остаток на складе,
+// создаёт запись о покупке и отражает её в журнале в рамках переданной транзакции БД.
+// Для товара с вариантами variant задаёт выбранный вариант (см. SelectVariant): остаток
+// уменьшается у варианта, а цена берётся из варианта, если она задана.
+func BuyMerch(tx *gorm.DB, userID uint, merch *models.Merch, variant *models.MerchVariant) (*models.Purchase, error) {
+	if err := reserveItem(tx, merch, variant, 1); err != nil {
+		return nil, err
+	}
+	if err := Debit(tx, userID, ItemPrice(merch, variant)); err != nil {
+		return nil, err
+	}
+	return recordPurchase(tx, models.Purchase{UserID: userID}, userID, merch, variant)
+}
+
+// BuyGift покупает товар за счёт buyerID и кладёт его в инвентарь recipientID.
+// Работает как BuyMerch, но запись о покупке принадлежит получателю и хранит
+// покупателя и комментарий (уже очищенный SanitizeMessage).
+func BuyGift(tx *gorm.DB, buyerID, recipientID uint, merch *models.Merch, variant *models.MerchVariant, note string) (*models.Purchase, error) {
+	if err := reserveItem(tx, merch, variant, 1); err != nil {
+		return nil, err
+	}
+	if err := Debit(tx, buyerID, ItemPrice(merch, variant)); err != nil {
+		return nil, err
+	}
+	gift := models.Purchase{UserID: recipientID, GiftFromID: &buyerID, GiftNote: note}
+	return recordPurchase(tx, gift, buyerID, merch, variant)
+}
+
+// reserveItem уменьшает остаток варианта, если он выбран, иначе остаток товара.
+func reserveItem(tx *gorm.DB, merch *models.Merch, variant *models.MerchVariant, quantity int) error {
+	if variant != nil {
+		return reserveVariantStock(tx, variant.ID, quantity)
+	}
+	return ReserveStock(tx, merch.ID, quantity)
+}
+
+// recordPurchase создаёт запись о покупке одной единицы товара, оплаченной payerID,
+// и отражает её в журнале. Монеты к этому моменту уже должны быть списаны.
+func recordPurchase(tx *gorm.DB, purchase models.Purchase, payerID uint, merch *models.Merch, variant *models.MerchVariant) (*models.Purchase, error) {
+	price := ItemPrice(merch, variant)
+	purchase.MerchID = merch.ID
+	purchase.Price = price
+	if variant != nil {
+		purchase.VariantID = &variant.ID
+	}
+	if err := tx.Create(&purchase).Error; err != nil {
+		return nil, err
+	}
+	// Товар и вариант присваиваются после вставки, чтобы GORM не пытался сохранить их повторно
+	purchase.Merch = *merch
+	purchase.Variant = variant
+
+	err := PostEntry(tx, &models.LedgerEntry{
+		Kind:       models.LedgerKindPurchase,
+		PurchaseID: &purchase.ID,
+		Postings: []models.LedgerPosting{
+			UserPosting(payerID, -price),
+			SystemPosting(models.LedgerAccountShop, price),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}